@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	brainwalletWordlist    string
+	brainwalletRules       string
+	brainwalletDerivations []string
+	brainwalletTargets     string
+	brainwalletWorkers     int
+	brainwalletCheckpoint  string
+)
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(brainwalletCmd)
+
+	brainwalletCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve of the keys, S256 for Bitcoin and Ethereum")
+	brainwalletCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	brainwalletCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the signature type, which decides the public key format")
+	brainwalletCmd.Flags().StringVarP(&brainwalletWordlist, "wordlist", "w", "", "Path to the passphrases to try, one per line, stdin if not set")
+	brainwalletCmd.Flags().StringVarP(&brainwalletRules, "rules", "r", "", "Path to a file of hashcat style rules applied to every word")
+	brainwalletCmd.Flags().StringSliceVarP(&brainwalletDerivations, "derivation", "d", []string{"sha256"}, "Derivations from passphrase to key: sha256, sha512, sha3-256, keccak256, blake2b, <hash>x<rounds> or scrypt:salt[:N:r:p]")
+	brainwalletCmd.Flags().StringVarP(&brainwalletTargets, "targets", "t", "", "Path to the hex public keys, P2PKH addresses or Ethereum addresses to look for, one per line")
+	brainwalletCmd.Flags().IntVar(&brainwalletWorkers, "workers", 0, "Number of worker goroutines, one per CPU if zero")
+	brainwalletCmd.Flags().StringVar(&brainwalletCheckpoint, "checkpoint", "", "Path to a file recording progress, which the search resumes from if it exists")
+
+	_ = brainwalletCmd.MarkFlagRequired("targets")
+}
+
+var brainwalletCmd = &cobra.Command{
+	Use:   "brainwallet",
+	Short: "Search a wordlist for passphrases whose keys match the targets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		search, err := newBrainwalletSearch()
+		if err != nil {
+			return err
+		}
+
+		var words io.Reader = os.Stdin
+		if brainwalletWordlist != "" {
+			file, err := os.Open(brainwalletWordlist)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			words = file
+		}
+
+		// Stop on an interrupt so the checkpoint is written.
+		ctx, stop := interruptContext()
+		defer stop()
+
+		result, err := search.Run(ctx, words, func(m *recovery.BrainwalletMatch) {
+			fmt.Printf("Found %s:\n", m.Target)
+			fmt.Printf("  passphrase: %q\n", m.Passphrase)
+			fmt.Printf("  derivation: %s\n", m.Derivation)
+			fmt.Printf("         pub: %x\n", m.Key.Pub)
+			fmt.Printf("        priv: %x\n", m.Key.D)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Searched %d words, %d candidates, found %d of %d targets\n",
+			result.Words, result.Candidates, len(result.Matches), search.Targets.Len())
+		return nil
+	},
+}
+
+func newBrainwalletSearch() (*recovery.BrainwalletSearch, error) {
+	curveID, err := curveIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	sigID, err := recovery.NewSignatureIdentifier(sigName)
+	if err != nil {
+		return nil, err
+	}
+
+	search := &recovery.BrainwalletSearch{
+		CurveID:    curveID,
+		SigID:      sigID,
+		Workers:    brainwalletWorkers,
+		Checkpoint: brainwalletCheckpoint,
+	}
+	for _, spec := range brainwalletDerivations {
+		d, err := recovery.NewBrainwalletDerivation(spec)
+		if err != nil {
+			return nil, err
+		}
+		search.Derivations = append(search.Derivations, d)
+	}
+	if brainwalletRules != "" {
+		if search.Rules, err = recovery.LoadBrainwalletRules(brainwalletRules); err != nil {
+			return nil, err
+		}
+	}
+	if search.Targets, err = recovery.LoadKeyTargets(brainwalletTargets); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// interruptContext returns a context that's done on an interrupt, and the function to release it.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupt)
+		cancel()
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dlpPub      string
+	dlpInterval string
+	dlpOpts     dlp.Options
+)
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(dlpCmd)
+
+	dlpCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve or DSA group of the public key")
+	dlpCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	dlpCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the signature type, which decides the public key format")
+	dlpCmd.Flags().StringVarP(&dlpPub, "pub", "p", "", "Hex public key")
+	dlpCmd.Flags().StringVar(&dlpInterval, "interval", "", "Interval lo:hi the private key lies in, decimal or 0x prefixed hex, the whole group if not set")
+	dlpCmd.Flags().IntVar(&dlpOpts.Workers, "workers", 0, "Number of worker goroutines, one per CPU if zero")
+	dlpCmd.Flags().IntVar(&dlpOpts.DistinguishedBits, "dp-bits", 0, "Zero bits for a distinguished point, fewer store more points, chosen automatically if zero")
+	dlpCmd.Flags().IntVar(&dlpOpts.MaxPoints, "max-points", 0, "Most points to store, chosen automatically if zero")
+}
+
+var dlpCmd = &cobra.Command{
+	Use:   "dlp",
+	Short: "Recover a private key with a generic discrete log, over the whole group or an interval",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curveID, err := curveIdentifier()
+		if err != nil {
+			return err
+		}
+		sigID, err := recovery.NewSignatureIdentifier(sigName)
+		if err != nil {
+			return err
+		}
+		conf, err := recovery.New(curveID, sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			return err
+		}
+
+		pub, err := hex.DecodeString(dlpPub)
+		if err != nil {
+			return err
+		}
+
+		var priv *recovery.PrivateKey
+		if dlpInterval == "" {
+			priv, err = conf.SolveDiscreteLog(pub, &dlpOpts)
+		} else {
+			var lo, hi *big.Int
+			if lo, hi, err = parseInterval(dlpInterval); err != nil {
+				return err
+			}
+			priv, err = conf.SolveInterval(pub, lo, hi, &dlpOpts)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Recovered private key:")
+		fmt.Printf("   pub: %x\n", priv.Pub)
+		fmt.Printf("  priv: %x\n", priv.D)
+
+		return nil
+	},
+}
+
+// parseInterval parses lo:hi.
+func parseInterval(s string) (*big.Int, *big.Int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("interval must be lo:hi: %q", s)
+	}
+
+	lo, ok := new(big.Int).SetString(parts[0], 0)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid interval start: %s", parts[0])
+	}
+	hi, ok := new(big.Int).SetString(parts[1], 0)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid interval end: %s", parts[1])
+	}
+	return lo, hi, nil
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ecdhKey           string
+	ecdhPub           string
+	ecdhTag           string
+	ecdhXOnly         bool
+	ecdhListen        string
+	ecdhOracleCommand string
+	ecdhOracleURL     string
+	ecdhInvalidCurves string
+	ecdhMaxPrimeBits  int
+)
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(ecdhCmd)
+	ecdhCmd.AddCommand(ecdhOracleCmd, ecdhAttackCmd, ecdhPointsCmd)
+
+	for _, cmd := range []*cobra.Command{ecdhOracleCmd, ecdhAttackCmd, ecdhPointsCmd} {
+		cmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve the oracle uses")
+		cmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+		cmd.Flags().BoolVar(&ecdhXOnly, "x-only", false, "The oracle takes only the x coordinate of points")
+	}
+	for _, cmd := range []*cobra.Command{ecdhOracleCmd, ecdhAttackCmd} {
+		cmd.Flags().StringVarP(&ecdhTag, "tag", "t", string(recovery.ECDHTag_SHA256), "What the oracle answers with, sha256 or hmac-sha256 of the shared x coordinate")
+	}
+	for _, cmd := range []*cobra.Command{ecdhAttackCmd, ecdhPointsCmd} {
+		cmd.Flags().StringVar(&ecdhInvalidCurves, "invalid-curves", "", "Path to a JSON list of {\"b\", \"order\"} curves to take points from")
+		cmd.Flags().IntVar(&ecdhMaxPrimeBits, "max-prime-bits", recovery.ECDHDefaultMaxPrimeBits, "Largest subgroup order to brute force, in bits")
+	}
+
+	ecdhOracleCmd.Flags().StringVarP(&ecdhKey, "key", "k", "", "Hex private key, random if not set")
+	ecdhOracleCmd.Flags().StringVar(&ecdhListen, "listen", "", "Serve over HTTP on this address instead of stdin and stdout")
+	ecdhAttackCmd.Flags().StringVarP(&ecdhPub, "pub", "p", "", "Hex public key of the oracle as x||y")
+	ecdhAttackCmd.Flags().StringVar(&ecdhOracleCommand, "oracle-cmd", "", "Shell command for an oracle reading hex points from stdin")
+	ecdhAttackCmd.Flags().StringVar(&ecdhOracleURL, "oracle-url", "", "URL of an oracle taking hex points as the POST body")
+}
+
+var ecdhCmd = &cobra.Command{
+	Use:   "ecdh",
+	Short: "Recover static ECDH keys from oracles that don't validate points",
+}
+
+var ecdhOracleCmd = &cobra.Command{
+	Use:   "oracle",
+	Short: "Run a deliberately vulnerable ECDH oracle answering hex points with hex tags",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curveID, err := curveIdentifier()
+		if err != nil {
+			return err
+		}
+		tag, err := recovery.NewECDHTag(ecdhTag)
+		if err != nil {
+			return err
+		}
+
+		var d *big.Int
+		if ecdhKey != "" {
+			var ok bool
+			if d, ok = new(big.Int).SetString(ecdhKey, 16); !ok {
+				return fmt.Errorf("invalid private key: %s", ecdhKey)
+			}
+		}
+
+		oracle, err := recovery.NewLocalECDHOracle(curveID, d, tag, ecdhXOnly)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Oracle public key: %x\n", oracle.PublicKey())
+
+		if ecdhListen != "" {
+			return http.ListenAndServe(ecdhListen, oracle)
+		}
+		return oracle.Serve(os.Stdin, os.Stdout)
+	},
+}
+
+var ecdhAttackCmd = &cobra.Command{
+	Use:   "attack",
+	Short: "Query an oracle with points of small order and recover its private key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		attack, err := ecdhAttack()
+		if err != nil {
+			return err
+		}
+		if attack.Tag, err = recovery.NewECDHTag(ecdhTag); err != nil {
+			return err
+		}
+		if attack.Pub, err = hex.DecodeString(ecdhPub); err != nil {
+			return err
+		}
+
+		var oracle recovery.ECDHOracle
+		switch {
+		case ecdhOracleCommand != "" && ecdhOracleURL != "":
+			return fmt.Errorf("only one of --oracle-cmd and --oracle-url can be set")
+		case ecdhOracleCommand != "":
+			proc, err := recovery.NewProcessECDHOracle(ecdhOracleCommand)
+			if err != nil {
+				return err
+			}
+			defer proc.Close()
+			oracle = proc
+		case ecdhOracleURL != "":
+			oracle = &recovery.HTTPECDHOracle{URL: ecdhOracleURL}
+		default:
+			return fmt.Errorf("one of --oracle-cmd or --oracle-url is required")
+		}
+
+		result, err := attack.Recover(oracle)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Queries: %d\n", result.Queries)
+		for _, r := range result.Residues {
+			fmt.Printf("  d = ±%s mod %s (%s)\n", r.Residue, r.Modulus, r.Source)
+		}
+		if result.Key == nil {
+			return fmt.Errorf("the residues leave too much of the key to search")
+		}
+
+		fmt.Println("Recovered private key:")
+		fmt.Printf("   pub: %x\n", result.Key.Pub)
+		fmt.Printf("  priv: %x\n", result.Key.D)
+
+		return nil
+	},
+}
+
+var ecdhPointsCmd = &cobra.Command{
+	Use:   "points",
+	Short: "Print a point of each small prime order an attack would query",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		attack, err := ecdhAttack()
+		if err != nil {
+			return err
+		}
+
+		points, err := attack.Points()
+		if err != nil {
+			return err
+		}
+		for _, pt := range points {
+			fmt.Printf("%s\t%s\t%x\n", pt.Source, pt.Order, pt.Point)
+		}
+
+		return nil
+	},
+}
+
+// ecdhAttack returns the attack described by the flags shared by attack and points.
+func ecdhAttack() (*recovery.ECDHAttack, error) {
+	curveID, err := curveIdentifier()
+	if err != nil {
+		return nil, err
+	}
+
+	attack := &recovery.ECDHAttack{CurveID: curveID, XOnly: ecdhXOnly, MaxPrimeBits: ecdhMaxPrimeBits}
+	if ecdhInvalidCurves != "" {
+		if attack.InvalidCurves, err = recovery.LoadInvalidCurves(ecdhInvalidCurves); err != nil {
+			return nil, err
+		}
+	}
+	return attack, nil
+}
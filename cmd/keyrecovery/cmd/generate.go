@@ -11,8 +11,11 @@ func init() { //nolint:gochecknoinits
 	rootCmd.AddCommand(generateCmd)
 
 	generateCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve used to generate signatures")
+	generateCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
 	generateCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the type of signature provided")
+	generateCmd.Flags().StringVar(&sm2ID, "sm2-id", recovery.SM2DefaultID, "Distinguishing ID of the signer for SM2 signatures")
 	generateCmd.Flags().StringVarP(&recoveryMode, "mode", "m", "nonce-reuse", "The algorithm to use when recovering the private key")
+	generateCmd.Flags().StringVarP(&sigFormat, "format", "f", recovery.Format_PubSigMsg, "Layout of each signature, either pub||sig||msg or sig||msg to omit the public key")
 }
 
 var generateCmd = &cobra.Command{
@@ -20,7 +23,7 @@ var generateCmd = &cobra.Command{
 	Short: "TODO",
 	// Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		curveID, err := recovery.NewCurveIdentifier(curveName)
+		curveID, err := curveIdentifier()
 		if err != nil {
 			return err
 		}
@@ -35,7 +38,7 @@ var generateCmd = &cobra.Command{
 			return err
 		}
 
-		conf, err := recovery.New(curveID, sigID, mode)
+		conf, err := newConfig(curveID, sigID, mode)
 		if err != nil {
 			return err
 		}
@@ -46,7 +49,14 @@ var generateCmd = &cobra.Command{
 		}
 
 		for _, sig := range sigs {
-			fmt.Printf("%x\n", sig.Bytes())
+			switch sigFormat {
+			case recovery.Format_PubSigMsg:
+				fmt.Printf("%x\n", sig.Bytes())
+			case recovery.Format_SigMsg:
+				fmt.Printf("%x%x\n", sig.Sig, sig.Msg)
+			default:
+				return fmt.Errorf("unsupported signature format: %s", sigFormat)
+			}
 		}
 
 		return nil
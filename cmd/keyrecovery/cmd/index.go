@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jakecraige/keyrecovery/pkg/index"
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexDir     string
+	indexRunSize int
+)
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexAddCmd, indexLoadCmd, indexQueryCmd)
+
+	indexCmd.PersistentFlags().StringVarP(&indexDir, "dir", "d", ".keyrecovery-index", "Directory the index is stored in")
+	indexCmd.PersistentFlags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve used to generate signatures")
+	indexCmd.PersistentFlags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	indexCmd.PersistentFlags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the type of signature provided")
+	indexCmd.PersistentFlags().StringVar(&sm2ID, "sm2-id", recovery.SM2DefaultID, "Distinguishing ID of the signer for SM2 signatures")
+
+	for _, cmd := range []*cobra.Command{indexAddCmd, indexLoadCmd} {
+		cmd.Flags().StringVarP(&inputPath, "input", "i", "", "Path to file a with newline separated signatures")
+	}
+	indexLoadCmd.Flags().IntVar(&indexRunSize, "run-size", index.DefaultRunSize, "Number of signatures to sort in memory at a time")
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Maintain a persistent index of signature r values to detect nonce reuse incrementally",
+}
+
+var indexAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add signatures to the index, reporting any which reuse a previously seen nonce",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		r, closeInput, err := openInput()
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		entries := make([]*index.Entry, 0)
+		sigs := newEntryScanner(r, conf)
+		for {
+			entry, err := sigs.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+
+		matches, err := idx.Add(string(conf.CurveID()), entries)
+		if err != nil {
+			return err
+		}
+
+		printMatches(matches)
+		return nil
+	},
+}
+
+var indexLoadCmd = &cobra.Command{
+	Use:   "load",
+	Short: "Bulk load a large set of signatures into the index using an external sort",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		r, closeInput, err := openInput()
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		matches, err := idx.BulkLoad(string(conf.CurveID()), newEntryScanner(r, conf), indexRunSize)
+		if err != nil {
+			return err
+		}
+
+		printMatches(matches)
+		return nil
+	},
+}
+
+var indexQueryCmd = &cobra.Command{
+	Use:   "query [r...]",
+	Short: "Print the indexed signatures with the given hex encoded r values",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf, idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+		curveID := conf.CurveID()
+
+		byteLen, err := conf.RLen()
+		if err != nil {
+			return err
+		}
+		for _, arg := range args {
+			r, err := hex.DecodeString(arg)
+			if err != nil {
+				return err
+			}
+			if len(r) > byteLen {
+				return fmt.Errorf("r value %s is too large for curve %s", arg, curveID)
+			}
+
+			// Index keys are the fixed width r values from the signatures so pad any shorter input.
+			padded := make([]byte, byteLen)
+			copy(padded[byteLen-len(r):], r)
+
+			entries, err := idx.Query(string(curveID), padded)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				fmt.Printf("%x\n", entry.Data)
+			}
+		}
+
+		return nil
+	},
+}
+
+func openIndex() (*recovery.Config, *index.Index, error) {
+	curveID, err := curveIdentifier()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sigID, err := recovery.NewSignatureIdentifier(sigName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The index only deals with r values so the recovery mode doesn't matter.
+	conf, err := newConfig(curveID, sigID, recovery.Recovery_NonceReuse)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idx, err := index.Open(indexDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conf, idx, nil
+}
+
+func openInput() (io.Reader, func(), error) {
+	if inputPath == "" {
+		return os.Stdin, func() {}, nil
+	}
+
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
+}
+
+func printMatches(matches []*index.Match) {
+	for _, match := range matches {
+		fmt.Printf("Nonce reuse detected, r=%x:\n", match.R)
+		for _, entry := range match.Entries {
+			fmt.Printf("  %x\n", entry.Data)
+		}
+	}
+}
+
+// entryScanner reads newline separated hex signatures and converts them to index entries.
+type entryScanner struct {
+	scanner *bufio.Scanner
+	conf    *recovery.Config
+}
+
+func newEntryScanner(r io.Reader, conf *recovery.Config) *entryScanner {
+	return &entryScanner{scanner: bufio.NewScanner(r), conf: conf}
+}
+
+func (s *entryScanner) Next() (*index.Entry, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	data, err := hex.DecodeString(s.scanner.Text())
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := s.conf.ParseSignature(data, recovery.Format_PubSigMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.conf.NonceCommitment(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &index.Entry{R: r, Data: data}, nil
+}
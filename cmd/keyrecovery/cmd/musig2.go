@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var musig2Nonces int
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(musig2Cmd)
+	musig2Cmd.AddCommand(musig2GenerateCmd, musig2RecoverCmd)
+
+	musig2GenerateCmd.Flags().IntVarP(&musig2Nonces, "nonces", "n", 2, "Number of nonces each signer contributes to a session")
+	musig2RecoverCmd.Flags().StringVarP(&inputPath, "input", "i", "", "Path to a JSON file of sessions")
+}
+
+var musig2Cmd = &cobra.Command{
+	Use:   "musig2",
+	Short: "Simulate MuSig2 signing sessions with a signer reusing nonces and recover its key",
+}
+
+var musig2GenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate sessions where the first signer reuses its nonces with different co-signers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, err := recovery.SimulateMuSig2NonceReuse(musig2Nonces)
+		if err != nil {
+			return err
+		}
+
+		out := make([]*musig2SessionJSON, len(sessions))
+		for i, session := range sessions {
+			out[i] = newMuSig2SessionJSON(session)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	},
+}
+
+var musig2RecoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Recover a signer's key from sessions in which it reused its nonces",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, closeInput, err := openInput()
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		var in []*musig2SessionJSON
+		if err := json.NewDecoder(r).Decode(&in); err != nil {
+			return err
+		}
+
+		sessions := make([]*recovery.MuSig2Session, len(in))
+		for i, s := range in {
+			if sessions[i], err = s.session(); err != nil {
+				return err
+			}
+		}
+
+		priv, err := recovery.RecoverMuSig2NonceReuse(sessions)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Recovered private key:")
+		fmt.Printf("   pub: %x\n", priv.Pub)
+		fmt.Printf("  priv: %x\n", priv.D)
+
+		return nil
+	},
+}
+
+// musig2SessionJSON is the hex encoded JSON representation of a session.
+type musig2SessionJSON struct {
+	PubKeys    []string   `json:"pubkeys"`
+	PubNonces  [][]string `json:"pubnonces"`
+	Msg        string     `json:"msg"`
+	Signer     int        `json:"signer"`
+	PartialSig string     `json:"partial_sig"`
+	Sig        string     `json:"sig,omitempty"`
+}
+
+func newMuSig2SessionJSON(s *recovery.MuSig2Session) *musig2SessionJSON {
+	out := &musig2SessionJSON{
+		Msg:        hex.EncodeToString(s.Msg),
+		Signer:     s.Signer,
+		PartialSig: hex.EncodeToString(s.PartialSig),
+		Sig:        hex.EncodeToString(s.Sig),
+	}
+	for i, pub := range s.PubKeys {
+		out.PubKeys = append(out.PubKeys, hex.EncodeToString(pub))
+
+		nonces := make([]string, len(s.PubNonces[i]))
+		for j, nonce := range s.PubNonces[i] {
+			nonces[j] = hex.EncodeToString(nonce)
+		}
+		out.PubNonces = append(out.PubNonces, nonces)
+	}
+
+	return out
+}
+
+func (s *musig2SessionJSON) session() (*recovery.MuSig2Session, error) {
+	var err error
+	out := &recovery.MuSig2Session{Signer: s.Signer}
+	if out.Msg, err = hex.DecodeString(s.Msg); err != nil {
+		return nil, err
+	}
+	if out.PartialSig, err = hex.DecodeString(s.PartialSig); err != nil {
+		return nil, err
+	}
+	if out.Sig, err = hex.DecodeString(s.Sig); err != nil {
+		return nil, err
+	}
+
+	for _, pub := range s.PubKeys {
+		b, err := hex.DecodeString(pub)
+		if err != nil {
+			return nil, err
+		}
+		out.PubKeys = append(out.PubKeys, b)
+	}
+	for _, nonces := range s.PubNonces {
+		decoded := make([][]byte, len(nonces))
+		for j, nonce := range nonces {
+			if decoded[j], err = hex.DecodeString(nonce); err != nil {
+				return nil, err
+			}
+		}
+		out.PubNonces = append(out.PubNonces, decoded)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jakecraige/keyrecovery/pkg/prng"
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nonceSeedModel      string
+	nonceSeedTime       string
+	nonceSeedWindow     time.Duration
+	nonceSeedResolution string
+	nonceSeedWorkers    int
+)
+
+// seedResolutions are the units of time a seed can count.
+var seedResolutions = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(nonceSeedCmd)
+
+	nonceSeedCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve used to generate signatures")
+	nonceSeedCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	nonceSeedCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the type of signature provided")
+	nonceSeedCmd.Flags().StringVar(&sm2ID, "sm2-id", recovery.SM2DefaultID, "Distinguishing ID of the signer for SM2 signatures")
+	nonceSeedCmd.Flags().StringVarP(&inputPath, "input", "i", "", "Path to file a with newline separated signatures, all by one signer")
+	nonceSeedCmd.Flags().StringVarP(&sigFormat, "format", "f", recovery.Format_PubSigMsg, "Layout of each signature, either pub||sig||msg or sig||msg to recover public keys")
+	nonceSeedCmd.Flags().StringVarP(&nonceSeedModel, "model", "m", "go-math-rand", "PRNG the nonces were drawn from: go-math-rand, python-random, glibc-rand, mt19937, java-random or bx-seed")
+	nonceSeedCmd.Flags().StringVarP(&nonceSeedTime, "time", "t", "", "Approximate signing time, RFC 3339 or Unix seconds")
+	nonceSeedCmd.Flags().DurationVarP(&nonceSeedWindow, "window", "w", time.Hour, "How far either side of --time to search")
+	nonceSeedCmd.Flags().StringVarP(&nonceSeedResolution, "resolution", "r", "s", "Unit of the time the PRNG was seeded with: s, ms, us or ns")
+	nonceSeedCmd.Flags().IntVar(&nonceSeedWorkers, "workers", 0, "Number of worker goroutines, one per CPU if zero")
+
+	_ = nonceSeedCmd.MarkFlagRequired("time")
+}
+
+var nonceSeedCmd = &cobra.Command{
+	Use:   "nonce-seed",
+	Short: "Recover a private key from a signature whose nonce came from a PRNG seeded with the time",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curveID, err := curveIdentifier()
+		if err != nil {
+			return err
+		}
+		sigID, err := recovery.NewSignatureIdentifier(sigName)
+		if err != nil {
+			return err
+		}
+		conf, err := newConfig(curveID, sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			return err
+		}
+		model, err := prng.Lookup(nonceSeedModel)
+		if err != nil {
+			return err
+		}
+		from, to, err := seedWindow(nonceSeedTime, nonceSeedWindow, nonceSeedResolution)
+		if err != nil {
+			return err
+		}
+
+		input, closeInput, err := openInput()
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+		sigs, err := readSignatures(conf, input)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+
+		search := &recovery.NonceSeedSearch{
+			CurveID: curveID,
+			SigID:   sigID,
+			Model:   model,
+			SM2ID:   sm2ID,
+			From:    from,
+			To:      to,
+			Workers: nonceSeedWorkers,
+		}
+		result, err := search.Run(ctx, sigs)
+		if err != nil {
+			return err
+		}
+		if result.Key == nil {
+			return fmt.Errorf("no nonce found in %d seeds", result.Seeds)
+		}
+
+		fmt.Println("Recovered private key:")
+		fmt.Printf("  seed: %d\n", result.Seed)
+		fmt.Printf("   pub: %x\n", result.Key.Pub)
+		fmt.Printf("  priv: %x\n", result.Key.D)
+
+		return nil
+	},
+}
+
+// seedWindow returns the seeds counting the given unit since the Unix epoch within window of the
+// time.
+func seedWindow(at string, window time.Duration, resolution string) (uint64, uint64, error) {
+	unit, ok := seedResolutions[resolution]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown resolution %s, expected s, ms, us or ns", resolution)
+	}
+
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		secs, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("time must be RFC 3339 or Unix seconds: %s", at)
+		}
+		t = time.Unix(secs, 0)
+	}
+
+	from, to := t.Add(-window).UnixNano()/int64(unit), t.Add(window).UnixNano()/int64(unit)
+	if from < 0 {
+		from = 0
+	}
+	return uint64(from), uint64(to), nil
+}
+
+// readSignatures parses newline separated hex signatures.
+func readSignatures(conf *recovery.Config, r io.Reader) ([]*recovery.Signature, error) {
+	var sigs []*recovery.Signature
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		data, err := hex.DecodeString(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		sig, err := conf.ParseSignature(data, sigFormat)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, scanner.Err()
+}
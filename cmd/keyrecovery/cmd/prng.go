@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jakecraige/keyrecovery/pkg/hdwallet"
+	"github.com/jakecraige/keyrecovery/pkg/prng"
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prngModel   string
+	prngSeeds   string
+	prngTargets string
+	prngWorkers int
+
+	prngWallet      string
+	prngPaths       string
+	prngIndexes     int
+	prngEntropyBits int
+	prngPassphrase  string
+)
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(prngCmd)
+
+	prngCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve of the keys, S256 for Bitcoin and Ethereum")
+	prngCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	prngCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the signature type, which decides the public key format")
+	prngCmd.Flags().StringVarP(&prngModel, "model", "m", "bx-seed", "PRNG and byte conversion the keys were made with: bx-seed, mt19937, glibc-rand, java-random, go-math-rand, python-random or debian-openssl-<i386|amd64>-<bits>")
+	prngCmd.Flags().StringVar(&prngSeeds, "seeds", "", "Seeds lo:hi to try, decimal or 0x prefixed hex, every seed of the model if not set")
+	prngCmd.Flags().StringVarP(&prngTargets, "targets", "t", "", "Path to the hex public keys, P2PKH addresses or Ethereum addresses to look for, one per line")
+	prngCmd.Flags().IntVar(&prngWorkers, "workers", 0, "Number of worker goroutines, one per CPU if zero")
+	prngCmd.Flags().StringVar(&prngWallet, "wallet", "", "Use the output as wallet entropy: bip39 for a mnemonic as with bx mnemonic-new, bip32 for the seed itself as with bx hd-new")
+	prngCmd.Flags().StringVar(&prngPaths, "paths", "m/44'/0'/0'/0/i,m/0", "Comma separated derivation paths of the wallet keys, where a last index of i stands for each of the first --indexes")
+	prngCmd.Flags().IntVar(&prngIndexes, "indexes", 5, "Number of indexes i in the derivation paths")
+	prngCmd.Flags().IntVar(&prngEntropyBits, "entropy-bits", 192, "Bits of wallet entropy, 192 unless bx seed was given -b")
+	prngCmd.Flags().StringVar(&prngPassphrase, "passphrase", "", "BIP39 passphrase of the wallet mnemonic")
+
+	_ = prngCmd.MarkFlagRequired("targets")
+}
+
+var prngCmd = &cobra.Command{
+	Use:   "prng",
+	Short: "Search the seeds of a weak PRNG for keys matching the targets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curveID, err := curveIdentifier()
+		if err != nil {
+			return err
+		}
+		sigID, err := recovery.NewSignatureIdentifier(sigName)
+		if err != nil {
+			return err
+		}
+		model, err := prng.Lookup(prngModel)
+		if err != nil {
+			return err
+		}
+		targets, err := recovery.LoadKeyTargets(prngTargets)
+		if err != nil {
+			return err
+		}
+
+		search := &recovery.PRNGSearch{
+			CurveID: curveID,
+			SigID:   sigID,
+			Model:   model,
+			Targets: targets,
+			To:      model.MaxSeed(),
+			Workers: prngWorkers,
+		}
+		if prngSeeds != "" {
+			lo, hi, err := parseInterval(prngSeeds)
+			if err != nil {
+				return err
+			}
+			if !lo.IsUint64() || !hi.IsUint64() {
+				return fmt.Errorf("seeds must be non-negative 64-bit integers: %s", prngSeeds)
+			}
+			search.From, search.To = lo.Uint64(), hi.Uint64()
+		}
+		if prngWallet != "" {
+			if search.Wallet, err = walletDerivation(); err != nil {
+				return err
+			}
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+
+		result, err := search.Run(ctx, func(m *recovery.PRNGMatch) {
+			fmt.Printf("Found %s:\n", m.Target)
+			fmt.Printf("  seed: %d\n", m.Seed)
+			if m.Path != "" {
+				fmt.Printf("  path: %s\n", m.Path)
+			}
+			fmt.Printf("   pub: %x\n", m.Key.Pub)
+			fmt.Printf("  priv: %x\n", m.Key.D)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Searched %d seeds, found %d of %d targets\n", result.Seeds, len(result.Matches), targets.Len())
+		return nil
+	},
+}
+
+// walletDerivation returns the wallet derivation from the flags.
+func walletDerivation() (*hdwallet.Derivation, error) {
+	if prngWallet != "bip39" && prngWallet != "bip32" {
+		return nil, fmt.Errorf("wallet must be bip39 or bip32: %s", prngWallet)
+	}
+	paths, err := hdwallet.ParsePaths(prngPaths, prngIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hdwallet.Derivation{
+		Mnemonic:    prngWallet == "bip39",
+		Passphrase:  prngPassphrase,
+		EntropyBits: prngEntropyBits,
+		Paths:       paths,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jakecraige/keyrecovery/pkg/recovery"
+	"github.com/spf13/cobra"
+)
+
+func init() { //nolint:gochecknoinits
+	rootCmd.AddCommand(pubkeysCmd)
+
+	pubkeysCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve used to generate signatures")
+	pubkeysCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	pubkeysCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the type of signature provided")
+	pubkeysCmd.Flags().StringVarP(&inputPath, "input", "i", "", "Path to file a with newline separated sig||msg signatures")
+}
+
+var pubkeysCmd = &cobra.Command{
+	Use:   "pubkeys",
+	Short: "Recover the candidate public keys of signatures and cross-match them to identify the signers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curveID, err := curveIdentifier()
+		if err != nil {
+			return err
+		}
+
+		sigID, err := recovery.NewSignatureIdentifier(sigName)
+		if err != nil {
+			return err
+		}
+
+		conf, err := recovery.New(curveID, sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			return err
+		}
+
+		r, closeInput, err := openInput()
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+
+		var sigs []*recovery.Signature
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			data, err := hex.DecodeString(scanner.Text())
+			if err != nil {
+				return err
+			}
+
+			sig, err := conf.ParseSignature(data, recovery.Format_SigMsg)
+			if err != nil {
+				return err
+			}
+			sigs = append(sigs, sig)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		for i, sig := range sigs {
+			candidates, err := conf.RecoverPublicKeys(sig)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Signature %d candidates:\n", i+1)
+			for _, pub := range candidates {
+				fmt.Printf("  %x\n", pub)
+			}
+		}
+
+		if err := conf.RecoverSigners(sigs); err != nil {
+			return err
+		}
+		fmt.Println("Signers:")
+		for i, sig := range sigs {
+			fmt.Printf("  %d: %x\n", i+1, sig.Pub)
+		}
+
+		return nil
+	},
+}
@@ -1,8 +1,12 @@
 package cmd
 
 import (
-	"crypto/ecdsa"
+	"bufio"
+	"bytes"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/jakecraige/keyrecovery/pkg/recovery"
@@ -11,7 +15,9 @@ import (
 
 var (
 	curveName    string
+	curveFile    string
 	sigName      string
+	sm2ID        string
 	sigFormat    string
 	recoveryMode string
 	inputPath    string
@@ -20,22 +26,30 @@ var (
 func init() { //nolint:gochecknoinits
 	rootCmd.AddCommand(recoverCmd)
 
-	recoverCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve used to generate signatures")
-	recoverCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the type of signature provided")
+	recoverCmd.Flags().StringVarP(&curveName, "curve", "c", "P256", "Name of the elliptic curve used to generate signatures, or auto to detect it")
+	recoverCmd.Flags().StringVar(&curveFile, "curve-file", "", "Path to a JSON file with custom short Weierstrass curve parameters, used instead of --curve")
+	recoverCmd.Flags().StringVarP(&sigName, "sig-type", "s", "ECDSA-SHA256", "Identifier for the type of signature provided, or auto to detect it")
+	recoverCmd.Flags().StringVar(&sm2ID, "sm2-id", recovery.SM2DefaultID, "Distinguishing ID of the signer for SM2 signatures")
 	recoverCmd.Flags().StringVarP(&recoveryMode, "mode", "m", "nonce-reuse", "The algorithm to use when recovering the private key")
 	recoverCmd.Flags().StringVarP(&inputPath, "input", "i", "", "Path to file a with newline separated signatures")
+	recoverCmd.Flags().StringVarP(&sigFormat, "format", "f", recovery.Format_PubSigMsg, "Layout of each signature, either pub||sig||msg or sig||msg to recover public keys")
 }
 
 var recoverCmd = &cobra.Command{
 	Use:   "recover",
 	Short: "TODO",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		curveID, err := recovery.NewCurveIdentifier(curveName)
-		if err != nil {
-			return err
+		var input io.Reader = os.Stdin
+		if inputPath != "" {
+			file, err := os.Open(inputPath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			input = file
 		}
 
-		sigID, err := recovery.NewSignatureIdentifier(sigName)
+		curveID, sigID, input, err := resolveIdentifiers(input)
 		if err != nil {
 			return err
 		}
@@ -45,25 +59,111 @@ var recoverCmd = &cobra.Command{
 			return err
 		}
 
-		conf, err := recovery.New(curveID, sigID, mode)
+		conf, err := newConfig(curveID, sigID, mode)
 		if err != nil {
 			return err
 		}
 
-		var priv *ecdsa.PrivateKey
-		if inputPath != "" {
-			priv, err = conf.RecoverFromFile(inputPath, "r||s")
-		} else {
-			priv, err = conf.RecoverFromReader(os.Stdin, "r||s")
+		switch {
+		case mode == recovery.Recovery_WeakCurve:
+			analysis, err := conf.AnalyzeCurve()
+			if err != nil {
+				return err
+			}
+			fmt.Println(analysis)
+		case curveID.IsSingular():
+			fmt.Fprintf(os.Stderr, "Curve is singular, any key can be recovered with --mode=%s\n", recovery.Recovery_WeakCurve)
+		case curveID.IsAnomalous():
+			fmt.Fprintf(os.Stderr, "Curve is anomalous, any key can be recovered with --mode=%s\n", recovery.Recovery_WeakCurve)
 		}
+
+		priv, err := conf.RecoverFromReader(input, sigFormat)
 		if err != nil {
 			return err
 		}
 
 		fmt.Println("Recovered private key:")
-		fmt.Printf("   pub: %x%x\n", priv.PublicKey.X, priv.PublicKey.Y)
+		fmt.Printf("   pub: %x\n", priv.Pub)
 		fmt.Printf("  priv: %x\n", priv.D)
 
 		return nil
 	},
 }
+
+// curveIdentifier returns the curve from --curve-file if it's set, otherwise the one named by
+// --curve.
+func curveIdentifier() (recovery.CurveIdentifier, error) {
+	if curveFile != "" {
+		return recovery.LoadCurveFile(curveFile)
+	}
+
+	return recovery.NewCurveIdentifier(curveName)
+}
+
+// newConfig returns the config for the curve, signature type and mode, with the distinguishing ID
+// from --sm2-id for SM2.
+func newConfig(curveID recovery.CurveIdentifier, sigID recovery.SignatureIdentifier, mode recovery.RecoveryMode) (*recovery.Config, error) {
+	conf, err := recovery.New(curveID, sigID, mode)
+	if err != nil || sm2ID == recovery.SM2DefaultID {
+		return conf, err
+	}
+	if err := conf.SetSM2ID(sm2ID); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// resolveIdentifiers returns the curve and signature type from the flags, detecting them from the
+// signatures when either is auto. Detection has to read the input, so the returned reader replays
+// it.
+func resolveIdentifiers(input io.Reader) (recovery.CurveIdentifier, recovery.SignatureIdentifier, io.Reader, error) {
+	var curves []recovery.CurveIdentifier
+	if curveFile != "" || curveName != "auto" {
+		curveID, err := curveIdentifier()
+		if err != nil {
+			return "", "", nil, err
+		}
+		curves = []recovery.CurveIdentifier{curveID}
+	}
+
+	var sigIDs []recovery.SignatureIdentifier
+	if sigName != "auto" {
+		sigID, err := recovery.NewSignatureIdentifier(sigName)
+		if err != nil {
+			return "", "", nil, err
+		}
+		sigIDs = []recovery.SignatureIdentifier{sigID}
+	}
+
+	if curves != nil && sigIDs != nil {
+		return curves[0], sigIDs[0], input, nil
+	}
+
+	buf, err := ioutil.ReadAll(input)
+	if err != nil {
+		return "", "", nil, err
+	}
+	var data [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line, err := hex.DecodeString(scanner.Text())
+		if err != nil {
+			return "", "", nil, err
+		}
+		data = append(data, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", nil, err
+	}
+
+	detections, err := recovery.Detect(data, sigFormat, curves, sigIDs)
+	if err != nil {
+		return "", "", nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Detected %s\n", detections[0])
+	for _, other := range detections[1:] {
+		fmt.Fprintf(os.Stderr, "  also matched %s\n", other)
+	}
+
+	return detections[0].CurveID, detections[0].SigID, bytes.NewReader(buf), nil
+}
@@ -0,0 +1,61 @@
+package dlp
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// BabyStepGiantStep returns d in [0, bound) with g^d = h. The table holds ⌈√bound⌉ baby steps, or
+// MaxPoints if that's fewer, in which case it takes bound/MaxPoints giant steps instead of √bound.
+func BabyStepGiantStep(grp Group, g, h Element, bound *big.Int, opts *Options) (*Result, error) {
+	if Equal(h, grp.Identity()) {
+		return &Result{D: new(big.Int)}, nil
+	}
+	if bound.Sign() <= 0 || Equal(g, grp.Identity()) {
+		return nil, fmt.Errorf("no discrete log below %s", bound)
+	}
+
+	m := new(big.Int).Sqrt(bound)
+	if new(big.Int).Mul(m, m).Cmp(bound) < 0 {
+		m.Add(m, big.NewInt(1))
+	}
+	if limit := big.NewInt(int64(opts.maxPoints())); m.Cmp(limit) > 0 {
+		m = limit
+	}
+	giant := new(big.Int).Add(bound, m)
+	giant.Sub(giant, big.NewInt(1))
+	giant.Div(giant, m)
+	if !giant.IsInt64() {
+		return nil, fmt.Errorf("%s giant steps are too many", giant)
+	}
+
+	// Baby steps g^j, keeping the first j when the order of g is smaller than m.
+	babies := m.Int64()
+	table := make(map[string]int64, babies)
+	x := grp.Identity()
+	for j := int64(0); j < babies; j++ {
+		key := grp.Key(x)
+		if _, ok := table[key]; ok {
+			break
+		}
+		table[key] = j
+		x = grp.Op(x, g)
+	}
+
+	// Giant steps h·g^(-i·m)
+	step := grp.Inverse(grp.Exp(g, m))
+	x = h
+	for i := int64(0); i < giant.Int64(); i++ {
+		if j, ok := table[grp.Key(x)]; ok {
+			d := new(big.Int).Mul(big.NewInt(i), m)
+			d.Add(d, big.NewInt(j))
+			if d.Cmp(bound) >= 0 {
+				break
+			}
+			return &Result{D: d, Steps: uint64(babies + i), Points: len(table)}, nil
+		}
+		x = grp.Op(x, step)
+	}
+
+	return nil, fmt.Errorf("no discrete log below %s", bound)
+}
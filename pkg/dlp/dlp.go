@@ -0,0 +1,187 @@
+// Package dlp implements generic discrete log solvers, which need nothing but the group operation
+// and so work the same on curve points, finite fields and anything else. Baby-step giant-step takes
+// about √n operations and stores as many elements, while Pollard's rho, in the parallel form of van
+// Oorschot and Wiener, takes about as long but only stores distinguished points. On groups where
+// inverting is cheap, such as curve points, rho walks on the pairs {x, x⁻¹} instead of the elements,
+// which saves a further factor of √2.
+//
+// Either is only practical up to orders of about 2^64, so they're for toy and deliberately weakened
+// groups and for the prime factors of smooth orders in Pohlig–Hellman.
+//
+// Discrete logs known to lie in an interval [lo, hi], such as keys with most of their bits leaked,
+// are found with Pollard's kangaroo (lambda) method instead, also in the parallel form of van
+// Oorschot and Wiener, in about 2·√(hi - lo) operations whatever the order. Tame kangaroos start at
+// known powers of g and wild ones at h times known powers of g. Every kangaroo jumps by a power of g
+// picked by the hash of its position, so two kangaroos landing on the same element follow the same
+// path from then on. A tame and a wild kangaroo meeting at a distinguished element gives d, while two
+// of the same kind meeting have merged and the later one is restarted elsewhere.
+package dlp
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+)
+
+// DefaultMaxPoints bounds the elements stored, the table of baby steps or of distinguished points.
+// Elements are around 100 bytes each with their map entries.
+const DefaultMaxPoints = 1 << 18
+
+// Element is an element of a group, the coordinates of a point or the coefficients of a field
+// element.
+type Element []*big.Int
+
+// Equal reports whether x and y are the same element.
+func Equal(x, y Element) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].Cmp(y[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Group is a cyclic group written multiplicatively.
+type Group interface {
+	Identity() Element
+	Op(x, y Element) Element
+	Inverse(x Element) Element
+	Exp(x Element, k *big.Int) Element
+
+	// Key returns an encoding of the element for use as a map key, the same for equal elements.
+	Key(x Element) string
+}
+
+// Negator is a group where inverting an element is about as cheap as comparing two, which lets rho
+// use the negation map.
+type Negator interface {
+	Group
+	Negate(x Element) Element
+}
+
+// Options tune the solvers. The zero value picks everything automatically.
+type Options struct {
+	// Workers is the number of goroutines walking for rho and the kangaroo, runtime.NumCPU() if
+	// zero. Each kangaroo worker walks one tame and one wild kangaroo.
+	Workers int
+
+	// DistinguishedBits is how many bits of an element's hash must be zero for rho or the kangaroo
+	// to store it. Fewer bits notice a collision sooner after it happens but store more elements.
+	// Chosen from the order or interval width and the number of workers if zero.
+	DistinguishedBits int
+
+	// MaxPoints is how many elements can be stored, DefaultMaxPoints if zero. Baby-step giant-step
+	// takes more giant steps when the table of √n baby steps doesn't fit, and rho and the kangaroo
+	// give up when the table of distinguished points is full.
+	MaxPoints int
+
+	// MaxSteps is how many steps rho or the kangaroo makes in total before giving up, eight times
+	// the expected number if zero. The kangaroo only ends on its own when d is found, so this is
+	// what stops it when h isn't in the interval.
+	MaxSteps uint64
+}
+
+func (o *Options) maxPoints() int {
+	if o == nil || o.MaxPoints <= 0 {
+		return DefaultMaxPoints
+	}
+	return o.MaxPoints
+}
+
+// Result is the discrete log along with the work it took.
+type Result struct {
+	D      *big.Int
+	Steps  uint64
+	Points int
+}
+
+// Solve returns d in [0, n) with g^d = h, where n is the order of g. It uses baby-step giant-step
+// when its table of √n elements fits in MaxPoints and rho otherwise.
+func Solve(grp Group, g, h Element, n *big.Int, opts *Options) (*Result, error) {
+	if new(big.Int).Sqrt(n).Cmp(big.NewInt(int64(opts.maxPoints()))) < 0 {
+		return BabyStepGiantStep(grp, g, h, n, opts)
+	}
+	return Rho(grp, g, h, n, opts)
+}
+
+// CurveGroup returns the group of points of an elliptic curve in short Weierstrass form, with the
+// point at infinity as (0, 0) like crypto/elliptic unless the curve has an Infinity method.
+func CurveGroup(curve elliptic.Curve) Negator {
+	return curveGroup{curve}
+}
+
+// infinityCurve is a curve whose point at infinity isn't (0, 0), since that's a point of the curve.
+type infinityCurve interface {
+	Infinity() (x, y *big.Int)
+}
+
+type curveGroup struct {
+	curve elliptic.Curve
+}
+
+func (g curveGroup) Identity() Element {
+	if c, ok := g.curve.(infinityCurve); ok {
+		x, y := c.Infinity()
+		return Element{x, y}
+	}
+	return Element{new(big.Int), new(big.Int)}
+}
+
+func (g curveGroup) Op(x, y Element) Element {
+	rx, ry := g.curve.Add(x[0], x[1], y[0], y[1])
+	return Element{rx, ry}
+}
+
+func (g curveGroup) Inverse(x Element) Element {
+	return g.Negate(x)
+}
+
+func (g curveGroup) Negate(x Element) Element {
+	if Equal(x, g.Identity()) {
+		return g.Identity()
+	}
+	y := new(big.Int)
+	if x[1].Sign() != 0 {
+		y.Sub(g.curve.Params().P, x[1])
+	}
+	return Element{new(big.Int).Set(x[0]), y}
+}
+
+func (g curveGroup) Exp(x Element, k *big.Int) Element {
+	// Some curves, secp256k1 among them, return nil rather than (0, 0) for a zero k.
+	if k.Sign() == 0 {
+		return g.Identity()
+	}
+	rx, ry := g.curve.ScalarMult(x[0], x[1], k.Bytes())
+	return Element{rx, ry}
+}
+
+func (g curveGroup) Key(x Element) string {
+	return EncodeElement(x, (g.curve.Params().P.BitLen()+7)/8)
+}
+
+// EncodeElement concatenates the components of an element, each padded to byteLen, for use as a
+// Key.
+func EncodeElement(x Element, byteLen int) string {
+	out := make([]byte, 0, len(x)*byteLen)
+	for _, v := range x {
+		b := v.Bytes()
+		for i := len(b); i < byteLen; i++ {
+			out = append(out, 0)
+		}
+		out = append(out, b...)
+	}
+	return string(out)
+}
+
+// checkSubgroup returns an error unless h^n is the identity, which it must be for h to be a power
+// of g when n is the order of g.
+func checkSubgroup(grp Group, h Element, n *big.Int) error {
+	if !Equal(grp.Exp(h, n), grp.Identity()) {
+		return fmt.Errorf("element is not in the subgroup of order %s", n)
+	}
+	return nil
+}
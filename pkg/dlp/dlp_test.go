@@ -0,0 +1,196 @@
+package dlp_test
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+// Curves y^2 = x^3 - 3x + b of prime order n.
+var curves = []struct {
+	bits            int
+	p, n, b, gx, gy string
+}{
+	{32, "0xcded0de7", "0xcded8647", "0x7ea6d07e", "0x54a65392", "0xa5daf4c5"},
+	{40, "0xc82d3471b3", "0xc82d36ebd9", "0x6c0b61c2dc", "0x9d3f16136d", "0x9741ffa8e"},
+	{48, "0xcdb6306c3ead", "0xcdb6312ce26f", "0x1ae2b9a0add1", "0x72f42899f50c", "0x9800f77e9e8a"},
+	{56, "0xecf5139f7298fd", "0xecf513a5d2fdeb", "0x60bfdc73675df4", "0x5c272893ceba2e", "0x497cf70463df34"},
+	{64, "0xea08ca1a17b86b4b", "0xea08ca1b0b63097b", "0x2ee788b87007ec2d", "0x85feffbc0c267ac4", "0x31ef758176869e14"},
+}
+
+func hexInt(s string) *big.Int {
+	x, _ := new(big.Int).SetString(s, 0)
+	return x
+}
+
+func curve(bits int) *elliptic.CurveParams {
+	for _, c := range curves {
+		if c.bits == bits {
+			return &elliptic.CurveParams{
+				P: hexInt(c.p), N: hexInt(c.n), B: hexInt(c.b),
+				Gx: hexInt(c.gx), Gy: hexInt(c.gy), BitSize: bits,
+			}
+		}
+	}
+	panic(fmt.Sprintf("no %d-bit curve", bits))
+}
+
+// zpGroup is the subgroup of Z_p* of prime order, which has no cheap inverse.
+type zpGroup struct {
+	p *big.Int
+}
+
+func (g zpGroup) Identity() dlp.Element { return dlp.Element{big.NewInt(1)} }
+
+func (g zpGroup) Op(x, y dlp.Element) dlp.Element {
+	r := new(big.Int).Mul(x[0], y[0])
+	return dlp.Element{r.Mod(r, g.p)}
+}
+
+func (g zpGroup) Inverse(x dlp.Element) dlp.Element {
+	return dlp.Element{new(big.Int).ModInverse(x[0], g.p)}
+}
+
+func (g zpGroup) Exp(x dlp.Element, k *big.Int) dlp.Element {
+	return dlp.Element{new(big.Int).Exp(x[0], k, g.p)}
+}
+
+func (g zpGroup) Key(x dlp.Element) string { return string(x[0].Bytes()) }
+
+type problem struct {
+	grp  dlp.Group
+	g, h dlp.Element
+	n, d *big.Int
+}
+
+func curveProblem(params *elliptic.CurveParams, d *big.Int) *problem {
+	grp := dlp.CurveGroup(params)
+	g := dlp.Element{params.Gx, params.Gy}
+	return &problem{grp: grp, g: g, h: grp.Exp(g, d), n: params.N, d: d}
+}
+
+func zpProblem(d *big.Int) *problem {
+	grp := zpGroup{hexInt("0x66f6c32b8b473ec7")}
+	g := dlp.Element{hexInt("0x1dc94e81ad0fada6")}
+	return &problem{grp: grp, g: g, h: grp.Exp(g, d), n: hexInt("0xcded8647"), d: d}
+}
+
+func TestCurves(t *testing.T) {
+	for _, c := range curves {
+		params := curve(c.bits)
+		if !params.IsOnCurve(params.Gx, params.Gy) {
+			t.Errorf("%d-bit: G is not on the curve", c.bits)
+		}
+		if x, y := params.ScalarBaseMult(params.N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+			t.Errorf("%d-bit: n·G is not the point at infinity", c.bits)
+		}
+	}
+}
+
+func TestBabyStepGiantStep(t *testing.T) {
+	var tests = []struct {
+		name  string
+		p     *problem
+		bound *big.Int
+		opts  *dlp.Options
+	}{
+		{"curve", curveProblem(curve(32), big.NewInt(0xdeadbee)), big.NewInt(1 << 28), nil},
+		{"full order", curveProblem(curve(32), hexInt("0xcded8646")), hexInt("0xcded8647"), nil},
+		{"small table", curveProblem(curve(32), big.NewInt(0xbeef)), big.NewInt(1 << 20), &dlp.Options{MaxPoints: 64}},
+		{"zp", zpProblem(big.NewInt(12345678)), big.NewInt(1 << 24), nil},
+		{"zero", zpProblem(big.NewInt(0)), big.NewInt(1), nil},
+	}
+
+	for _, tt := range tests {
+		result, err := dlp.BabyStepGiantStep(tt.p.grp, tt.p.g, tt.p.h, tt.bound, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if result.D.Cmp(tt.p.d) != 0 {
+			t.Errorf("%s: d = %x, want %x", tt.name, result.D, tt.p.d)
+		}
+	}
+
+	p := curveProblem(curve(32), big.NewInt(1<<20))
+	if _, err := dlp.BabyStepGiantStep(p.grp, p.g, p.h, big.NewInt(1<<20), nil); err == nil {
+		t.Errorf("expected an error for a discrete log past the bound")
+	}
+}
+
+func TestRho(t *testing.T) {
+	var tests = []struct {
+		name string
+		p    *problem
+		opts *dlp.Options
+	}{
+		{"negation map", curveProblem(curve(32), hexInt("0x9c3e5d07")), nil},
+		{"no negation map", zpProblem(hexInt("0x1234abcd")), nil},
+		{"one worker", curveProblem(curve(32), hexInt("0x5a5a5a5a")), &dlp.Options{Workers: 1}},
+		{"every point distinguished", curveProblem(curve(32), hexInt("0x0badf00d")), &dlp.Options{DistinguishedBits: 1}},
+		{"small d", curveProblem(curve(32), big.NewInt(7)), nil},
+	}
+
+	for _, tt := range tests {
+		result, err := dlp.Rho(tt.p.grp, tt.p.g, tt.p.h, tt.p.n, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if result.D.Cmp(tt.p.d) != 0 {
+			t.Errorf("%s: d = %x, want %x", tt.name, result.D, tt.p.d)
+		}
+	}
+
+	// h isn't in the subgroup generated by g when the order is wrong.
+	p := curveProblem(curve(32), big.NewInt(1234))
+	if _, err := dlp.Rho(p.grp, p.g, p.h, hexInt("0xcded0de7"), nil); err == nil {
+		t.Errorf("expected an error for an element outside the subgroup")
+	}
+}
+
+func benchmarkSolver(b *testing.B, bits int, solve func(p *problem) (*dlp.Result, error)) {
+	params := curve(bits)
+	var steps uint64
+	for i := 0; i < b.N; i++ {
+		d, err := rand.Int(rand.Reader, params.N)
+		if err != nil {
+			b.Fatal(err)
+		}
+		p := curveProblem(params, d)
+
+		result, err := solve(p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.D.Cmp(d) != 0 {
+			b.Fatalf("d = %x, want %x", result.D, d)
+		}
+		steps += result.Steps
+	}
+	b.ReportMetric(float64(steps)/float64(b.N), "steps/op")
+}
+
+func BenchmarkBabyStepGiantStep(b *testing.B) {
+	for _, bits := range []int{40, 48} {
+		b.Run(fmt.Sprintf("%d-bit", bits), func(b *testing.B) {
+			benchmarkSolver(b, bits, func(p *problem) (*dlp.Result, error) {
+				return dlp.BabyStepGiantStep(p.grp, p.g, p.h, p.n, &dlp.Options{MaxPoints: 1 << 24})
+			})
+		})
+	}
+}
+
+func BenchmarkRho(b *testing.B) {
+	for _, bits := range []int{40, 48, 56, 64} {
+		b.Run(fmt.Sprintf("%d-bit", bits), func(b *testing.B) {
+			benchmarkSolver(b, bits, func(p *problem) (*dlp.Result, error) {
+				return dlp.Rho(p.grp, p.g, p.h, p.n, nil)
+			})
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package dlp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// kangarooMinWidth is the interval width below which baby-step giant-step is used instead.
+const kangarooMinWidth = 1 << 12
+
+// Kangaroo returns d in [lo, hi] with g^d = h.
+func Kangaroo(grp Group, g, h Element, lo, hi *big.Int, opts *Options) (*Result, error) {
+	return KangarooContext(context.Background(), grp, g, h, lo, hi, opts)
+}
+
+// KangarooContext is Kangaroo which gives up when ctx is done.
+func KangarooContext(ctx context.Context, grp Group, g, h Element, lo, hi *big.Int, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if lo.Sign() < 0 || hi.Cmp(lo) < 0 {
+		return nil, fmt.Errorf("invalid interval [%s, %s]", lo, hi)
+	}
+
+	width := new(big.Int).Sub(hi, lo)
+	if width.Cmp(big.NewInt(kangarooMinWidth)) < 0 {
+		// h·g^-lo = g^(d - lo) with d - lo in [0, width].
+		shifted := h
+		if lo.Sign() != 0 {
+			shifted = grp.Op(h, grp.Inverse(grp.Exp(g, lo)))
+		}
+		result, err := BabyStepGiantStep(grp, g, shifted, width.Add(width, one), opts)
+		if err != nil {
+			return nil, fmt.Errorf("no discrete log in [%s, %s]", lo, hi)
+		}
+		result.D.Add(result.D, lo)
+		return result, nil
+	}
+
+	s := newKangaroos(grp, g, h, lo, hi, width, opts)
+	return s.run(ctx)
+}
+
+var one = big.NewInt(1)
+
+type kangaroo struct {
+	tame bool
+	x    Element
+	key  string
+
+	// dist is the kangaroo's discrete log for a tame one and the offset from h for a wild one.
+	dist *big.Int
+}
+
+type trap struct {
+	tame bool
+	dist *big.Int
+}
+
+type kangaroos struct {
+	grp    Group
+	g, h   Element
+	lo, hi *big.Int
+	width  *big.Int
+
+	workers   int
+	dpMask    uint64
+	maxPoints int
+	maxSteps  uint64
+
+	// jumps are the jump sizes, powers of two, and jx the powers of g they multiply by.
+	jumps []*big.Int
+	jx    []Element
+
+	steps uint64 // atomic
+
+	mu    sync.Mutex
+	traps map[string]trap
+	d     *big.Int
+	err   error
+	done  chan struct{}
+}
+
+func newKangaroos(grp Group, g, h Element, lo, hi, width *big.Int, opts *Options) *kangaroos {
+	s := &kangaroos{
+		grp:       grp,
+		g:         g,
+		h:         h,
+		lo:        lo,
+		hi:        hi,
+		width:     width,
+		workers:   opts.Workers,
+		maxPoints: opts.maxPoints(),
+		maxSteps:  opts.MaxSteps,
+		traps:     map[string]trap{},
+		done:      make(chan struct{}),
+	}
+	if s.workers <= 0 {
+		s.workers = runtime.NumCPU()
+	}
+
+	// With m kangaroos the mean jump should be about m·√w/4 for the herds to meet in 2·√w/m jumps
+	// each. Jumps of 2^0 ... 2^(k-1) have a mean of about 2^k/k.
+	herd := 2 * s.workers
+	sqrtW := new(big.Int).Sqrt(width)
+	mean := new(big.Int).Mul(sqrtW, big.NewInt(int64(herd)))
+	mean.Rsh(mean, 2)
+	k := 1
+	for ; ; k++ {
+		m := new(big.Int).Lsh(one, uint(k))
+		if m.Div(m, big.NewInt(int64(k))).Cmp(mean) >= 0 {
+			break
+		}
+	}
+	for i := 0; i < k; i++ {
+		jump := new(big.Int).Lsh(one, uint(i))
+		s.jumps = append(s.jumps, jump)
+		s.jx = append(s.jx, grp.Exp(g, jump))
+	}
+
+	// Each kangaroo walks about 2^bits past a collision before it's noticed, so that overhead is
+	// kept to an eighth of the 2·√w jumps the collision takes.
+	bits := opts.DistinguishedBits
+	if bits <= 0 {
+		bits = sqrtW.BitLen() - big.NewInt(int64(herd)).BitLen() - 3
+		if bits < 0 {
+			bits = 0
+		}
+	}
+	s.dpMask = 1<<uint(bits) - 1
+
+	if s.maxSteps == 0 {
+		expected := new(big.Int).Lsh(sqrtW, 1)
+		expected.Add(expected, big.NewInt(int64(herd)<<uint(bits)))
+		expected.Lsh(expected, 3)
+		s.maxSteps = ^uint64(0)
+		if expected.IsUint64() {
+			s.maxSteps = expected.Uint64()
+		}
+	}
+
+	return s
+}
+
+func (s *kangaroos) run(ctx context.Context) (*Result, error) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.finish(nil, ctx.Err())
+		case <-s.done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.walk(); err != nil {
+				s.finish(nil, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The ctx watcher can still be running, so the outcome is read under the lock it finishes with.
+	s.mu.Lock()
+	d, err, points := s.d, s.err, len(s.traps)
+	s.mu.Unlock()
+	steps := atomic.LoadUint64(&s.steps)
+
+	if d == nil && err == nil {
+		err = fmt.Errorf("no discrete log in [%s, %s] after %d steps", s.lo, s.hi, steps)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{D: d, Steps: steps, Points: points}, nil
+}
+
+// walk jumps a tame and a wild kangaroo until the search is over.
+func (s *kangaroos) walk() error {
+	herd := make([]*kangaroo, 2)
+	for i := range herd {
+		var err error
+		if herd[i], err = s.start(i == 0); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return nil
+		default:
+		}
+		if atomic.AddUint64(&s.steps, stepBatch) > s.maxSteps {
+			s.finish(nil, nil)
+			return nil
+		}
+
+		for n := 0; n < stepBatch/len(herd); n++ {
+			for i, k := range herd {
+				h := hash(k.key)
+				if h&s.dpMask == 0 {
+					restart, err := s.trap(k)
+					if err != nil || s.isDone() {
+						return err
+					}
+					if restart {
+						if herd[i], err = s.start(k.tame); err != nil {
+							return err
+						}
+						continue
+					}
+				}
+
+				j := (h >> 32) % uint64(len(s.jumps))
+				k.x = s.grp.Op(k.x, s.jx[j])
+				k.key = s.grp.Key(k.x)
+				k.dist.Add(k.dist, s.jumps[j])
+			}
+		}
+	}
+}
+
+// start returns a tame kangaroo at a random power of g in the middle half of the interval, or a
+// wild one at h times a random power of g up to half the width.
+func (s *kangaroos) start(tame bool) (*kangaroo, error) {
+	half := new(big.Int).Rsh(s.width, 1)
+	if half.Sign() == 0 {
+		half.SetInt64(1)
+	}
+	dist, err := rand.Int(rand.Reader, half)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &kangaroo{tame: tame, dist: dist}
+	if tame {
+		dist.Add(dist, new(big.Int).Rsh(s.width, 2))
+		dist.Add(dist, s.lo)
+		k.x = s.grp.Exp(s.g, dist)
+	} else {
+		k.x = s.grp.Op(s.grp.Exp(s.g, dist), s.h)
+	}
+	k.key = s.grp.Key(k.x)
+	return k, nil
+}
+
+// trap records the kangaroo at a distinguished element. It reports whether the kangaroo should be
+// restarted, because another of its kind has already been there.
+func (s *kangaroos) trap(k *kangaroo) (bool, error) {
+	s.mu.Lock()
+	prev, ok := s.traps[k.key]
+	if !ok {
+		if len(s.traps) >= s.maxPoints {
+			s.mu.Unlock()
+			return false, fmt.Errorf("distinguished point table is full with %d points", s.maxPoints)
+		}
+		s.traps[k.key] = trap{tame: k.tame, dist: new(big.Int).Set(k.dist)}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	if prev.tame == k.tame {
+		return true, nil
+	}
+
+	// g^t = h·g^w, so d = t - w.
+	d := new(big.Int)
+	if k.tame {
+		d.Sub(k.dist, prev.dist)
+	} else {
+		d.Sub(prev.dist, k.dist)
+	}
+	if d.Sign() >= 0 && Equal(s.grp.Exp(s.g, d), s.h) {
+		s.finish(d, nil)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *kangaroos) finish(d *big.Int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isDone() {
+		return
+	}
+	s.d, s.err = d, err
+	close(s.done)
+}
+
+func (s *kangaroos) isDone() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
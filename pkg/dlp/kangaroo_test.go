@@ -0,0 +1,61 @@
+package dlp_test
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+func TestKangaroo(t *testing.T) {
+	p256 := dlp.CurveGroup(elliptic.P256())
+	p256G := dlp.Element{elliptic.P256().Params().Gx, elliptic.P256().Params().Gy}
+	zp := zpGroup{hexInt("0x66f6c32b8b473ec7")}
+	zpG := dlp.Element{hexInt("0x1dc94e81ad0fada6")}
+
+	var tests = []struct {
+		name   string
+		grp    dlp.Group
+		g      dlp.Element
+		d      string
+		lo, hi string
+		opts   *dlp.Options
+	}{
+		{"baby-step giant-step", p256, p256G, "0x1234", "0x1000", "0x1fff", nil},
+		{"34-bit", p256, p256G, "0x2deadbeef", "0x200000000", "0x3ffffffff", nil},
+		{"one worker", p256, p256G, "0xcafebabe", "0x0", "0xffffffff", &dlp.Options{Workers: 1}},
+		{"every point distinguished", p256, p256G, "0x8badf00d", "0x80000000", "0x8fffffff", &dlp.Options{DistinguishedBits: 1}},
+		{"Z_p*", zp, zpG, "0x7c0ffee", "0x7000000", "0x7ffffff", nil},
+	}
+
+	for _, tt := range tests {
+		d, lo, hi := hexInt(tt.d), hexInt(tt.lo), hexInt(tt.hi)
+		result, err := dlp.Kangaroo(tt.grp, tt.g, tt.grp.Exp(tt.g, d), lo, hi, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if result.D.Cmp(d) != 0 {
+			t.Errorf("%s: d = %x, want %x", tt.name, result.D, d)
+		}
+	}
+}
+
+func TestKangarooOutsideInterval(t *testing.T) {
+	grp := dlp.CurveGroup(elliptic.P256())
+	g := dlp.Element{elliptic.P256().Params().Gx, elliptic.P256().Params().Gy}
+
+	h := grp.Exp(g, big.NewInt(1<<40))
+	if _, err := dlp.Kangaroo(grp, g, h, big.NewInt(0), big.NewInt(1<<24), &dlp.Options{MaxSteps: 1 << 16}); err == nil {
+		t.Errorf("expected an error for a key outside the interval")
+	}
+	if _, err := dlp.Kangaroo(grp, g, h, big.NewInt(1<<40+1), big.NewInt(1<<40+100), nil); err == nil {
+		t.Errorf("expected an error for a key below a small interval")
+	}
+
+	h = grp.Exp(g, big.NewInt(100))
+	if _, err := dlp.Kangaroo(grp, g, h, big.NewInt(200), big.NewInt(100), nil); err == nil {
+		t.Errorf("expected an error for an empty interval")
+	}
+}
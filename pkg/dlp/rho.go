@@ -0,0 +1,414 @@
+package dlp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// multipliers is the number of precomputed steps the walk picks from. The negation map gets
+	// walks stuck in fruitless cycles less often with more of them.
+	multipliers = 128
+
+	// rhoMinBits is the order size below which rho just uses baby-step giant-step.
+	rhoMinBits = 16
+
+	// stepBatch is how many steps a worker makes between checking whether to stop.
+	stepBatch = 1 << 10
+
+	// cycleCheck is how often a walk keeps its position to notice a fruitless cycle, which catches
+	// those up to this long. Longer ones are rare enough to leave to fruitlessWalk.
+	cycleCheck = 64
+
+	// fruitlessWalk is how many times the expected distance between distinguished points a walk
+	// goes without one before it's assumed to be stuck in a cycle and restarted.
+	fruitlessWalk = 16
+
+	// maxCandidates bounds the solutions tried when a collision only gives d modulo a divisor of n.
+	maxCandidates = 1 << 16
+)
+
+// Rho returns d in [0, n) with g^d = h, where n is the order of g, with parallel Pollard rho.
+func Rho(grp Group, g, h Element, n *big.Int, opts *Options) (*Result, error) {
+	return RhoContext(context.Background(), grp, g, h, n, opts)
+}
+
+// RhoContext is Rho which gives up when ctx is done.
+func RhoContext(ctx context.Context, grp Group, g, h Element, n *big.Int, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if n.BitLen() <= rhoMinBits {
+		return BabyStepGiantStep(grp, g, h, n, opts)
+	}
+	if Equal(h, grp.Identity()) {
+		return &Result{D: new(big.Int)}, nil
+	}
+	if err := checkSubgroup(grp, h, n); err != nil {
+		return nil, err
+	}
+
+	s, err := newRho(grp, g, h, n, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx)
+}
+
+// walk is a point x = g^a·h^b, together with its key and hash so they're computed once a step.
+type walk struct {
+	x    Element
+	key  string
+	hash uint64
+	a, b *big.Int
+
+	// since is the number of steps since the last distinguished point.
+	since uint64
+
+	// saved is the key at step savedAt, every cycleCheck steps, to notice fruitless cycles.
+	saved   string
+	savedAt uint64
+}
+
+type mark struct {
+	a, b *big.Int
+}
+
+type rho struct {
+	grp  Group
+	neg  Negator
+	g, h Element
+	n    *big.Int
+
+	workers   int
+	dpMask    uint64
+	maxPoints int
+	maxSteps  uint64
+	maxWalk   uint64
+
+	// m[j] = g^ma[j]·h^mb[j] are the steps of the walk.
+	m      []Element
+	ma, mb []*big.Int
+
+	steps uint64 // atomic
+
+	mu     sync.Mutex
+	points map[string]mark
+	d      *big.Int
+	err    error
+	done   chan struct{}
+}
+
+func newRho(grp Group, g, h Element, n *big.Int, opts *Options) (*rho, error) {
+	s := &rho{
+		grp:       grp,
+		g:         g,
+		h:         h,
+		n:         n,
+		workers:   opts.Workers,
+		maxPoints: opts.maxPoints(),
+		maxSteps:  opts.MaxSteps,
+		points:    map[string]mark{},
+		done:      make(chan struct{}),
+	}
+	s.neg, _ = grp.(Negator)
+	if s.workers <= 0 {
+		s.workers = runtime.NumCPU()
+	}
+
+	for j := 0; j < multipliers; j++ {
+		a, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		b, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		s.m = append(s.m, grp.Op(grp.Exp(g, a), grp.Exp(h, b)))
+		s.ma = append(s.ma, a)
+		s.mb = append(s.mb, b)
+	}
+
+	// Each walk goes about 2^bits past a collision before it's noticed, which is kept to around a
+	// 32nd of the √n steps the collision takes, as long as the distinguished points found in that
+	// time fit in half the table.
+	sqrtN := new(big.Int).Sqrt(n)
+	bits := opts.DistinguishedBits
+	if bits <= 0 {
+		bits = sqrtN.BitLen() - big.NewInt(int64(s.workers)).BitLen() - 5
+		if min := sqrtN.BitLen() - big.NewInt(int64(s.maxPoints)).BitLen() + 2; bits < min {
+			bits = min
+		}
+		if bits < 0 {
+			bits = 0
+		}
+	}
+	s.dpMask = 1<<uint(bits) - 1
+	s.maxWalk = fruitlessWalk << uint(bits)
+
+	if s.maxSteps == 0 {
+		expected := new(big.Int).Lsh(sqrtN, 1)
+		expected.Add(expected, big.NewInt(int64(s.workers)<<uint(bits)))
+		expected.Lsh(expected, 3)
+		s.maxSteps = ^uint64(0)
+		if expected.IsUint64() {
+			s.maxSteps = expected.Uint64()
+		}
+	}
+
+	return s, nil
+}
+
+func (s *rho) run(ctx context.Context) (*Result, error) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.finish(nil, ctx.Err())
+		case <-s.done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.walk(); err != nil {
+				s.finish(nil, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The ctx watcher can still be running, so the outcome is read under the lock it finishes with.
+	s.mu.Lock()
+	d, err, points := s.d, s.err, len(s.points)
+	s.mu.Unlock()
+	steps := atomic.LoadUint64(&s.steps)
+
+	if d == nil && err == nil {
+		err = fmt.Errorf("no discrete log found after %d steps", steps)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{D: d, Steps: steps, Points: points}, nil
+}
+
+// walk steps a walk until the search is over.
+func (s *rho) walk() error {
+	w, err := s.start()
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return nil
+		default:
+		}
+		if atomic.AddUint64(&s.steps, stepBatch) > s.maxSteps {
+			s.finish(nil, nil)
+			return nil
+		}
+
+		for i := 0; i < stepBatch; i++ {
+			restart := w.since > s.maxWalk
+			if w.hash>>32&s.dpMask == 0 {
+				if restart, err = s.trap(w); err != nil || s.isDone() {
+					return err
+				}
+				w.since, w.saved = 0, ""
+			}
+			if restart {
+				if w, err = s.start(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			s.step(w)
+		}
+	}
+}
+
+// start returns a walk at a random g^a·h^b.
+func (s *rho) start() (*walk, error) {
+	a, err := rand.Int(rand.Reader, s.n)
+	if err != nil {
+		return nil, err
+	}
+	b, err := rand.Int(rand.Reader, s.n)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &walk{x: s.grp.Op(s.grp.Exp(s.g, a), s.grp.Exp(s.h, b)), a: a, b: b}
+	s.canonicalize(w)
+	return w, nil
+}
+
+// step moves the walk on. With the negation map a walk can fall into a fruitless cycle, where the
+// steps undo each other, so the key every cycleCheck steps is kept and seeing it again means the
+// walk has to escape.
+func (s *rho) step(w *walk) {
+	s.next(w)
+	w.since++
+	if s.neg == nil {
+		return
+	}
+
+	if w.key == w.saved {
+		s.escape(w, w.since-w.savedAt)
+		w.saved = ""
+	}
+	if w.since%cycleCheck == 0 {
+		w.saved, w.savedAt = w.key, w.since
+	}
+}
+
+// next moves the walk on by the multiplier its hash picks. With the negation map, a step that lands
+// on an element picking the same multiplier would most likely be undone by the next one, so the
+// next multiplier is tried instead, which is still a function of the element alone.
+func (s *rho) next(w *walk) {
+	from := *w
+	j := int(from.hash % multipliers)
+	for t := 0; t < multipliers; t++ {
+		*w = from
+		w.x = s.grp.Op(from.x, s.m[j])
+		w.a = new(big.Int).Add(from.a, s.ma[j])
+		w.b = new(big.Int).Add(from.b, s.mb[j])
+		s.canonicalize(w)
+		if s.neg == nil || int(w.hash%multipliers) != j {
+			break
+		}
+		j = (j + 1) % multipliers
+	}
+}
+
+// escape moves a walk out of a cycle of the given length by squaring the smallest element of it,
+// so every walk falling into the same cycle leaves it the same way.
+func (s *rho) escape(w *walk, length uint64) {
+	min := *w
+	for i := uint64(1); i < length; i++ {
+		s.next(w)
+		if w.key < min.key {
+			min = *w
+		}
+	}
+
+	*w = min
+	w.x = s.grp.Op(min.x, min.x)
+	w.a = new(big.Int).Lsh(min.a, 1)
+	w.b = new(big.Int).Lsh(min.b, 1)
+	s.canonicalize(w)
+}
+
+// canonicalize replaces x by the smaller of x and x⁻¹ with the negation map, and reduces a and b.
+func (s *rho) canonicalize(w *walk) {
+	w.key = s.grp.Key(w.x)
+	if s.neg != nil {
+		inv := s.neg.Negate(w.x)
+		if key := s.grp.Key(inv); key < w.key {
+			w.x, w.key = inv, key
+			w.a.Neg(w.a)
+			w.b.Neg(w.b)
+		}
+	}
+	w.a.Mod(w.a, s.n)
+	w.b.Mod(w.b, s.n)
+	w.hash = hash(w.key)
+}
+
+// trap records the walk at a distinguished point and solves for d if another walk has already been
+// there. It reports whether the walk should be restarted, because it merged with another without
+// giving d.
+func (s *rho) trap(w *walk) (bool, error) {
+	s.mu.Lock()
+	prev, ok := s.points[w.key]
+	if !ok {
+		if len(s.points) >= s.maxPoints {
+			s.mu.Unlock()
+			return false, fmt.Errorf("distinguished point table is full with %d points", s.maxPoints)
+		}
+		s.points[w.key] = mark{a: new(big.Int).Set(w.a), b: new(big.Int).Set(w.b)}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	if d := s.solve(prev, w); d != nil {
+		s.finish(d, nil)
+		return false, nil
+	}
+	return true, nil
+}
+
+// solve returns d from g^a1·h^b1 = g^a2·h^b2, that is d·(b2 - b1) = a1 - a2 mod n, if the
+// collision determines it.
+func (s *rho) solve(prev mark, w *walk) *big.Int {
+	db := new(big.Int).Sub(w.b, prev.b)
+	db.Mod(db, s.n)
+	da := new(big.Int).Sub(prev.a, w.a)
+	da.Mod(da, s.n)
+	if db.Sign() == 0 {
+		return nil
+	}
+
+	// With g = gcd(db, n), d is only fixed mod n/g and there are g candidates.
+	g := new(big.Int).GCD(nil, nil, db, s.n)
+	if new(big.Int).Mod(da, g).Sign() != 0 || g.Cmp(big.NewInt(maxCandidates)) > 0 {
+		return nil
+	}
+	m := new(big.Int).Div(s.n, g)
+	d := new(big.Int).Div(db, g)
+	d.ModInverse(d, m)
+	d.Mul(d, new(big.Int).Div(da, g))
+	d.Mod(d, m)
+
+	for k := int64(0); k < g.Int64(); k++ {
+		if Equal(s.grp.Exp(s.g, d), s.h) {
+			return d
+		}
+		d.Add(d, m)
+	}
+	return nil
+}
+
+func (s *rho) finish(d *big.Int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isDone() {
+		return
+	}
+	s.d, s.err = d, err
+	close(s.done)
+}
+
+func (s *rho) isDone() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// hash is 64-bit FNV-1a of the key.
+func hash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
@@ -0,0 +1,146 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+)
+
+// Hardened is added to an index for a hardened child, written i' in paths.
+const Hardened uint32 = 1 << 31
+
+// Key is a BIP32 extended private key.
+type Key struct {
+	d     *big.Int
+	chain []byte
+}
+
+// NewMaster returns the master key of the seed, HMAC-SHA512 of it keyed with "Bitcoin seed".
+func NewMaster(seed []byte) (*Key, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	d := new(big.Int).SetBytes(sum[:32])
+	if d.Sign() == 0 || d.Cmp(secp256k1.S256().N) >= 0 {
+		return nil, fmt.Errorf("seed gives an invalid master key")
+	}
+	return &Key{d: d, chain: sum[32:]}, nil
+}
+
+// Child returns the child key at the index, hardened if it's at least Hardened.
+func (k *Key) Child(index uint32) (*Key, error) {
+	curve := secp256k1.S256()
+
+	// Hardened children hash the private key and the others its compressed public key.
+	mac := hmac.New(sha512.New, k.chain)
+	if index >= Hardened {
+		mac.Write([]byte{0})
+		mac.Write(k.PrivateKey())
+	} else {
+		x, y := curve.ScalarBaseMult(k.PrivateKey())
+		mac.Write([]byte{0x02 | byte(y.Bit(0))})
+		mac.Write(leftPad(x.Bytes(), 32))
+	}
+	var i [4]byte
+	binary.BigEndian.PutUint32(i[:], index)
+	mac.Write(i[:])
+	sum := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(curve.N) >= 0 {
+		return nil, fmt.Errorf("child %d is invalid", index)
+	}
+	d := tweak.Add(tweak, k.d)
+	if d.Mod(d, curve.N).Sign() == 0 {
+		return nil, fmt.Errorf("child %d is invalid", index)
+	}
+	return &Key{d: d, chain: sum[32:]}, nil
+}
+
+// PrivateKey returns the private key as 32 big endian bytes.
+func (k *Key) PrivateKey() []byte {
+	return leftPad(k.d.Bytes(), 32)
+}
+
+func leftPad(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+	return append(make([]byte, n-len(b)), b...)
+}
+
+// Path is the indexes of a derivation from the master key.
+type Path []uint32
+
+// ParsePath parses a path such as m/44'/0'/0'/0/0, with h also marking hardened indexes.
+func ParsePath(s string) (Path, error) {
+	parts := strings.Split(s, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path must start with m: %q", s)
+	}
+
+	path := make(Path, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			part, offset = part[:len(part)-1], Hardened
+		}
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q in derivation path %q", part, s)
+		}
+		path = append(path, uint32(index)+offset)
+	}
+	return path, nil
+}
+
+// ParsePaths parses comma separated paths, where an index of i, or i' for hardened ones, stands for
+// each of 0 to count-1.
+func ParsePaths(spec string, count int) ([]Path, error) {
+	var paths []Path
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		var expanded []string
+		switch {
+		case strings.Contains(s, "/i/") || strings.Contains(s, "/i'/") || strings.Contains(s, "/ih/"):
+			return nil, fmt.Errorf("i can only be the last index of a derivation path: %q", s)
+		case strings.HasSuffix(s, "/i"), strings.HasSuffix(s, "/i'"), strings.HasSuffix(s, "/ih"):
+			prefix, suffix := s[:strings.LastIndex(s, "/i")+1], s[strings.LastIndex(s, "/i")+2:]
+			for n := 0; n < count; n++ {
+				expanded = append(expanded, prefix+strconv.Itoa(n)+suffix)
+			}
+		default:
+			expanded = []string{s}
+		}
+
+		for _, e := range expanded {
+			path, err := ParsePath(e)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range p {
+		b.WriteString("/")
+		if index >= Hardened {
+			b.WriteString(strconv.FormatUint(uint64(index-Hardened), 10) + "'")
+		} else {
+			b.WriteString(strconv.FormatUint(uint64(index), 10))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package hdwallet
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Mnemonic returns the BIP39 mnemonic of the entropy, which must be 16 to 32 bytes in steps of 4.
+// Each word is 11 bits of the entropy followed by the first len(entropy)/4 bits of its SHA-256.
+func Mnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", fmt.Errorf("BIP39 entropy must be 16 to 32 bytes in steps of 4, not %d", len(entropy))
+	}
+
+	sum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), sum[0])
+	words := make([]string, (len(entropy)*8+len(entropy)/4)/11)
+	for i := range words {
+		var index int
+		for bit := i * 11; bit < (i+1)*11; bit++ {
+			index = index<<1 | int(data[bit/8]>>(7-uint(bit%8))&1)
+		}
+		words[i] = englishWords[index]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// Seed returns the BIP39 seed of the mnemonic, PBKDF2-HMAC-SHA512 of it with "mnemonic" followed by
+// the passphrase as the salt.
+func Seed(mnemonic, passphrase string) []byte {
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+}
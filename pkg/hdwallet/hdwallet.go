@@ -0,0 +1,83 @@
+// Package hdwallet derives the keys of BIP32 hierarchical deterministic wallets over secp256k1, the
+// way wallet tools turn the entropy they drew into keys:
+//
+//	bip39  the entropy is a BIP39 mnemonic, whose PBKDF2 seed is the BIP32 seed, as with
+//	       bx seed | bx mnemonic-new | bx mnemonic-to-seed | bx hd-new
+//	bip32  the entropy is the BIP32 seed itself, as with bx seed | bx hd-new
+//
+// Keys are then derived from the master key along each path. Mnemonics are English and
+// passphrases are used as given, so one that isn't ASCII has to be in NFKD form already.
+package hdwallet
+
+import (
+	"fmt"
+)
+
+// Derivation turns the entropy of a wallet into the keys at its paths.
+type Derivation struct {
+	// Mnemonic makes the entropy a BIP39 mnemonic rather than the BIP32 seed.
+	Mnemonic bool
+
+	// Passphrase is the BIP39 passphrase of the mnemonic.
+	Passphrase string
+
+	// EntropyBits is the length of the entropy.
+	EntropyBits int
+
+	Paths []Path
+}
+
+// Validate checks that the entropy length suits the derivation and that there are paths.
+func (d *Derivation) Validate() error {
+	bits := d.EntropyBits
+	if d.Mnemonic {
+		if bits < 128 || bits > 256 || bits%32 != 0 {
+			return fmt.Errorf("BIP39 entropy must be 128 to 256 bits in steps of 32, not %d", bits)
+		}
+	} else if bits < 128 || bits > 512 || bits%8 != 0 {
+		return fmt.Errorf("BIP32 seeds must be 128 to 512 bits in whole bytes, not %d", bits)
+	}
+	if len(d.Paths) == 0 {
+		return fmt.Errorf("no derivation paths")
+	}
+	return nil
+}
+
+// Keys returns the 32 byte private key at each of the paths of the wallet made from entropy.
+func (d *Derivation) Keys(entropy []byte) ([][]byte, error) {
+	seed := entropy
+	if d.Mnemonic {
+		mnemonic, err := Mnemonic(entropy)
+		if err != nil {
+			return nil, err
+		}
+		seed = Seed(mnemonic, d.Passphrase)
+	}
+	master, err := NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths usually share all but their last index, so the keys along the previous path are kept.
+	keys := make([][]byte, len(d.Paths))
+	chain := []*Key{master}
+	var prev Path
+	for i, path := range d.Paths {
+		n := 0
+		for n < len(prev) && n < len(path) && prev[n] == path[n] {
+			n++
+		}
+		chain = chain[:n+1]
+		for _, index := range path[n:] {
+			child, err := chain[len(chain)-1].Child(index)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, child)
+		}
+
+		keys[i] = chain[len(chain)-1].PrivateKey()
+		prev = path
+	}
+	return keys, nil
+}
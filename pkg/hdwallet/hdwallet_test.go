@@ -0,0 +1,122 @@
+package hdwallet_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jakecraige/keyrecovery/pkg/hdwallet"
+)
+
+func TestMnemonic(t *testing.T) {
+	// Test vectors from the BIP39 reference implementation, with the passphrase TREZOR.
+	var tests = []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		entropy, _ := hex.DecodeString(tt.entropy)
+		mnemonic, err := hdwallet.Mnemonic(entropy)
+		if err != nil || mnemonic != tt.mnemonic {
+			t.Errorf("Mnemonic(%s) = %q, %v, want %q", tt.entropy, mnemonic, err, tt.mnemonic)
+		}
+		if tt.seed == "" {
+			continue
+		}
+		if seed := hex.EncodeToString(hdwallet.Seed(tt.mnemonic, "TREZOR")); seed != tt.seed {
+			t.Errorf("Seed(%q) = %s, want %s", tt.mnemonic, seed, tt.seed)
+		}
+	}
+
+	if _, err := hdwallet.Mnemonic(make([]byte, 18)); err == nil {
+		t.Errorf("expected an error for 18 bytes of entropy")
+	}
+}
+
+func TestDerive(t *testing.T) {
+	// Test vector 1 from BIP32.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	var tests = []struct {
+		path string
+		key  string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+
+	d := &hdwallet.Derivation{EntropyBits: 128}
+	for _, tt := range tests {
+		path, err := hdwallet.ParsePath(tt.path)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", tt.path, err)
+		}
+		if path.String() != tt.path {
+			t.Errorf("ParsePath(%s).String() = %s", tt.path, path)
+		}
+		d.Paths = append(d.Paths, path)
+	}
+	if err := d.Validate(); err != nil {
+		t.Fatalf("validating derivation: %v", err)
+	}
+
+	keys, err := d.Keys(seed)
+	if err != nil {
+		t.Fatalf("deriving keys: %v", err)
+	}
+	for i, tt := range tests {
+		if key := hex.EncodeToString(keys[i]); key != tt.key {
+			t.Errorf("%s: key = %s, want %s", tt.path, key, tt.key)
+		}
+	}
+
+	// The same key along a path given out of order.
+	d.Paths = []hdwallet.Path{d.Paths[5], d.Paths[1], d.Paths[5]}
+	again, err := d.Keys(seed)
+	if err != nil || !bytes.Equal(again[0], keys[5]) || !bytes.Equal(again[1], keys[1]) || !bytes.Equal(again[2], keys[5]) {
+		t.Errorf("keys along paths out of order don't match: %v", err)
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	paths, err := hdwallet.ParsePaths("m/44'/0'/0'/0/i, m/0, m/1h/i'", 2)
+	if err != nil {
+		t.Fatalf("parsing paths: %v", err)
+	}
+	want := []string{"m/44'/0'/0'/0/0", "m/44'/0'/0'/0/1", "m/0", "m/1'/0'", "m/1'/1'"}
+	if len(paths) != len(want) {
+		t.Fatalf("got %d paths, want %d", len(paths), len(want))
+	}
+	for i, p := range paths {
+		if p.String() != want[i] {
+			t.Errorf("path %d = %s, want %s", i, p, want[i])
+		}
+	}
+
+	for _, spec := range []string{"44'/0", "m/x", "m/i/0", "m/2147483648"} {
+		if _, err := hdwallet.ParsePaths(spec, 1); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Package index implements a persistent on-disk index of signature r values keyed by curve so that
+// nonce reuse can be detected incrementally as new signatures arrive, without re-reading the whole
+// corpus each time.
+package index
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	segmentExt = ".idx"
+
+	// maxSegments is the number of segments a curve may accumulate from incremental additions before
+	// they are compacted into one.
+	maxSegments = 16
+
+	// DefaultRunSize is the number of entries sorted in memory at a time during a bulk load.
+	DefaultRunSize = 1 << 20
+)
+
+// Entry is a single indexed signature. R is the signature's r value and Data is the raw signature
+// as it was provided to the index.
+type Entry struct {
+	R    []byte
+	Data []byte
+}
+
+// Match is a group of distinct entries which share the same r value, indicating the nonce was
+// reused between them.
+type Match struct {
+	R       []byte
+	Entries []*Entry
+}
+
+// EntryReader yields entries to be bulk loaded one at a time. It returns io.EOF once exhausted.
+type EntryReader interface {
+	Next() (*Entry, error)
+}
+
+type Index struct {
+	dir string
+}
+
+// Open opens the index stored in dir, creating it if it doesn't exist.
+func Open(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Index{dir: dir}, nil
+}
+
+// Query returns all entries indexed for the curve with the given r value.
+func (idx *Index) Query(curve string, r []byte) ([]*Entry, error) {
+	segments, err := idx.segments(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	key := hex.EncodeToString(r)
+	entries := make([]*Entry, 0)
+	for _, path := range segments {
+		records, err := searchSegment(path, key)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rec := range records {
+			entry, err := rec.entry()
+			if err != nil {
+				return nil, err
+			}
+			if !containsEntry(entries, entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// Add checks each entry against everything previously indexed for the curve and then stores it.
+// Every new entry which shares an r value with another entry, either already indexed or within the
+// same batch, is reported in a match along with those entries. Entries which were already indexed
+// are ignored.
+func (idx *Index) Add(curve string, entries []*Entry) ([]*Match, error) {
+	matches := make([]*Match, 0)
+	byKey := make(map[string]*Match)
+	reported := make(map[string]bool)
+	records := make([]record, 0, len(entries))
+
+	for _, entry := range entries {
+		key := hex.EncodeToString(entry.R)
+
+		match, ok := byKey[key]
+		if !ok {
+			existing, err := idx.Query(curve, entry.R)
+			if err != nil {
+				return nil, err
+			}
+
+			match = &Match{R: entry.R, Entries: existing}
+			byKey[key] = match
+		}
+		if containsEntry(match.Entries, entry) {
+			continue
+		}
+
+		match.Entries = append(match.Entries, entry)
+		if len(match.Entries) > 1 && !reported[key] {
+			matches = append(matches, match)
+			reported[key] = true
+		}
+		records = append(records, newRecord(entry))
+	}
+
+	if len(records) == 0 {
+		return matches, nil
+	}
+
+	sortRecords(records)
+	path, err := idx.nextSegmentPath(curve)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSegment(path, records); err != nil {
+		return nil, err
+	}
+
+	segments, err := idx.segments(curve)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > maxSegments {
+		if _, err := idx.Compact(curve); err != nil {
+			return nil, err
+		}
+	}
+
+	return matches, nil
+}
+
+// BulkLoad imports a large number of entries for the curve using an external sort. Entries are
+// sorted in memory in runs of runSize, spilled to disk and then merged together with the existing
+// segments into a single new segment. All groups of entries sharing an r value are reported,
+// including those which were only found among previously indexed entries.
+func (idx *Index) BulkLoad(curve string, r EntryReader, runSize int) ([]*Match, error) {
+	if runSize <= 0 {
+		runSize = DefaultRunSize
+	}
+
+	tmpDir, err := ioutil.TempDir(idx.dir, "bulkload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	runs := make([]string, 0)
+	buf := make([]record, 0, runSize)
+	spill := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+
+		sortRecords(buf)
+		path := filepath.Join(tmpDir, fmt.Sprintf("run-%06d%s", len(runs), segmentExt))
+		if err := writeSegment(path, buf); err != nil {
+			return err
+		}
+		runs = append(runs, path)
+		buf = buf[:0]
+		return nil
+	}
+
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, newRecord(entry))
+		if len(buf) == runSize {
+			if err := spill(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := spill(); err != nil {
+		return nil, err
+	}
+
+	return idx.merge(curve, runs)
+}
+
+// Compact merges all segments for the curve into one, reporting every group of entries which share
+// an r value.
+func (idx *Index) Compact(curve string) ([]*Match, error) {
+	return idx.merge(curve, nil)
+}
+
+func (idx *Index) merge(curve string, extra []string) ([]*Match, error) {
+	existing, err := idx.segments(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := idx.nextSegmentPath(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*Match, 0)
+	var groupErr error
+	err = mergeSegments(out, append(existing, extra...), func(group []record) {
+		match, err := newMatch(group)
+		if err != nil {
+			groupErr = err
+			return
+		}
+		matches = append(matches, match)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if groupErr != nil {
+		return nil, groupErr
+	}
+
+	// The merged segment contains everything, so the old ones can now be dropped.
+	for _, path := range existing {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return matches, nil
+}
+
+func (idx *Index) curveDir(curve string) (string, error) {
+	if curve == "" || strings.ContainsAny(curve, `/\`) || curve == "." || curve == ".." {
+		return "", fmt.Errorf("invalid curve name for index: %q", curve)
+	}
+
+	dir := filepath.Join(idx.dir, curve)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// segments returns the paths of all segments for the curve, oldest first.
+func (idx *Index) segments(curve string) ([]string, error) {
+	dir, err := idx.curveDir(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "seg-*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func (idx *Index) nextSegmentPath(curve string) (string, error) {
+	dir, err := idx.curveDir(curve)
+	if err != nil {
+		return "", err
+	}
+
+	segments, err := idx.segments(curve)
+	if err != nil {
+		return "", err
+	}
+
+	seq := 0
+	if len(segments) > 0 {
+		last := filepath.Base(segments[len(segments)-1])
+		if _, err := fmt.Sscanf(last, "seg-%d"+segmentExt, &seq); err != nil {
+			return "", fmt.Errorf("unexpected segment name %s: %v", last, err)
+		}
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("seg-%09d%s", seq+1, segmentExt)), nil
+}
+
+func newRecord(entry *Entry) record {
+	return record{key: hex.EncodeToString(entry.R), data: hex.EncodeToString(entry.Data)}
+}
+
+func (r record) entry() (*Entry, error) {
+	rBytes, err := hex.DecodeString(r.key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := hex.DecodeString(r.data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{R: rBytes, Data: data}, nil
+}
+
+func newMatch(group []record) (*Match, error) {
+	match := &Match{Entries: make([]*Entry, len(group))}
+	for i, rec := range group {
+		entry, err := rec.entry()
+		if err != nil {
+			return nil, err
+		}
+		match.Entries[i] = entry
+	}
+	match.R = match.Entries[0].R
+
+	return match, nil
+}
+
+func containsEntry(entries []*Entry, entry *Entry) bool {
+	for _, e := range entries {
+		if string(e.Data) == string(entry.Data) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package index_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/jakecraige/keyrecovery/pkg/index"
+)
+
+type sliceReader struct {
+	entries []*index.Entry
+}
+
+func (s *sliceReader) Next() (*index.Entry, error) {
+	if len(s.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	entry := s.entries[0]
+	s.entries = s.entries[1:]
+	return entry, nil
+}
+
+func entry(r byte, data string) *index.Entry {
+	return &index.Entry{R: []byte{0, r}, Data: []byte(data)}
+}
+
+func TestIndexIncrementalAndBulkLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyrecovery-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	idx, err := index.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bulk load with a tiny run size so the external merge is exercised across many runs.
+	initial := make([]*index.Entry, 0)
+	for i := 0; i < 50; i++ {
+		initial = append(initial, entry(byte(i), fmt.Sprintf("sig-%d", i)))
+	}
+	initial = append(initial, entry(7, "sig-7-reused"))
+
+	matches, err := idx.BulkLoad("P256", &sliceReader{entries: initial}, 4)
+	if err != nil {
+		t.Fatalf("bulk loading: %v", err)
+	}
+	if len(matches) != 1 || len(matches[0].Entries) != 2 || matches[0].R[1] != 7 {
+		t.Fatalf("expected one match for r=7 from bulk load, got %+v", matches)
+	}
+
+	// Incremental additions are checked against everything seen before, and re-adding a known
+	// signature doesn't count as reuse.
+	for i := 0; i < 20; i++ {
+		matches, err = idx.Add("P256", []*index.Entry{entry(byte(100+i), fmt.Sprintf("new-%d", i))})
+		if err != nil {
+			t.Fatalf("adding: %v", err)
+		}
+		if len(matches) != 0 {
+			t.Fatalf("unexpected match adding fresh signature: %+v", matches)
+		}
+	}
+
+	matches, err = idx.Add("P256", []*index.Entry{entry(42, "sig-42"), entry(42, "sig-42-reused"), entry(110, "new-10")})
+	if err != nil {
+		t.Fatalf("adding: %v", err)
+	}
+	if len(matches) != 1 || len(matches[0].Entries) != 2 || matches[0].R[1] != 42 {
+		t.Fatalf("expected one match for r=42, got %+v", matches)
+	}
+
+	// Other curves are tracked separately.
+	matches, err = idx.Add("secp256k1", []*index.Entry{entry(42, "other-curve")})
+	if err != nil {
+		t.Fatalf("adding: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("unexpected match across curves: %+v", matches)
+	}
+
+	for _, tt := range []struct {
+		r    byte
+		want int
+	}{{0, 1}, {7, 2}, {42, 2}, {49, 1}, {119, 1}, {50, 0}, {255, 0}} {
+		entries, err := idx.Query("P256", []byte{0, tt.r})
+		if err != nil {
+			t.Fatalf("querying: %v", err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("query r=%d: expected %d entries, got %d", tt.r, tt.want, len(entries))
+		}
+	}
+}
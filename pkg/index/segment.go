@@ -0,0 +1,293 @@
+package index
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+/*
+* Segments are immutable text files of "<key> <data>\n" records sorted by key. Keys are the hex
+* encoded r values which are fixed width for a given curve, so lexical order matches numeric order
+* and a segment can be binary searched in place without loading it into memory.
+ */
+
+type record struct {
+	key, data string
+}
+
+func (r record) String() string {
+	return r.key + " " + r.data
+}
+
+func parseRecord(line string) (record, error) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return record{}, fmt.Errorf("malformed index record: %q", line)
+	}
+
+	return record{key: line[:i], data: line[i+1:]}, nil
+}
+
+// writeSegment writes the records, which must already be sorted, to path atomically.
+func writeSegment(path string, records []record) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	for _, rec := range records {
+		if _, err := fmt.Fprintln(w, rec); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func sortRecords(records []record) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].key == records[j].key {
+			return records[i].data < records[j].data
+		}
+		return records[i].key < records[j].key
+	})
+}
+
+// searchSegment returns all records in the segment at path with the given key.
+func searchSegment(path, key string) ([]record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	// Find the first byte offset whose following line has a key >= the target. Since lines are
+	// sorted this predicate is monotonic over offsets, and the line found from that offset is the
+	// first matching record.
+	var searchErr error
+	off := sort.Search(int(size), func(i int) bool {
+		_, line, err := lineAt(file, size, int64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		if line == "" {
+			return true
+		}
+		rec, err := parseRecord(line)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return rec.key >= key
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	start, _, err := lineAt(file, size, int64(off))
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]record, 0)
+	scanner := bufio.NewScanner(io.NewSectionReader(file, start, size-start))
+	for scanner.Scan() {
+		rec, err := parseRecord(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if rec.key != key {
+			break
+		}
+		matches = append(matches, rec)
+	}
+
+	return matches, scanner.Err()
+}
+
+// lineAt returns the first complete line beginning at or after off along with its offset. An empty
+// line is returned when there are no more lines.
+func lineAt(r io.ReaderAt, size, off int64) (int64, string, error) {
+	if off > 0 {
+		// Start one byte back so that a line beginning exactly at off is not skipped.
+		off--
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(r, off, size-off))
+	if off > 0 {
+		skipped, err := br.ReadString('\n')
+		if err == io.EOF {
+			return size, "", nil
+		} else if err != nil {
+			return 0, "", err
+		}
+		off += int64(len(skipped))
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+
+	return off, strings.TrimSuffix(line, "\n"), nil
+}
+
+// segmentReader iterates over the records of a sorted file in order.
+type segmentReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	current record
+}
+
+func openSegmentReader(path string) (*segmentReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &segmentReader{file: file, scanner: bufio.NewScanner(file)}, nil
+}
+
+// next advances the reader, returning false once the segment is exhausted.
+func (s *segmentReader) next() (bool, error) {
+	if !s.scanner.Scan() {
+		return false, s.scanner.Err()
+	}
+
+	rec, err := parseRecord(s.scanner.Text())
+	if err != nil {
+		return false, err
+	}
+	s.current = rec
+	return true, nil
+}
+
+func (s *segmentReader) Close() error {
+	return s.file.Close()
+}
+
+// mergeHeap orders segment readers by their current record for a k-way merge.
+type mergeHeap []*segmentReader
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].current.key == h[j].current.key {
+		return h[i].current.data < h[j].current.data
+	}
+	return h[i].current.key < h[j].current.key
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*segmentReader)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// mergeSegments performs a k-way merge of the sorted files at paths into a single segment at out,
+// dropping duplicate records. Each group of distinct records sharing a key is passed to onGroup.
+func mergeSegments(out string, paths []string, onGroup func([]record)) error {
+	h := make(mergeHeap, 0, len(paths))
+	defer func() {
+		for _, r := range h {
+			r.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		r, err := openSegmentReader(path)
+		if err != nil {
+			return err
+		}
+
+		ok, err := r.next()
+		if err != nil {
+			r.Close()
+			return err
+		}
+		if !ok {
+			r.Close()
+			continue
+		}
+		h = append(h, r)
+	}
+	heap.Init(&h)
+
+	tmp := out + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+
+	group := make([]record, 0)
+	flush := func() {
+		if len(group) > 1 && onGroup != nil {
+			onGroup(append([]record(nil), group...))
+		}
+		group = group[:0]
+	}
+
+	for h.Len() > 0 {
+		r := h[0]
+		rec := r.current
+
+		if len(group) > 0 && group[0].key != rec.key {
+			flush()
+		}
+		if len(group) == 0 || group[len(group)-1] != rec {
+			group = append(group, rec)
+			if _, err := fmt.Fprintln(w, rec); err != nil {
+				file.Close()
+				return err
+			}
+		}
+
+		ok, err := r.next()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+			r.Close()
+		}
+	}
+	flush()
+
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, out)
+}
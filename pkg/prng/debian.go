@@ -0,0 +1,241 @@
+package prng
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// debianArch is what a Debian OpenSSL key depends on besides the PID: the size of long, which
+// md_rand hashes its counters as and which is also the size of time_t, and the size of struct
+// stat. Nothing else about the values passed to RAND_add makes it into the pool, only their length.
+// Both architectures are little-endian.
+type debianArch struct {
+	long int
+	stat int
+}
+
+var debianArchs = map[string]debianArch{
+	"i386":  {long: 4, stat: 88},
+	"amd64": {long: 8, stat: 144},
+}
+
+// debianOpenSSL is an EC key made by openssl ecparam -genkey with Debian's OpenSSL 0.9.8c-1 to
+// 0.9.8g-9, for an order of bits bits, by a user without a ~/.rnd. The seed is the PID, the only
+// input left in the pool. The bytes are the first number BN_rand_range draws, which is the key
+// unless it's at least the order. For the orders of the usual curves, which start with 11 or 101 in
+// binary, that's very rarely the case.
+type debianOpenSSL struct {
+	arch debianArch
+	bits int
+}
+
+// newDebianOpenSSL returns the model named debian-openssl-<arch>-<bits>.
+func newDebianOpenSSL(name string) (Model, bool) {
+	parts := strings.Split(name, "-")
+	if len(parts) != 4 || parts[0] != "debian" || parts[1] != "openssl" {
+		return nil, false
+	}
+	arch, ok := debianArchs[parts[2]]
+	bits, err := strconv.Atoi(parts[3])
+	if !ok || err != nil || bits < 8 || bits > 1024 {
+		return nil, false
+	}
+	return debianOpenSSL{arch: arch, bits: bits}, true
+}
+
+func (m debianOpenSSL) Fill(seed uint64, out []byte) {
+	r := &mdRand{arch: m.arch, pid: uint32(seed)}
+
+	// RAND_load_file stats the missing ~/.rnd and adds the struct anyway, and RAND_status then polls
+	// since the pool hasn't been initialized.
+	r.add(m.arch.stat, 0)
+	r.poll()
+
+	// bnrand adds the time, then draws the bytes and clears the bits above the order's.
+	r.add(m.arch.long, 0)
+	buf := make([]byte, (m.bits+7)/8)
+	r.bytes(buf)
+	buf[0] &= byte(0xff >> uint(len(buf)*8-m.bits))
+
+	n := copy(out, buf)
+	for i := n; i < len(out); i++ {
+		out[i] = 0
+	}
+}
+
+// MaxSeed is the largest PID, 32767 by default on Linux.
+func (debianOpenSSL) MaxSeed() uint64 { return 32767 }
+
+const (
+	mdRandStateSize      = 1023
+	mdRandEntropyNeeded  = 32
+	mdRandDigestLength   = sha1.Size
+	mdRandHalfDigestSize = mdRandDigestLength / 2
+)
+
+// mdRand is OpenSSL 0.9.8's md_rand.c with SHA-1, without the two calls Debian removed that hashed
+// the caller's buffer into the pool.
+type mdRand struct {
+	arch debianArch
+	pid  uint32
+
+	state                [mdRandStateSize]byte
+	stateNum, stateIndex int
+	md                   [mdRandDigestLength]byte
+	mdCount              [2]int64
+	entropy              float64
+	initialized, stirred bool
+}
+
+// counters returns md_count as the two longs it's hashed as.
+func (r *mdRand) counters(c [2]int64) []byte {
+	out := make([]byte, 2*r.arch.long)
+	for i, v := range c {
+		if r.arch.long == 8 {
+			binary.LittleEndian.PutUint64(out[i*8:], uint64(v))
+		} else {
+			binary.LittleEndian.PutUint32(out[i*4:], uint32(v))
+		}
+	}
+	return out
+}
+
+// poll is RAND_poll on Linux: 32 bytes of /dev/urandom, then the PID, the UID and the time as longs.
+func (r *mdRand) poll() {
+	r.add(mdRandEntropyNeeded, mdRandEntropyNeeded)
+	for i := 0; i < 3; i++ {
+		r.add(r.arch.long, 0)
+	}
+	r.initialized = true
+}
+
+// add is ssleay_rand_add of num bytes of which none are hashed.
+func (r *mdRand) add(num int, entropy float64) {
+	stIdx := r.stateIndex
+	mdc := r.mdCount
+	localMD := r.md
+
+	r.stateIndex += num
+	if r.stateIndex >= mdRandStateSize {
+		r.stateIndex %= mdRandStateSize
+		r.stateNum = mdRandStateSize
+	} else if r.stateNum < mdRandStateSize && r.stateIndex > r.stateNum {
+		r.stateNum = r.stateIndex
+	}
+	r.mdCount[1] += int64(num / mdRandDigestLength)
+	if num%mdRandDigestLength > 0 {
+		r.mdCount[1]++
+	}
+
+	for i := 0; i < num; i += mdRandDigestLength {
+		j := num - i
+		if j > mdRandDigestLength {
+			j = mdRandDigestLength
+		}
+
+		h := sha1.New() //nolint:gosec
+		h.Write(localMD[:])
+		if k := stIdx + j - mdRandStateSize; k > 0 {
+			h.Write(r.state[stIdx : stIdx+j-k])
+			h.Write(r.state[:k])
+		} else {
+			h.Write(r.state[stIdx : stIdx+j])
+		}
+		h.Write(r.counters(mdc))
+		h.Sum(localMD[:0])
+		mdc[1]++
+
+		for k := 0; k < j; k++ {
+			r.state[stIdx] ^= localMD[k]
+			if stIdx++; stIdx >= mdRandStateSize {
+				stIdx = 0
+			}
+		}
+	}
+
+	for k := range r.md {
+		r.md[k] ^= localMD[k]
+	}
+	if r.entropy < mdRandEntropyNeeded {
+		r.entropy += entropy
+	}
+}
+
+// bytes is ssleay_rand_bytes, stirring the pool the first time it's called with enough entropy.
+func (r *mdRand) bytes(buf []byte) {
+	num := len(buf)
+	if num <= 0 {
+		return
+	}
+	numCeil := (1 + (num-1)/mdRandHalfDigestSize) * mdRandHalfDigestSize
+
+	if !r.initialized {
+		r.poll()
+	}
+	ok := r.entropy >= mdRandEntropyNeeded
+	if !ok {
+		if r.entropy -= float64(num); r.entropy < 0 {
+			r.entropy = 0
+		}
+	}
+	if !r.stirred {
+		for n := mdRandStateSize; n > 0; n -= mdRandDigestLength {
+			r.add(mdRandDigestLength, 0)
+		}
+		r.stirred = ok
+	}
+
+	stIdx, stNum := r.stateIndex, r.stateNum
+	mdc := r.mdCount
+	localMD := r.md
+
+	r.stateIndex += numCeil
+	if r.stateIndex > r.stateNum {
+		r.stateIndex %= r.stateNum
+	}
+	r.mdCount[0]++
+
+	pid := r.pid
+	for out := buf; len(out) > 0; {
+		j := len(out)
+		if j > mdRandHalfDigestSize {
+			j = mdRandHalfDigestSize
+		}
+
+		h := sha1.New() //nolint:gosec
+		if pid != 0 {
+			var p [4]byte
+			binary.LittleEndian.PutUint32(p[:], pid)
+			h.Write(p[:])
+			pid = 0
+		}
+		h.Write(localMD[:])
+		h.Write(r.counters(mdc))
+		if k := stIdx + mdRandHalfDigestSize - stNum; k > 0 {
+			h.Write(r.state[stIdx : stIdx+mdRandHalfDigestSize-k])
+			h.Write(r.state[:k])
+		} else {
+			h.Write(r.state[stIdx : stIdx+mdRandHalfDigestSize])
+		}
+		h.Sum(localMD[:0])
+
+		for i := 0; i < mdRandHalfDigestSize; i++ {
+			r.state[stIdx] ^= localMD[i]
+			if stIdx++; stIdx >= stNum {
+				stIdx = 0
+			}
+			if i < j {
+				out[i] = localMD[i+mdRandHalfDigestSize]
+			}
+		}
+		out = out[j:]
+	}
+
+	h := sha1.New() //nolint:gosec
+	h.Write(r.counters(mdc))
+	h.Write(localMD[:])
+	h.Write(r.md[:])
+	h.Sum(r.md[:0])
+}
@@ -0,0 +1,51 @@
+package prng
+
+// GlibcRand is rand() and random() from glibc, the additive feedback generator of degree 31 that
+// srand() seeds. Other C libraries use other generators.
+type GlibcRand struct {
+	// r is a ring of the last 34 words, the next of which goes at index n mod 34.
+	r [34]int32
+	n int
+}
+
+// NewGlibcRand returns the generator after srand(seed).
+func NewGlibcRand(seed uint32) *GlibcRand {
+	g := &GlibcRand{}
+	if seed == 0 {
+		seed = 1
+	}
+
+	// The first words come from a Lehmer generator, computed with Schrage's method like glibc so
+	// the signed overflow behaves the same.
+	g.r[0] = int32(seed)
+	for i := 1; i < 31; i++ {
+		hi, lo := g.r[i-1]/127773, g.r[i-1]%127773
+		word := 16807*lo - 2836*hi
+		if word < 0 {
+			word += 2147483647
+		}
+		g.r[i] = word
+	}
+	for i := 31; i < 34; i++ {
+		g.r[i] = g.r[i-31]
+	}
+	g.n = 34
+
+	// srand() discards the first 310 outputs.
+	for i := 0; i < 310; i++ {
+		g.next()
+	}
+	return g
+}
+
+func (g *GlibcRand) next() uint32 {
+	i := g.n % 34
+	g.r[i] = int32(uint32(g.r[(g.n-31)%34]) + uint32(g.r[(g.n-3)%34]))
+	g.n++
+	return uint32(g.r[i])
+}
+
+// Int31 returns the next output of rand(), in [0, 2^31).
+func (g *GlibcRand) Int31() int32 {
+	return int32(g.next() >> 1)
+}
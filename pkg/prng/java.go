@@ -0,0 +1,38 @@
+package prng
+
+const (
+	javaMultiplier = 0x5deece66d
+	javaMask       = 1<<48 - 1
+)
+
+// JavaRandom is java.util.Random, a 48-bit linear congruential generator.
+type JavaRandom struct {
+	seed uint64
+}
+
+// NewJavaRandom returns the generator of new Random(seed). Only the low 48 bits of the seed matter.
+func NewJavaRandom(seed int64) *JavaRandom {
+	return &JavaRandom{seed: (uint64(seed) ^ javaMultiplier) & javaMask}
+}
+
+func (j *JavaRandom) next(bits uint) int32 {
+	j.seed = (j.seed*javaMultiplier + 0xb) & javaMask
+	return int32(j.seed >> (48 - bits))
+}
+
+// NextInt returns the next output of nextInt().
+func (j *JavaRandom) NextInt() int32 {
+	return j.next(32)
+}
+
+// NextBytes fills out like nextBytes(), each int giving four bytes least significant first.
+func (j *JavaRandom) NextBytes(out []byte) {
+	for i := 0; i < len(out); {
+		rnd := j.NextInt()
+		for n := 0; n < 4 && i < len(out); n++ {
+			out[i] = byte(rnd)
+			rnd >>= 8
+			i++
+		}
+	}
+}
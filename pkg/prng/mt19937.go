@@ -0,0 +1,78 @@
+package prng
+
+// MT19937 is the 32-bit Mersenne Twister, std::mt19937 in C++ and the generator behind Python's
+// random module.
+type MT19937 struct {
+	mt    [624]uint32
+	index int
+}
+
+// NewMT19937 returns the generator seeded like std::mt19937(seed).
+func NewMT19937(seed uint32) *MT19937 {
+	m := &MT19937{index: 624}
+	m.mt[0] = seed
+	for i := 1; i < 624; i++ {
+		m.mt[i] = 1812433253*(m.mt[i-1]^m.mt[i-1]>>30) + uint32(i)
+	}
+	return m
+}
+
+// NewMT19937Array returns the generator seeded with init_by_array, as Python seeds it from an
+// integer split into 32-bit words, least significant first.
+func NewMT19937Array(key []uint32) *MT19937 {
+	m := NewMT19937(19650218)
+	i, j := 1, 0
+	n := 624
+	if len(key) > n {
+		n = len(key)
+	}
+	for k := n; k > 0; k-- {
+		m.mt[i] = (m.mt[i] ^ (m.mt[i-1]^m.mt[i-1]>>30)*1664525) + key[j] + uint32(j)
+		i++
+		j++
+		if i >= 624 {
+			m.mt[0] = m.mt[623]
+			i = 1
+		}
+		if j >= len(key) {
+			j = 0
+		}
+	}
+	for k := 623; k > 0; k-- {
+		m.mt[i] = (m.mt[i] ^ (m.mt[i-1]^m.mt[i-1]>>30)*1566083941) - uint32(i)
+		i++
+		if i >= 624 {
+			m.mt[0] = m.mt[623]
+			i = 1
+		}
+	}
+	m.mt[0] = 0x80000000
+	return m
+}
+
+// Uint32 returns the next output.
+func (m *MT19937) Uint32() uint32 {
+	if m.index >= 624 {
+		m.twist()
+	}
+	y := m.mt[m.index]
+	m.index++
+
+	y ^= y >> 11
+	y ^= y << 7 & 0x9d2c5680
+	y ^= y << 15 & 0xefc60000
+	y ^= y >> 18
+	return y
+}
+
+func (m *MT19937) twist() {
+	for i := 0; i < 624; i++ {
+		y := m.mt[i]&0x80000000 | m.mt[(i+1)%624]&0x7fffffff
+		next := m.mt[(i+397)%624] ^ y>>1
+		if y&1 != 0 {
+			next ^= 0x9908b0df
+		}
+		m.mt[i] = next
+	}
+	m.index = 0
+}
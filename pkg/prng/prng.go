@@ -0,0 +1,159 @@
+// Package prng reproduces the non-cryptographic generators keys have been generated with, together
+// with the way each tool turned their output into key bytes. Seeded with 32 bits or so, every key
+// such a tool could have made can be enumerated:
+//
+//	bx-seed       libbitcoin explorer's bx seed, the entropy of the Milk Sad wallets: MT19937
+//	              seeded with 32 bits of the time, each byte drawn with
+//	              std::uniform_int_distribution(0, 255) as libstdc++ implements it
+//	mt19937       std::mt19937 outputs written out little-endian
+//	glibc-rand    srand(seed) then rand() & 0xff for each byte, the usual C idiom
+//	java-random   new Random(seed).nextBytes()
+//	go-math-rand  rand.New(rand.NewSource(seed)).Read() with Go's math/rand
+//	python-random random.seed(seed) then random.getrandbits() of every byte, big-endian
+//
+//	debian-openssl-<arch>-<bits>
+//	              Debian's OpenSSL 0.9.8c-1 to 0.9.8g-9, whose pool only kept the process ID, as
+//	              openssl ecparam -genkey draws a key for an order of bits bits with no ~/.rnd on
+//	              i386 or amd64
+//
+// The Debian models depend on the architecture and on everything the program fed the pool first, so
+// only that one program is modeled. Models has them for 256 bit orders, and Lookup takes any size.
+package prng
+
+import (
+	"fmt"
+	"math/rand" //nolint:gosec
+	"sort"
+)
+
+// Model is the bytes a tool made a key from, given the seed of its generator.
+type Model interface {
+	// Fill writes the first len(out) bytes the tool would have made with the seed.
+	Fill(seed uint64, out []byte)
+
+	// MaxSeed is the largest seed that makes a different generator.
+	MaxSeed() uint64
+}
+
+// Models are the models by name.
+var Models = map[string]Model{
+	"bx-seed":       bxSeed{},
+	"mt19937":       mt19937Words{},
+	"glibc-rand":    glibcRandBytes{},
+	"java-random":   javaRandomBytes{},
+	"go-math-rand":  goMathRandBytes{},
+	"python-random": pythonRandomBytes{},
+
+	"debian-openssl-i386-256":  debianOpenSSL{arch: debianArchs["i386"], bits: 256},
+	"debian-openssl-amd64-256": debianOpenSSL{arch: debianArchs["amd64"], bits: 256},
+}
+
+// Lookup returns the model with the name.
+func Lookup(name string) (Model, error) {
+	if m, ok := Models[name]; ok {
+		return m, nil
+	}
+	if m, ok := newDebianOpenSSL(name); ok {
+		return m, nil
+	}
+
+	names := make([]string, 0, len(Models))
+	for n := range Models {
+		names = append(names, n)
+	}
+	names = append(names, "debian-openssl-<i386|amd64>-<bits>")
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown PRNG model %s, expected one of %v", name, names)
+}
+
+type bxSeed struct{}
+
+func (bxSeed) Fill(seed uint64, out []byte) {
+	m := NewMT19937(uint32(seed))
+	for i := range out {
+		out[i] = uniformByte(m)
+	}
+}
+
+func (bxSeed) MaxSeed() uint64 { return 1<<32 - 1 }
+
+// uniformByte is std::uniform_int_distribution<>(0, 255) in libstdc++, which divides the output by
+// (2^32 - 1) / 256 after rejecting the top few values, keeping about its top byte.
+func uniformByte(m *MT19937) byte {
+	const scaling = (1<<32 - 1) / 256
+	for {
+		if v := m.Uint32(); v < 256*scaling {
+			return byte(v / scaling)
+		}
+	}
+}
+
+type mt19937Words struct{}
+
+func (mt19937Words) Fill(seed uint64, out []byte) {
+	m := NewMT19937(uint32(seed))
+	for i := 0; i < len(out); {
+		v := m.Uint32()
+		for n := 0; n < 4 && i < len(out); n++ {
+			out[i] = byte(v)
+			v >>= 8
+			i++
+		}
+	}
+}
+
+func (mt19937Words) MaxSeed() uint64 { return 1<<32 - 1 }
+
+type glibcRandBytes struct{}
+
+func (glibcRandBytes) Fill(seed uint64, out []byte) {
+	g := NewGlibcRand(uint32(seed))
+	for i := range out {
+		out[i] = byte(g.Int31())
+	}
+}
+
+func (glibcRandBytes) MaxSeed() uint64 { return 1<<32 - 1 }
+
+type javaRandomBytes struct{}
+
+func (javaRandomBytes) Fill(seed uint64, out []byte) {
+	NewJavaRandom(int64(seed)).NextBytes(out)
+}
+
+func (javaRandomBytes) MaxSeed() uint64 { return javaMask }
+
+type goMathRandBytes struct{}
+
+func (goMathRandBytes) Fill(seed uint64, out []byte) {
+	// Only fails if the source does, which it can't.
+	_, _ = rand.New(rand.NewSource(int64(seed))).Read(out) //nolint:gosec
+}
+
+func (goMathRandBytes) MaxSeed() uint64 { return 1<<63 - 1 }
+
+type pythonRandomBytes struct{}
+
+// Fill is getrandbits(8·len(out)), which fills 32-bit words from the least significant up, the last
+// one shifted down to the bits left.
+func (pythonRandomBytes) Fill(seed uint64, out []byte) {
+	key := []uint32{uint32(seed)}
+	if hi := uint32(seed >> 32); hi != 0 {
+		key = append(key, hi)
+	}
+	m := NewMT19937Array(key)
+
+	for i, bits := 0, 8*len(out); bits > 0; bits -= 32 {
+		v := m.Uint32()
+		if bits < 32 {
+			v >>= uint(32 - bits)
+		}
+		for n := 0; n < 4 && i < len(out); n++ {
+			out[len(out)-1-i] = byte(v)
+			v >>= 8
+			i++
+		}
+	}
+}
+
+func (pythonRandomBytes) MaxSeed() uint64 { return 1<<64 - 1 }
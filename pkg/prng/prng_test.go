@@ -0,0 +1,129 @@
+package prng_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jakecraige/keyrecovery/pkg/hdwallet"
+	"github.com/jakecraige/keyrecovery/pkg/prng"
+)
+
+func TestMT19937(t *testing.T) {
+	m := prng.NewMT19937(5489)
+	if v := m.Uint32(); v != 3499211612 {
+		t.Errorf("first output = %d, want 3499211612", v)
+	}
+	for i := 2; i < 10000; i++ {
+		m.Uint32()
+	}
+	if v := m.Uint32(); v != 4123659995 {
+		t.Errorf("10000th output = %d, want 4123659995", v)
+	}
+
+	// random.seed(42); random.random() in Python.
+	m = prng.NewMT19937Array([]uint32{42})
+	a, b := m.Uint32()>>5, m.Uint32()>>6
+	if r := (float64(a)*67108864 + float64(b)) / (1 << 53); r != 0.6394267984578837 {
+		t.Errorf("random() = %v, want 0.6394267984578837", r)
+	}
+}
+
+func TestGlibcRand(t *testing.T) {
+	g := prng.NewGlibcRand(1)
+	for _, want := range []int32{1804289383, 846930886, 1681692777} {
+		if v := g.Int31(); v != want {
+			t.Errorf("rand() = %d, want %d", v, want)
+		}
+	}
+}
+
+func TestJavaRandom(t *testing.T) {
+	if v := prng.NewJavaRandom(42).NextInt(); v != -1170105035 {
+		t.Errorf("nextInt() = %d, want -1170105035", v)
+	}
+}
+
+func TestBxSeed(t *testing.T) {
+	// bx seed -b 256 | bx mnemonic-new at time zero, from the Milk Sad disclosure.
+	out := make([]byte, 32)
+	prng.Models["bx-seed"].Fill(0, out)
+	want := "milk sad wage cup reward umbrella raven visa give list decorate bulb gold raise twenty fly " +
+		"manual stand float super gentle climb fold park"
+	if mnemonic, err := hdwallet.Mnemonic(out); err != nil || mnemonic != want {
+		t.Errorf("mnemonic = %q, %v, want %q", mnemonic, err, want)
+	}
+}
+
+func TestPythonRandom(t *testing.T) {
+	// random.seed(seed); hex(random.getrandbits(8 * len(out))) in Python.
+	var tests = []struct {
+		seed uint64
+		want string
+	}{
+		{42, "23b8c1e9392456de3eb13b9046685257bdd640fb06671ad11c80317fa3b1799d"},
+		{42, "1ca3b1799d"},
+		{1<<40 + 5, "8454f73c811f1124"},
+	}
+
+	for _, tt := range tests {
+		out := make([]byte, len(tt.want)/2)
+		prng.Models["python-random"].Fill(tt.seed, out)
+		if got := hex.EncodeToString(out); got != tt.want {
+			t.Errorf("seed %d: got %s, want %s", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestDebianOpenSSL(t *testing.T) {
+	// There are no published keys to check against offline, so these are from a separate Python
+	// transliteration of md_rand.c and the calls openssl ecparam -genkey makes.
+	var tests = []struct {
+		model string
+		pid   uint64
+		want  string
+	}{
+		{"debian-openssl-i386-256", 1, "d35fa800cc2f5ec64cfb29125cfb66b3bc895019ea0c366220f6ef8142a3602a"},
+		{"debian-openssl-i386-256", 32767, "8ea8b08164a34fb5f48c788cd16b1d86764069b74115d34435dd1f58f9db109a"},
+		{"debian-openssl-amd64-256", 1234, "1d918cba12aa8738fbf0e9255c530f2a6f8536cdc95675c5e528dfdef8e1aab6"},
+		{"debian-openssl-i386-521", 1234, "011ed3619b14938f3b25dd45ad0973bbef9de76b783b4fe72aa149cce3a976cb" +
+			"028f8944521e0ed5f377d3330046ba7658eb9782c2ec2ed50d10b727b830f3ec6610"},
+	}
+
+	for _, tt := range tests {
+		m, err := prng.Lookup(tt.model)
+		if err != nil {
+			t.Fatalf("looking up %s: %v", tt.model, err)
+		}
+		out := make([]byte, len(tt.want)/2)
+		m.Fill(tt.pid, out)
+		if got := hex.EncodeToString(out); got != tt.want {
+			t.Errorf("%s, PID %d: got %s, want %s", tt.model, tt.pid, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"debian-openssl-ppc-256", "debian-openssl-i386-x", "debian-openssl-i386"} {
+		if _, err := prng.Lookup(name); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
+
+func TestModels(t *testing.T) {
+	for name, m := range prng.Models {
+		a, b := make([]byte, 32), make([]byte, 32)
+		m.Fill(1234, a)
+		m.Fill(1234, b)
+		if !bytes.Equal(a, b) {
+			t.Errorf("%s: same seed gave different bytes", name)
+		}
+		m.Fill(1235, b)
+		if bytes.Equal(a, b) {
+			t.Errorf("%s: different seeds gave the same bytes", name)
+		}
+	}
+
+	if _, err := prng.Lookup("openssl-rand"); err == nil {
+		t.Errorf("expected an error for an unknown model")
+	}
+}
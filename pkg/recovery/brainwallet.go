@@ -0,0 +1,429 @@
+package recovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+* Brainwallets are keys derived from a passphrase, most often d = SHA256(passphrase), which made
+* every memorable phrase anyone ever used a key someone else could guess. The search takes a
+* wordlist, mangles each word with rules, derives a key from each result under every derivation and
+* looks the public key up in the KeyTargets.
+*
+* Words are handed to the workers in batches and the checkpoint records how many lines from the
+* start of the wordlist have been searched completely, so an interrupted search resumes after them.
+ */
+
+const (
+	// brainwalletBatchSize is the number of words a worker takes at a time.
+	brainwalletBatchSize = 256
+
+	// brainwalletCheckpointInterval is how often the checkpoint is written by default.
+	brainwalletCheckpointInterval = 10 * time.Second
+)
+
+// brainwalletHashes are the hashes a derivation can be built from.
+var brainwalletHashes = map[string]func() hash.Hash{
+	"sha256":    newSHA256,
+	"sha512":    newSHA512,
+	"sha3-256":  newSHA3_256,
+	"keccak256": newKeccak256,
+	"blake2b":   newBLAKE2b512,
+}
+
+// BrainwalletDerivation turns a passphrase into the secret a key is made from.
+type BrainwalletDerivation struct {
+	spec string
+
+	newHash func() hash.Hash
+	rounds  int
+
+	scryptSalt    []byte
+	scryptN       int
+	scryptR       int
+	scryptP       int
+	scryptEnabled bool
+}
+
+// NewBrainwalletDerivation parses a derivation:
+//
+//	sha256, sha512, sha3-256, keccak256, blake2b   the hash of the passphrase
+//	sha256x1000                                    the hash applied 1000 times to its own digest
+//	scrypt:salt[:N:r:p]                            scrypt, with N=16384, r=8 and p=1 by default
+func NewBrainwalletDerivation(spec string) (*BrainwalletDerivation, error) {
+	d := &BrainwalletDerivation{spec: spec, rounds: 1}
+
+	if strings.HasPrefix(spec, "scrypt:") {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 2 && len(parts) != 5 {
+			return nil, fmt.Errorf("scrypt derivation must be scrypt:salt or scrypt:salt:N:r:p: %s", spec)
+		}
+		d.scryptEnabled = true
+		d.scryptSalt = []byte(parts[1])
+		d.scryptN, d.scryptR, d.scryptP = 16384, 8, 1
+		if len(parts) == 5 {
+			params := []*int{&d.scryptN, &d.scryptR, &d.scryptP}
+			for i, part := range parts[2:] {
+				v, err := strconv.Atoi(part)
+				if err != nil || v <= 0 {
+					return nil, fmt.Errorf("invalid scrypt parameter: %s", part)
+				}
+				*params[i] = v
+			}
+		}
+		return d, nil
+	}
+
+	name := spec
+	if i := strings.LastIndex(spec, "x"); i > 0 {
+		if rounds, err := strconv.Atoi(spec[i+1:]); err == nil {
+			if rounds <= 0 {
+				return nil, fmt.Errorf("invalid number of rounds: %s", spec)
+			}
+			name, d.rounds = spec[:i], rounds
+		}
+	}
+	if d.newHash = brainwalletHashes[name]; d.newHash == nil {
+		return nil, fmt.Errorf("unknown brainwallet derivation: %s", spec)
+	}
+	return d, nil
+}
+
+func (d *BrainwalletDerivation) String() string {
+	return d.spec
+}
+
+// Secret returns the secret derived from the passphrase.
+func (d *BrainwalletDerivation) Secret(passphrase []byte) []byte {
+	if d.scryptEnabled {
+		// Only fails for invalid parameters, which are checked when parsing.
+		out, _ := scrypt.Key(passphrase, d.scryptSalt, d.scryptN, d.scryptR, d.scryptP, 32)
+		return out
+	}
+
+	h := d.newHash()
+	digest := passphrase
+	for i := 0; i < d.rounds; i++ {
+		h.Reset()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+	return digest
+}
+
+// BrainwalletSearch is a search of a wordlist for passphrases whose keys are among the targets.
+type BrainwalletSearch struct {
+	CurveID     CurveIdentifier
+	SigID       SignatureIdentifier
+	Derivations []*BrainwalletDerivation
+
+	// Rules mangle every word, which is used as it is if there are none.
+	Rules   []*BrainwalletRule
+	Targets *KeyTargets
+
+	// Workers is the number of goroutines deriving keys, runtime.NumCPU() if zero.
+	Workers int
+
+	// Checkpoint is a file recording the progress of the search, which it resumes from if it
+	// exists. The wordlist must be the same when resuming.
+	Checkpoint string
+
+	// CheckpointInterval is how often the checkpoint is written, every ten seconds if zero.
+	CheckpointInterval time.Duration
+}
+
+// BrainwalletMatch is a passphrase whose key is one of the targets.
+type BrainwalletMatch struct {
+	Passphrase string
+	Derivation *BrainwalletDerivation
+	Target     string
+	Key        *PrivateKey
+}
+
+// BrainwalletResult is the progress of a search.
+type BrainwalletResult struct {
+	// Words is the number of lines of the wordlist searched, including those skipped by resuming.
+	Words      int64
+	Candidates int64
+	Matches    []*BrainwalletMatch
+}
+
+// brainwalletCheckpoint is the progress written to the checkpoint file.
+type brainwalletCheckpoint struct {
+	Search string `json:"search"`
+	Words  int64  `json:"words"`
+}
+
+// brainwalletBatch is a batch of words starting at line start of the wordlist, counting from zero.
+type brainwalletBatch struct {
+	start int64
+	words []string
+}
+
+type brainwalletDone struct {
+	start      int64
+	words      int
+	candidates int64
+	matches    []*BrainwalletMatch
+}
+
+// Run searches the words, one per line, calling found for each match as it's found. It stops when
+// every target has been found or ctx is done, and either way writes the checkpoint.
+func (s *BrainwalletSearch) Run(ctx context.Context, words io.Reader, found func(*BrainwalletMatch)) (*BrainwalletResult, error) {
+	scheme, err := newScheme(s.CurveID, s.SigID, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := scheme.(*dsaScheme); ok {
+		return nil, fmt.Errorf("brainwallet search needs an elliptic curve")
+	}
+	if len(s.Derivations) == 0 {
+		return nil, fmt.Errorf("no brainwallet derivations")
+	}
+	if s.Targets == nil || s.Targets.Len() == 0 {
+		return nil, fmt.Errorf("no brainwallet targets")
+	}
+
+	skip, err := s.readCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	result := &BrainwalletResult{Words: skip}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan *brainwalletBatch)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(batches)
+		readErr <- readBrainwalletBatches(ctx, words, skip, batches)
+	}()
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	done := make(chan *brainwalletDone)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if d := s.searchBatch(ctx, scheme, batch); d != nil {
+					done <- d
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Batches finish out of order, so only the words before the first unfinished one are done.
+	finished := map[int64]int{}
+	matched := map[string]bool{}
+	var checkpointErr error
+	interval := s.CheckpointInterval
+	if interval <= 0 {
+		interval = brainwalletCheckpointInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+collect:
+	for {
+		select {
+		case d, ok := <-done:
+			if !ok {
+				break collect
+			}
+			finished[d.start] = d.words
+			for n, ok := finished[result.Words]; ok; n, ok = finished[result.Words] {
+				delete(finished, result.Words)
+				result.Words += int64(n)
+			}
+
+			result.Candidates += d.candidates
+			for _, m := range d.matches {
+				result.Matches = append(result.Matches, m)
+				matched[m.Target] = true
+				if found != nil {
+					found(m)
+				}
+			}
+			if len(matched) == s.Targets.Len() {
+				cancel()
+			}
+
+		case <-ticker.C:
+			// Stop on a failed write, but only return once the workers have, so none is left
+			// blocked sending its batch.
+			if checkpointErr == nil {
+				if checkpointErr = s.writeCheckpoint(result.Words); checkpointErr != nil {
+					cancel()
+				}
+			}
+		}
+	}
+
+	if checkpointErr != nil {
+		return result, checkpointErr
+	}
+	if err := s.writeCheckpoint(result.Words); err != nil {
+		return result, err
+	}
+	return result, <-readErr
+}
+
+// readBrainwalletBatches sends the lines after the first skip in batches until the end or ctx is
+// done.
+func readBrainwalletBatches(ctx context.Context, r io.Reader, skip int64, batches chan<- *brainwalletBatch) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	batch := &brainwalletBatch{start: skip}
+	send := func() bool {
+		select {
+		case batches <- batch:
+			batch = &brainwalletBatch{start: batch.start + int64(len(batch.words))}
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for line := int64(0); scanner.Scan(); line++ {
+		if line < skip {
+			continue
+		}
+		batch.words = append(batch.words, strings.TrimSuffix(scanner.Text(), "\r"))
+		if len(batch.words) == brainwalletBatchSize && !send() {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(batch.words) > 0 {
+		send()
+	}
+	return nil
+}
+
+// searchBatch tries every candidate from the words of the batch. It returns nil if ctx is done
+// first, as the batch then doesn't count as searched.
+func (s *BrainwalletSearch) searchBatch(ctx context.Context, scheme scheme, batch *brainwalletBatch) *brainwalletDone {
+	d := &brainwalletDone{start: batch.start, words: len(batch.words)}
+	for _, word := range batch.words {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		for _, candidate := range s.candidates(word) {
+			for _, derivation := range s.Derivations {
+				k := secretKey(scheme, derivation.Secret([]byte(candidate)))
+				if k == nil {
+					continue
+				}
+				d.candidates++
+
+				pub := scheme.publicKey(k)
+				if target, ok := s.Targets.match(scheme, pub); ok {
+					d.matches = append(d.matches, &BrainwalletMatch{
+						Passphrase: candidate,
+						Derivation: derivation,
+						Target:     target,
+						Key:        newPrivateKey(scheme, k),
+					})
+				}
+			}
+		}
+	}
+	return d
+}
+
+// candidates returns the distinct results of the rules on the word.
+func (s *BrainwalletSearch) candidates(word string) []string {
+	if len(s.Rules) == 0 {
+		return []string{word}
+	}
+
+	var out []string
+	seen := map[string]bool{}
+	for _, rule := range s.Rules {
+		c := rule.Apply(word)
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// fingerprint identifies the search in the checkpoint, so a different one doesn't resume from it.
+func (s *BrainwalletSearch) fingerprint() string {
+	parts := []string{string(s.CurveID), string(s.SigID)}
+	for _, d := range s.Derivations {
+		parts = append(parts, d.String())
+	}
+	for _, r := range s.Rules {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, "\n")
+}
+
+// readCheckpoint returns the number of lines already searched.
+func (s *BrainwalletSearch) readCheckpoint() (int64, error) {
+	if s.Checkpoint == "" {
+		return 0, nil
+	}
+	data, err := ioutil.ReadFile(s.Checkpoint)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	var cp brainwalletCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return 0, fmt.Errorf("parsing checkpoint %s: %w", s.Checkpoint, err)
+	}
+	if cp.Search != s.fingerprint() {
+		return 0, fmt.Errorf("checkpoint %s is for a different search", s.Checkpoint)
+	}
+	return cp.Words, nil
+}
+
+// writeCheckpoint replaces the checkpoint with one recording words lines as searched.
+func (s *BrainwalletSearch) writeCheckpoint(words int64) error {
+	if s.Checkpoint == "" {
+		return nil
+	}
+	data, err := json.Marshal(&brainwalletCheckpoint{Search: s.fingerprint(), Words: words})
+	if err != nil {
+		return err
+	}
+
+	tmp := s.Checkpoint + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Checkpoint)
+}
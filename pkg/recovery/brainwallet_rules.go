@@ -0,0 +1,183 @@
+package recovery
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+* Word mangling rules for the brainwallet search, in the syntax of hashcat and John the Ripper so
+* existing rule files mostly work. Only the common functions are implemented; a rule using any other
+* is rejected rather than silently applied differently:
+*
+*   :    nothing                 l    lowercase               u    uppercase
+*   c    capitalize              C    invert capitalize       t    toggle case
+*   r    reverse                 d    duplicate               f    reflect, word + reverse
+*   [    delete the first        ]    delete the last         $X   append X
+*   ^X   prepend X               sXY  replace X with Y        @X   delete every X
+*
+* Spaces between functions are ignored and lines starting with # are comments.
+ */
+
+// BrainwalletRule is a sequence of mangling functions applied to each word in turn.
+type BrainwalletRule struct {
+	text string
+	ops  []func(string) string
+}
+
+// ParseBrainwalletRule parses a single rule.
+func ParseBrainwalletRule(text string) (*BrainwalletRule, error) {
+	rule := &BrainwalletRule{text: text}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		// arg returns the n-th argument of the function at i.
+		arg := func(n int) (rune, error) {
+			if i+n >= len(runes) {
+				return 0, fmt.Errorf("rule %q: %c at %d is missing an argument", text, runes[i], i)
+			}
+			return runes[i+n], nil
+		}
+
+		var op func(string) string
+		switch runes[i] {
+		case ' ', ':':
+			continue
+		case 'l':
+			op = strings.ToLower
+		case 'u':
+			op = strings.ToUpper
+		case 'c':
+			op = func(w string) string { return capitalize(strings.ToLower(w)) }
+		case 'C':
+			op = func(w string) string { return invertCapitalize(strings.ToUpper(w)) }
+		case 't':
+			op = toggleCase
+		case 'r':
+			op = reverseString
+		case 'd':
+			op = func(w string) string { return w + w }
+		case 'f':
+			op = func(w string) string { return w + reverseString(w) }
+		case '[':
+			op = func(w string) string {
+				_, n := utf8.DecodeRuneInString(w)
+				return w[n:]
+			}
+		case ']':
+			op = func(w string) string {
+				_, n := utf8.DecodeLastRuneInString(w)
+				return w[:len(w)-n]
+			}
+
+		case '$', '^', '@':
+			x, err := arg(1)
+			if err != nil {
+				return nil, err
+			}
+			switch runes[i] {
+			case '$':
+				op = func(w string) string { return w + string(x) }
+			case '^':
+				op = func(w string) string { return string(x) + w }
+			case '@':
+				op = func(w string) string { return strings.Replace(w, string(x), "", -1) }
+			}
+			i++
+
+		case 's':
+			x, err := arg(1)
+			if err != nil {
+				return nil, err
+			}
+			y, err := arg(2)
+			if err != nil {
+				return nil, err
+			}
+			op = func(w string) string { return strings.Replace(w, string(x), string(y), -1) }
+			i += 2
+
+		default:
+			return nil, fmt.Errorf("rule %q: unsupported function %c at %d", text, runes[i], i)
+		}
+		rule.ops = append(rule.ops, op)
+	}
+
+	return rule, nil
+}
+
+// LoadBrainwalletRules reads a rule file with one rule per line.
+func LoadBrainwalletRules(path string) ([]*BrainwalletRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []*BrainwalletRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := ParseBrainwalletRule(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Apply returns the word mangled by the rule.
+func (r *BrainwalletRule) Apply(word string) string {
+	for _, op := range r.ops {
+		word = op(word)
+	}
+	return word
+}
+
+func (r *BrainwalletRule) String() string {
+	return r.text
+}
+
+func capitalize(w string) string {
+	if w == "" {
+		return w
+	}
+	first, n := utf8.DecodeRuneInString(w)
+	return string(unicode.ToUpper(first)) + w[n:]
+}
+
+func invertCapitalize(w string) string {
+	if w == "" {
+		return w
+	}
+	first, n := utf8.DecodeRuneInString(w)
+	return string(unicode.ToLower(first)) + w[n:]
+}
+
+func toggleCase(w string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, w)
+}
+
+func reverseString(w string) string {
+	runes := []rune(w)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
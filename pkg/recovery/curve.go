@@ -2,7 +2,11 @@ package recovery
 
 import (
 	"crypto/elliptic"
+	"encoding/asn1"
 	"fmt"
+	"math/big"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 )
@@ -11,48 +15,333 @@ type CurveIdentifier string
 
 const (
 	Curve_S256 CurveIdentifier = "secp256k1"
+	Curve_P192 CurveIdentifier = "P192"
+	Curve_P224 CurveIdentifier = "P224"
 	Curve_P256 CurveIdentifier = "P256"
 	Curve_P384 CurveIdentifier = "P384"
 	Curve_P521 CurveIdentifier = "P521"
+
+	Curve_BrainpoolP256r1 CurveIdentifier = "brainpoolP256r1"
+	Curve_BrainpoolP384r1 CurveIdentifier = "brainpoolP384r1"
+	Curve_BrainpoolP512r1 CurveIdentifier = "brainpoolP512r1"
+
+	Curve_Ed25519 CurveIdentifier = "Ed25519"
+	Curve_SM2P256 CurveIdentifier = "SM2P256"
+	Curve_STARK   CurveIdentifier = "STARK"
+
+	Curve_TC26_256_A CurveIdentifier = "TC26-256-A"
+	Curve_TC26_256_B CurveIdentifier = "TC26-256-B"
+	Curve_TC26_512_A CurveIdentifier = "TC26-512-A"
+	Curve_TC26_512_B CurveIdentifier = "TC26-512-B"
+	Curve_TC26_512_C CurveIdentifier = "TC26-512-C"
+
+	// DSA groups are identified by the bit lengths of p and q since the parameters themselves are
+	// carried in each public key.
+	Curve_DSA_1024_160 CurveIdentifier = "DSA-1024-160"
+	Curve_DSA_2048_224 CurveIdentifier = "DSA-2048-224"
+	Curve_DSA_2048_256 CurveIdentifier = "DSA-2048-256"
+	Curve_DSA_3072_256 CurveIdentifier = "DSA-3072-256"
+)
+
+type curveKind int
+
+const (
+	curveWeierstrass curveKind = iota
+	curveEdwards
+	curveDSA
 )
 
-func (c CurveIdentifier) Curve() elliptic.Curve {
-	switch c {
-	case Curve_S256:
-		return secp256k1.S256()
-	case Curve_P256:
-		return elliptic.P256()
-	case Curve_P384:
-		return elliptic.P384()
-	case Curve_P521:
-		return elliptic.P521()
+// curveInfo is a registry entry. Curves are looked up by their identifier, any of their aliases or
+// their ASN.1 OID, so names from other tools and OIDs from certificates and key files both work.
+type curveInfo struct {
+	id      CurveIdentifier
+	aliases []string
+	oid     asn1.ObjectIdentifier
+	kind    curveKind
+	curve   func() elliptic.Curve
+	dsa     dsaSize
+
+	// custom is set for curves added with RegisterCurve.
+	custom *weierstrassCurve
+}
+
+// curveRegistryMu guards curveRegistry, which grows when custom curves are registered.
+var curveRegistryMu sync.RWMutex
+
+var curveRegistry = []curveInfo{
+	{
+		id:      Curve_S256,
+		aliases: []string{"S256", "K256", "K-256"},
+		oid:     asn1.ObjectIdentifier{1, 3, 132, 0, 10},
+		curve:   func() elliptic.Curve { return secp256k1.S256() },
+	},
+	{
+		id:      Curve_P192,
+		aliases: []string{"P-192", "secp192r1", "prime192v1"},
+		oid:     asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 1},
+		curve:   p192,
+	},
+	{
+		id:      Curve_P224,
+		aliases: []string{"P-224", "secp224r1"},
+		oid:     asn1.ObjectIdentifier{1, 3, 132, 0, 33},
+		curve:   elliptic.P224,
+	},
+	{
+		id:      Curve_P256,
+		aliases: []string{"P-256", "secp256r1", "prime256v1"},
+		oid:     asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7},
+		curve:   elliptic.P256,
+	},
+	{
+		id:      Curve_P384,
+		aliases: []string{"P-384", "secp384r1"},
+		oid:     asn1.ObjectIdentifier{1, 3, 132, 0, 34},
+		curve:   elliptic.P384,
+	},
+	{
+		id:      Curve_P521,
+		aliases: []string{"P-521", "secp521r1"},
+		oid:     asn1.ObjectIdentifier{1, 3, 132, 0, 35},
+		curve:   elliptic.P521,
+	},
+	{
+		id:    Curve_BrainpoolP256r1,
+		oid:   asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7},
+		curve: func() elliptic.Curve { return namedCurve(&brainpoolP256r1) },
+	},
+	{
+		id:    Curve_BrainpoolP384r1,
+		oid:   asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11},
+		curve: func() elliptic.Curve { return namedCurve(&brainpoolP384r1) },
+	},
+	{
+		id:    Curve_BrainpoolP512r1,
+		oid:   asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13},
+		curve: func() elliptic.Curve { return namedCurve(&brainpoolP512r1) },
+	},
+	{
+		id:      Curve_Ed25519,
+		aliases: []string{"edwards25519"},
+		oid:     asn1.ObjectIdentifier{1, 3, 101, 112},
+		kind:    curveEdwards,
+		curve:   func() elliptic.Curve { return edwards25519() },
+	},
+	{
+		id:      Curve_SM2P256,
+		aliases: []string{"SM2", "sm2p256v1"},
+		oid:     asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301},
+		curve:   func() elliptic.Curve { return sm2p256() },
+	},
+	{
+		id:      Curve_STARK,
+		aliases: []string{"stark-curve", "StarkEx"},
+		curve:   func() elliptic.Curve { return stark() },
+	},
+	{
+		id:      Curve_TC26_256_A,
+		aliases: []string{"id-tc26-gost-3410-2012-256-paramSetA"},
+		oid:     asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 1},
+		curve:   func() elliptic.Curve { return tc26Curve("256-A") },
+	},
+	{
+		id:      Curve_TC26_256_B,
+		aliases: []string{"id-tc26-gost-3410-2012-256-paramSetB"},
+		oid:     asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 2},
+		curve:   func() elliptic.Curve { return tc26Curve("256-B") },
+	},
+	{
+		id:      Curve_TC26_512_A,
+		aliases: []string{"id-tc26-gost-3410-12-512-paramSetA"},
+		oid:     asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 1},
+		curve:   func() elliptic.Curve { return tc26Curve("512-A") },
+	},
+	{
+		id:      Curve_TC26_512_B,
+		aliases: []string{"id-tc26-gost-3410-12-512-paramSetB"},
+		oid:     asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 2},
+		curve:   func() elliptic.Curve { return tc26Curve("512-B") },
+	},
+	{
+		id:      Curve_TC26_512_C,
+		aliases: []string{"id-tc26-gost-3410-2012-512-paramSetC"},
+		oid:     asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 3},
+		curve:   func() elliptic.Curve { return tc26Curve("512-C") },
+	},
+	{id: Curve_DSA_1024_160, kind: curveDSA, dsa: dsaSize{1024, 160}},
+	{id: Curve_DSA_2048_224, kind: curveDSA, dsa: dsaSize{2048, 224}},
+	{id: Curve_DSA_2048_256, kind: curveDSA, dsa: dsaSize{2048, 256}},
+	{id: Curve_DSA_3072_256, kind: curveDSA, dsa: dsaSize{3072, 256}},
+}
+
+func (c CurveIdentifier) info() *curveInfo {
+	curveRegistryMu.RLock()
+	defer curveRegistryMu.RUnlock()
+
+	for i := range curveRegistry {
+		if curveRegistry[i].id == c {
+			return &curveRegistry[i]
+		}
+	}
+
+	return nil
+}
+
+// Curves returns the identifiers of every registered curve and DSA group.
+func Curves() []CurveIdentifier {
+	curveRegistryMu.RLock()
+	defer curveRegistryMu.RUnlock()
+
+	ids := make([]CurveIdentifier, len(curveRegistry))
+	for i, info := range curveRegistry {
+		ids[i] = info.id
+	}
+	return ids
+}
+
+// Curve returns the elliptic curve, or an error for DSA groups, which have none.
+func (c CurveIdentifier) Curve() (elliptic.Curve, error) {
+	info := c.info()
+	if info == nil {
+		return nil, fmt.Errorf("unknown curve: %s", c)
+	}
+	if info.curve == nil {
+		return nil, fmt.Errorf("%s is a DSA group, not an elliptic curve", c)
+	}
+
+	return info.curve(), nil
+}
+
+// OID returns the curve's ASN.1 object identifier, or nil if it doesn't have one.
+func (c CurveIdentifier) OID() asn1.ObjectIdentifier {
+	if info := c.info(); info != nil {
+		return info.oid
+	}
+	return nil
+}
+
+// IsDSA reports whether the identifier refers to a DSA group rather than an elliptic curve.
+func (c CurveIdentifier) IsDSA() bool {
+	info := c.info()
+	return info != nil && info.kind == curveDSA
+}
+
+// IsAnomalous reports whether the order of the curve's base point is the prime of its field, which
+// makes discrete logs easy with Smart's attack.
+func (c CurveIdentifier) IsAnomalous() bool {
+	info := c.info()
+	if info == nil || info.kind != curveWeierstrass || c.IsSingular() {
+		return false
+	}
+
+	params := info.curve().Params()
+	return params.N.Cmp(params.P) == 0
+}
+
+// IsSingular reports whether the curve's discriminant is zero. Only custom curves can be singular
+// and the nonsingular points of one are isomorphic to a group of the field.
+func (c CurveIdentifier) IsSingular() bool {
+	info := c.info()
+	return info != nil && info.custom != nil && info.custom.singularity() != ""
+}
+
+func (c CurveIdentifier) dsaSize() dsaSize {
+	info := c.info()
+	if info == nil || info.kind != curveDSA {
+		panic("should be unreachable")
 	}
 
-	panic("should be unreachable")
+	return info.dsa
 }
 
+// orderBits returns the bit length of the group order.
+func (c CurveIdentifier) orderBits() int {
+	info := c.info()
+	if info.kind == curveDSA {
+		return info.dsa.N
+	}
+	return info.curve().Params().N.BitLen()
+}
+
+// coefficientA returns a of the Weierstrass curve y^2 = x^3 + a·x + b.
+func (c CurveIdentifier) coefficientA() *big.Int {
+	if custom := c.info().custom; custom != nil {
+		return custom.a
+	}
+	return curveA(c.info().curve().Params())
+}
+
+// IsSupported reports whether signatures of the given type can be made over the curve. Hashed
+// ECDSA and DSA work with any hash over any curve of the right shape since the digest is truncated
+// to the order. Schemes that are tied to one curve by their encoding only support that curve.
 func (c CurveIdentifier) IsSupported(sigID SignatureIdentifier) bool {
+	info := c.info()
+	if info == nil || sigID.info() == nil {
+		return false
+	}
+
 	switch sigID {
-	case Sig_ECDSA_SHA256, Sig_ECDSA_KECCAK256:
-		return c == Curve_S256 || c == Curve_P256 || c == Curve_P384
-	case Sig_ECDSA_SHA512:
-		return c == Curve_P521
+	case Sig_SCHNORR_BIP340:
+		return c == Curve_S256
+	case Sig_ECDSA_STARK:
+		return c == Curve_STARK
+	}
+
+	switch sigID.family() {
+	case familyECDSA, familySM2:
+		return info.kind == curveWeierstrass
+	case familyEdDSA:
+		return info.kind == curveEdwards
+	case familyDSA:
+		return info.kind == curveDSA
+	case familyGOST:
+		// GOST R 34.10-2012 pairs Streebog-256 with orders up to 256 bits and Streebog-512 with
+		// the larger ones.
+		bits, _ := sigID.hashBits()
+		return info.kind == curveWeierstrass && (c.orderBits() <= 256) == (bits == 256)
 	default:
 		return false
 	}
 }
 
+// NewCurveIdentifier looks up a curve by its identifier, one of its aliases or its dotted ASN.1
+// OID. Names are matched case-insensitively.
 func NewCurveIdentifier(id string) (CurveIdentifier, error) {
-	switch id {
-	case string(Curve_S256):
-		return Curve_S256, nil
-	case string(Curve_P256):
-		return Curve_P256, nil
-	case string(Curve_P384):
-		return Curve_P384, nil
-	case string(Curve_P521):
-		return Curve_P521, nil
-	default:
-		return "", fmt.Errorf("unsupported curve identifier: %s", id)
+	curveRegistryMu.RLock()
+	defer curveRegistryMu.RUnlock()
+
+	for _, info := range curveRegistry {
+		if matchesCurve(info, id) {
+			return info.id, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported curve identifier: %s", id)
+}
+
+func matchesCurve(info curveInfo, name string) bool {
+	if strings.EqualFold(name, string(info.id)) || (info.oid != nil && name == info.oid.String()) {
+		return true
+	}
+	for _, alias := range info.aliases {
+		if strings.EqualFold(name, alias) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CurveIdentifierFromOID looks up a curve by its ASN.1 OID.
+func CurveIdentifierFromOID(oid asn1.ObjectIdentifier) (CurveIdentifier, error) {
+	curveRegistryMu.RLock()
+	defer curveRegistryMu.RUnlock()
+
+	for _, info := range curveRegistry {
+		if info.oid != nil && info.oid.Equal(oid) {
+			return info.id, nil
+		}
 	}
+
+	return "", fmt.Errorf("unsupported curve OID: %s", oid)
 }
@@ -0,0 +1,186 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+)
+
+/*
+* User-defined short Weierstrass curves y^2 = x^3 + a·x + b. They're added to the curve registry
+* under their own name so the rest of the package, and every strategy, treats them like any of the
+* named curves. The order of G doesn't have to be prime and the curve can even be singular since weak
+* curves are exactly what custom parameters are usually for, but the parameters are checked enough
+* to catch typos.
+*
+* A parameter file is JSON with the values as decimal or 0x prefixed hex, either as strings or
+* numbers:
+*
+*   {"name": "ctf", "p": "0x...", "a": "0x...", "b": "0x...", "gx": "0x...", "gy": "0x...", "n": "0x..."}
+ */
+
+type curveParamsFile struct {
+	Name string   `json:"name"`
+	P    *jsonInt `json:"p"`
+	A    *jsonInt `json:"a"`
+	B    *jsonInt `json:"b"`
+	Gx   *jsonInt `json:"gx"`
+	Gy   *jsonInt `json:"gy"`
+	N    *jsonInt `json:"n"`
+}
+
+// jsonInt is a big.Int that can be given as a JSON number or as a decimal or hex string.
+type jsonInt struct {
+	big.Int
+}
+
+func (i *jsonInt) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	if _, ok := i.SetString(s, 0); !ok {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	return nil
+}
+
+// LoadCurveFile reads a JSON curve parameter file and registers the curve it describes.
+func LoadCurveFile(path string) (CurveIdentifier, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return ParseCurveParams(data)
+}
+
+// ParseCurveParams parses JSON curve parameters and registers the curve they describe.
+func ParseCurveParams(data []byte) (CurveIdentifier, error) {
+	var file curveParamsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", fmt.Errorf("invalid curve parameters: %w", err)
+	}
+
+	fields := []struct {
+		name  string
+		value *jsonInt
+	}{{"p", file.P}, {"a", file.A}, {"b", file.B}, {"gx", file.Gx}, {"gy", file.Gy}, {"n", file.N}}
+	for _, field := range fields {
+		if field.value == nil {
+			return "", fmt.Errorf("invalid curve parameters: missing %s", field.name)
+		}
+	}
+
+	params := &elliptic.CurveParams{
+		Name: file.Name,
+		P:    &file.P.Int,
+		N:    &file.N.Int,
+		B:    &file.B.Int,
+		Gx:   &file.Gx.Int,
+		Gy:   &file.Gy.Int,
+	}
+	return RegisterCurve(params, &file.A.Int)
+}
+
+// RegisterCurve validates the parameters of the curve y^2 = x^3 + a·x + b and adds it to the
+// registry under params.Name, which must not clash with another curve. BitSize is filled in if
+// it's zero. Registering the same parameters again returns the existing identifier.
+func RegisterCurve(params *elliptic.CurveParams, a *big.Int) (CurveIdentifier, error) {
+	if params.Name == "" {
+		return "", fmt.Errorf("invalid curve: missing name")
+	}
+	params = &elliptic.CurveParams{
+		Name:    params.Name,
+		BitSize: params.BitSize,
+		P:       new(big.Int).Set(params.P),
+		N:       new(big.Int).Set(params.N),
+		B:       new(big.Int).Set(params.B),
+		Gx:      new(big.Int).Set(params.Gx),
+		Gy:      new(big.Int).Set(params.Gy),
+	}
+	if params.BitSize == 0 {
+		// Scalars are padded to the curve size so it has to fit the order as well as coordinates.
+		params.BitSize = params.P.BitLen()
+		if params.N.BitLen() > params.BitSize {
+			params.BitSize = params.N.BitLen()
+		}
+	}
+
+	curve, err := newCustomCurve(params, a)
+	if err != nil {
+		return "", fmt.Errorf("invalid curve %s: %w", params.Name, err)
+	}
+
+	id := CurveIdentifier(params.Name)
+	curveRegistryMu.Lock()
+	defer curveRegistryMu.Unlock()
+
+	for _, info := range curveRegistry {
+		if !matchesCurve(info, params.Name) {
+			continue
+		}
+		if info.custom != nil && info.id == id && sameCurve(info.custom, curve) {
+			return id, nil
+		}
+		return "", fmt.Errorf("curve name %s is already taken", params.Name)
+	}
+
+	curveRegistry = append(curveRegistry, curveInfo{
+		id:     id,
+		curve:  func() elliptic.Curve { return curve },
+		custom: curve,
+	})
+	return id, nil
+}
+
+func newCustomCurve(params *elliptic.CurveParams, a *big.Int) (*weierstrassCurve, error) {
+	p := params.P
+	if p.Cmp(big.NewInt(3)) <= 0 || !p.ProbablyPrime(20) {
+		return nil, fmt.Errorf("p must be a prime larger than 3")
+	}
+	for _, v := range []*big.Int{a, params.B, params.Gx, params.Gy} {
+		if v.Sign() < 0 || v.Cmp(p) >= 0 {
+			return nil, fmt.Errorf("a, b and the coordinates of G must be in [0, p)")
+		}
+	}
+
+	// A singular curve, with 4a^3 + 27b^2 = 0, isn't an elliptic curve but it's accepted since
+	// attacking one is the point. IsSingular flags it.
+	curve := newWeierstrassCurve(params, a)
+	singular := curve.singularity()
+
+	if !curve.IsOnCurve(params.Gx, params.Gy) {
+		return nil, fmt.Errorf("G is not on the curve")
+	}
+	if singular != "" && params.Gy.Sign() == 0 && params.Gx.Cmp(curve.singularX()) == 0 {
+		return nil, fmt.Errorf("G is the singular point")
+	}
+
+	// n·G must be the point at infinity and the group order, a multiple of n, must be within the
+	// Hasse bound p + 1 ± 2√p.
+	n := params.N
+	if n.Cmp(one) <= 0 {
+		return nil, fmt.Errorf("n must be larger than 1")
+	}
+	if x, y := curve.ScalarBaseMult(n.Bytes()); !curve.isInfinity(x, y) {
+		return nil, fmt.Errorf("n·G is not the point at infinity")
+	}
+	bound := new(big.Int).Sqrt(new(big.Int).Lsh(p, 2))
+	bound.Add(bound, one)
+	low := new(big.Int).Add(p, one)
+	high := new(big.Int).Add(low, bound)
+	low.Sub(low, bound)
+	multiple := new(big.Int).Div(high, n)
+	if multiple.Sign() == 0 || multiple.Mul(multiple, n).Cmp(low) < 0 {
+		return nil, fmt.Errorf("no multiple of n is a possible group order")
+	}
+
+	return curve, nil
+}
+
+func sameCurve(c1, c2 *weierstrassCurve) bool {
+	p1, p2 := c1.params, c2.params
+	return c1.a.Cmp(c2.a) == 0 && p1.P.Cmp(p2.P) == 0 && p1.N.Cmp(p2.N) == 0 && p1.B.Cmp(p2.B) == 0 &&
+		p1.Gx.Cmp(p2.Gx) == 0 && p1.Gy.Cmp(p2.Gy) == 0
+}
@@ -0,0 +1,111 @@
+package recovery
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+* Detection of the curve and signature type of signatures without any metadata. Every supported
+* combination is tried on a sample of the signatures: the key and signature lengths have to fit the
+* data, the public key has to be on the curve, and then the signatures have to verify under the
+* combination's hash. Without public keys the signers are recovered first, which only finds a key
+* shared by several signatures with the right curve and hash, so it works the same way.
+ */
+
+// detectSampleSize is how many signatures are checked against each candidate, which is enough to
+// tell them apart while keeping the number of verifications down.
+const detectSampleSize = 6
+
+// Detection is a curve and signature type that the input signatures verify under.
+type Detection struct {
+	CurveID CurveIdentifier
+	SigID   SignatureIdentifier
+
+	// Verified is how many of the Checked signatures verified. Faulty or deliberately invalid
+	// signatures mean it won't always be all of them.
+	Verified int
+	Checked  int
+}
+
+func (d Detection) String() string {
+	return fmt.Sprintf("%s %s (%d/%d sample signatures verified)", d.CurveID, d.SigID, d.Verified, d.Checked)
+}
+
+// Detect returns the combinations of curve and signature type which the serialized signatures
+// verify under, with the best match first. A nil curves or sigIDs tries every registered one.
+func Detect(data [][]byte, format string, curves []CurveIdentifier, sigIDs []SignatureIdentifier) ([]Detection, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no signatures to detect the curve and signature type from")
+	}
+	if curves == nil {
+		curves = Curves()
+	}
+	if sigIDs == nil {
+		sigIDs = Signatures()
+	}
+	if len(data) > detectSampleSize {
+		data = data[:detectSampleSize]
+	}
+
+	var detections []Detection
+	for _, curveID := range curves {
+		for _, sigID := range sigIDs {
+			if !curveID.IsSupported(sigID) {
+				continue
+			}
+
+			if verified := detectVerified(data, format, curveID, sigID); verified > 0 {
+				detections = append(detections, Detection{
+					CurveID:  curveID,
+					SigID:    sigID,
+					Verified: verified,
+					Checked:  len(data),
+				})
+			}
+		}
+	}
+	if len(detections) == 0 {
+		return nil, fmt.Errorf("the signatures don't verify under any supported curve and signature type")
+	}
+
+	sort.SliceStable(detections, func(i, j int) bool {
+		return detections[i].Verified > detections[j].Verified
+	})
+	return detections, nil
+}
+
+// detectVerified returns how many of the signatures verify when parsed as the curve and signature
+// type, or zero if they can't be parsed as it at all.
+func detectVerified(data [][]byte, format string, curveID CurveIdentifier, sigID SignatureIdentifier) int {
+	scheme, err := newScheme(curveID, sigID, "")
+	if err != nil {
+		return 0
+	}
+
+	sigs := make([]*Signature, len(data))
+	for i, d := range data {
+		if sigs[i], err = SignatureFromBytes(d, curveID, sigID, format); err != nil {
+			return 0
+		}
+	}
+
+	if format == Format_SigMsg {
+		if _, ok := scheme.(pubRecoverer); !ok || recoverSigners(scheme, sigs) != nil {
+			return 0
+		}
+	}
+
+	verified := 0
+	for _, sig := range sigs {
+		// Signatures whose signer couldn't be recovered can't be checked.
+		if sig.Pub == nil {
+			continue
+		}
+		bound, err := schemeForSignatures(curveID, sigID, "", []*Signature{sig})
+		if err == nil && bound.verify(sig) {
+			verified++
+		}
+	}
+	return verified
+}
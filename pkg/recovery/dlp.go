@@ -0,0 +1,244 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+	"runtime"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+/*
+* Discrete logs of public keys straight from their bytes, for every scheme. Keys known to lie in an
+* interval, such as puzzle wallets, keys with most of their bits leaked or nonces that are simply
+* small, use the parallel kangaroo, and anything that narrows a scalar down to an interval can hand
+* the rest to solveInterval. Keys in toy or deliberately weakened groups are solved over the whole
+* group order instead. Both are the generic solvers of pkg/dlp, on the curve's points or, for DSA,
+* GF(p)*.
+*
+* BIP340 public keys are only the x coordinate, which is shared by d·G and (n - d)·G, so both points
+* are searched for in an interval. Over the whole group either one gives a key with the same x.
+ */
+
+const (
+	// intervalMaxBits bounds the interval widths searched without being asked to, as the kangaroo
+	// takes around 2^(bits/2 + 1) point additions.
+	intervalMaxBits = 56
+
+	// discreteLogMaxBits bounds the group orders solved at all. Rho takes around 2^(bits/2) steps,
+	// so even this is out of reach without a lot of time and cores.
+	discreteLogMaxBits = 96
+)
+
+// publicGroup returns the group of the scheme, its generator and the public key as an element.
+func publicGroup(scheme scheme, pub []byte) (dlp.Group, element, element, error) {
+	if s, ok := scheme.(*dsaScheme); ok {
+		group, y, err := s.parsePub(pub)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return newGFp(group.P), element{group.G}, element{y}, nil
+	}
+
+	curve, qx, qy, err := publicPoint(scheme, pub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	params := curve.Params()
+	var grp dlp.Group = dlp.CurveGroup(curve)
+	if edwards, ok := curve.(*edwardsCurve); ok {
+		grp = edwardsGroup{edwards}
+	}
+	return grp, element{params.Gx, params.Gy}, element{qx, qy}, nil
+}
+
+// publicPoint returns the curve of the scheme and the point of the public key.
+func publicPoint(scheme scheme, pub []byte) (elliptic.Curve, *big.Int, *big.Int, error) {
+	var curve elliptic.Curve
+	var qx, qy *big.Int
+	var err error
+	switch s := scheme.(type) {
+	case *schnorrScheme:
+		curve = s.curve
+		qx, qy, err = s.liftX(pub)
+
+	case *eddsaScheme:
+		curve = s.curve
+		qx, qy, err = s.curve.decodePoint(pub)
+
+	case *ecdsaScheme:
+		curve = s.curve
+		qx, qy, err = parseUncompressed(curve, pub)
+	case *sm2Scheme:
+		curve = s.curve
+		qx, qy, err = parseUncompressed(curve, pub)
+	case *gostScheme:
+		curve = s.curve
+		qx, qy, err = parseUncompressed(curve, pub)
+
+	default:
+		return nil, nil, nil, fmt.Errorf("discrete logs are not supported for the signature type")
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return curve, qx, qy, nil
+}
+
+// parseUncompressed parses a public key serialized as x||y.
+func parseUncompressed(curve elliptic.Curve, pub []byte) (*big.Int, *big.Int, error) {
+	byteLen := byteLen(curve)
+	if len(pub) != 2*byteLen {
+		return nil, nil, fmt.Errorf("public key must be %d bytes", 2*byteLen)
+	}
+
+	x := new(big.Int).SetBytes(pub[:byteLen])
+	y := new(big.Int).SetBytes(pub[byteLen:])
+	if !curve.IsOnCurve(x, y) {
+		return nil, nil, fmt.Errorf("public key is not on the curve")
+	}
+	return x, y, nil
+}
+
+// solveInterval returns the private key for pub, which must be in [lo, hi].
+func solveInterval(scheme scheme, pub []byte, lo, hi *big.Int, opts *dlp.Options) (*PrivateKey, error) {
+	return solveLinear(scheme, pub, new(big.Int), one, lo, hi, opts)
+}
+
+// solveLinear returns the private key d = a + b·x mod n for pub with x in [lo, hi], with the
+// kangaroo on g^b and h·g^-a. Keys in an interval have a = 0 and b = 1, and keys behind a small
+// nonce take a and b from the nonce relation.
+func solveLinear(scheme scheme, pub []byte, a, b, lo, hi *big.Int, opts *dlp.Options) (*PrivateKey, error) {
+	if bs, ok := scheme.(boundScheme); ok {
+		var err error
+		if scheme, err = bs.bind(pub); err != nil {
+			return nil, err
+		}
+	}
+	n := scheme.order()
+	a, b = new(big.Int).Mod(a, n), new(big.Int).Mod(b, n)
+	if b.Sign() == 0 {
+		return nil, fmt.Errorf("the key doesn't depend on the unknown")
+	}
+
+	grp, g, h, err := publicGroup(scheme, pub)
+	if err != nil {
+		return nil, err
+	}
+	base, negated := grp.Exp(g, b), grp.Inverse(h)
+	if a.Sign() != 0 {
+		// Not every curve's addition takes the identity, so h is only shifted when it has to be.
+		shift := grp.Inverse(grp.Exp(g, a))
+		h, negated = grp.Op(h, shift), grp.Op(negated, shift)
+	}
+
+	var result *dlp.Result
+	if _, ok := scheme.(*schnorrScheme); ok {
+		result, err = solveEither(grp, base, h, negated, lo, hi, opts)
+	} else {
+		result, err = dlp.Kangaroo(grp, base, h, lo, hi, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d := new(big.Int).Mul(b, result.D)
+	d.Add(d, a)
+	priv := newPrivateKey(scheme, d.Mod(d, n))
+	if !bytes.Equal(priv.Pub, pub) {
+		return nil, fmt.Errorf("recovered key doesn't match the public key")
+	}
+	return priv, nil
+}
+
+// solveEither searches for two targets at the same time, splitting the workers between them, and
+// returns the first discrete log found.
+func solveEither(grp dlp.Group, g, h1, h2 element, lo, hi *big.Int, opts *dlp.Options) (*dlp.Result, error) {
+	half := dlp.Options{}
+	if opts != nil {
+		half = *opts
+	}
+	if half.Workers <= 0 {
+		half.Workers = runtime.NumCPU()
+	}
+	half.Workers = (half.Workers + 1) / 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type outcome struct {
+		result *dlp.Result
+		err    error
+	}
+	outcomes := make(chan outcome, 2)
+	for _, h := range []element{h1, h2} {
+		go func(h element) {
+			result, err := dlp.KangarooContext(ctx, grp, g, h, lo, hi, &half)
+			outcomes <- outcome{result, err}
+		}(h)
+	}
+
+	var err error
+	for i := 0; i < 2; i++ {
+		o := <-outcomes
+		if o.err == nil {
+			return o.result, nil
+		}
+		err = o.err
+	}
+	return nil, err
+}
+
+// discreteLog returns the private key for pub over the whole group order.
+func discreteLog(scheme scheme, pub []byte, opts *dlp.Options) (*PrivateKey, error) {
+	if bs, ok := scheme.(boundScheme); ok {
+		var err error
+		if scheme, err = bs.bind(pub); err != nil {
+			return nil, err
+		}
+	}
+
+	grp, g, h, err := publicGroup(scheme, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	n := scheme.order()
+	if n.BitLen() > discreteLogMaxBits {
+		return nil, fmt.Errorf("the group order is %d bits, too large to solve", n.BitLen())
+	}
+	result, err := dlp.Solve(grp, g, h, n, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	priv := newPrivateKey(scheme, result.D)
+	if !bytes.Equal(priv.Pub, pub) {
+		return nil, fmt.Errorf("recovered key doesn't match the public key")
+	}
+	return priv, nil
+}
+
+// SolveDiscreteLog recovers the private key of pub with a generic discrete log over the whole group,
+// which is only feasible for small or deliberately weakened groups.
+func (c *Config) SolveDiscreteLog(pub []byte, opts *dlp.Options) (*PrivateKey, error) {
+	scheme, err := newScheme(c.curveID, c.sigID, c.sm2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return discreteLog(scheme, pub, opts)
+}
+
+// SolveInterval recovers the private key of pub, which must be known to lie in [lo, hi].
+func (c *Config) SolveInterval(pub []byte, lo, hi *big.Int, opts *dlp.Options) (*PrivateKey, error) {
+	scheme, err := newScheme(c.curveID, c.sigID, c.sm2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return solveInterval(scheme, pub, lo, hi, opts)
+}
@@ -0,0 +1,216 @@
+package recovery
+
+import (
+	"crypto/dsa"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+/*
+* DSA over the order q subgroup of Z_p* generated by g. Unlike the curves, DSA domain parameters are
+* usually generated per key, so the public key of each signature carries them along with y as
+* p || q || g || y. The group identifier only fixes their sizes. The signature equation is the same
+* as ECDSA with r = (g^k mod p) mod q, so the same nonce relation applies over q.
+ */
+
+// DSAGroup is the cyclic group of prime order Q generated by G in Z_P*.
+type DSAGroup struct {
+	P, Q, G *big.Int
+}
+
+// Validate checks that the domain parameters describe a subgroup of prime order Q.
+func (g *DSAGroup) Validate() error {
+	if !g.P.ProbablyPrime(20) {
+		return fmt.Errorf("dsa p is not prime")
+	}
+	if !g.Q.ProbablyPrime(20) {
+		return fmt.Errorf("dsa q is not prime")
+	}
+	if new(big.Int).Mod(new(big.Int).Sub(g.P, one), g.Q).Sign() != 0 {
+		return fmt.Errorf("dsa q does not divide p-1")
+	}
+	if g.G.Cmp(one) <= 0 || g.G.Cmp(g.P) >= 0 || new(big.Int).Exp(g.G, g.Q, g.P).Cmp(one) != 0 {
+		return fmt.Errorf("dsa g does not generate a subgroup of order q")
+	}
+
+	return nil
+}
+
+// dsaSize is the bit length of p (L) and q (N) for a set of domain parameters.
+type dsaSize struct {
+	L, N int
+}
+
+func (s dsaSize) sizes() dsa.ParameterSizes {
+	switch s {
+	case dsaSize{1024, 160}:
+		return dsa.L1024N160
+	case dsaSize{2048, 224}:
+		return dsa.L2048N224
+	case dsaSize{2048, 256}:
+		return dsa.L2048N256
+	case dsaSize{3072, 256}:
+		return dsa.L3072N256
+	}
+
+	panic("should be unreachable")
+}
+
+type dsaScheme struct {
+	size  dsaSize
+	sigID SignatureIdentifier
+
+	// group is nil until bound to the parameters from a public key or generated.
+	group *DSAGroup
+}
+
+func (s *dsaScheme) order() *big.Int {
+	return s.group.Q
+}
+
+func (s *dsaScheme) pubLen() int {
+	return s.size.L/8*3 + s.size.N/8
+}
+
+func (s *dsaScheme) sigLen() int {
+	return s.size.N / 8 * 2
+}
+
+// bind returns a copy of the scheme using the domain parameters from the public key.
+func (s *dsaScheme) bind(pub []byte) (scheme, error) {
+	group, _, err := s.parsePub(pub)
+	if err != nil {
+		return nil, err
+	}
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &dsaScheme{size: s.size, sigID: s.sigID, group: group}, nil
+}
+
+func (s *dsaScheme) parsePub(pub []byte) (*DSAGroup, *big.Int, error) {
+	if len(pub) != s.pubLen() {
+		return nil, nil, fmt.Errorf("invalid dsa public key length: %d", len(pub))
+	}
+	pLen, qLen := s.size.L/8, s.size.N/8
+
+	group := &DSAGroup{
+		P: new(big.Int).SetBytes(pub[:pLen]),
+		Q: new(big.Int).SetBytes(pub[pLen : pLen+qLen]),
+		G: new(big.Int).SetBytes(pub[pLen+qLen : 2*pLen+qLen]),
+	}
+	y := new(big.Int).SetBytes(pub[2*pLen+qLen:])
+
+	return group, y, nil
+}
+
+// generateKey generates a new key, first generating domain parameters if none are bound.
+func (s *dsaScheme) generateKey(rand io.Reader) (*big.Int, error) {
+	if s.group == nil {
+		params := &dsa.Parameters{}
+		if err := dsa.GenerateParameters(params, rand, s.size.sizes()); err != nil {
+			return nil, err
+		}
+		s.group = &DSAGroup{P: params.P, Q: params.Q, G: params.G}
+	}
+
+	x, err := randScalar(s.group.Q)
+	if err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (s *dsaScheme) publicKey(x *big.Int) []byte {
+	pLen, qLen := s.size.L/8, s.size.N/8
+	y := new(big.Int).Exp(s.group.G, x, s.group.P)
+
+	out := make([]byte, 0, s.pubLen())
+	out = append(out, leftPad(s.group.P.Bytes(), pLen)...)
+	out = append(out, leftPad(s.group.Q.Bytes(), qLen)...)
+	out = append(out, leftPad(s.group.G.Bytes(), pLen)...)
+	out = append(out, leftPad(y.Bytes(), pLen)...)
+	return out
+}
+
+func (s *dsaScheme) sign(x, k *big.Int, msg []byte) ([]byte, error) {
+	q := s.group.Q
+
+	// r = (g^k mod p) mod q and s = k⁻¹(z + x·r) mod q
+	r := new(big.Int).Exp(s.group.G, k, s.group.P)
+	r.Mod(r, q)
+	kInv := new(big.Int).ModInverse(k, q)
+	if r.Sign() == 0 || kInv == nil {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	sig := new(big.Int).Mul(x, r)
+	sig.Add(sig, s.hashToInt(msg))
+	sig.Mul(sig, kInv)
+	sig.Mod(sig, q)
+	if sig.Sign() == 0 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	qLen := s.size.N / 8
+	out := make([]byte, qLen*2)
+	copy(out, leftPad(r.Bytes(), qLen))
+	copy(out[qLen:], leftPad(sig.Bytes(), qLen))
+	return out, nil
+}
+
+func (s *dsaScheme) verify(sig *Signature) bool {
+	group, y, err := s.parsePub(sig.Pub)
+	if err != nil || len(sig.Sig) != 2*s.size.N/8 || group.Validate() != nil {
+		return false
+	}
+	if y.Cmp(one) <= 0 || y.Cmp(group.P) >= 0 || new(big.Int).Exp(y, group.Q, group.P).Cmp(one) != 0 {
+		return false
+	}
+	bound := &dsaScheme{size: s.size, sigID: s.sigID, group: group}
+
+	q := group.Q
+	qLen := s.size.N / 8
+	r := new(big.Int).SetBytes(sig.Sig[:qLen])
+	w := new(big.Int).SetBytes(sig.Sig[qLen:])
+	if r.Sign() == 0 || r.Cmp(q) >= 0 || w.Sign() == 0 || w.Cmp(q) >= 0 {
+		return false
+	}
+	w.ModInverse(w, q)
+
+	// v = (g^(z·w) · y^(r·w) mod p) mod q must equal r
+	u1 := bound.hashToInt(sig.Msg)
+	u1.Mul(u1, w).Mod(u1, q)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, q)
+
+	v := new(big.Int).Exp(group.G, u1, group.P)
+	v.Mul(v, new(big.Int).Exp(y, u2, group.P))
+	v.Mod(v, group.P)
+	v.Mod(v, q)
+
+	return v.Cmp(r) == 0
+}
+
+func (s *dsaScheme) nonceRelation(sig *Signature) (*big.Int, *big.Int, error) {
+	q := s.order()
+	qLen := s.size.N / 8
+	r := new(big.Int).SetBytes(sig.Sig[:qLen])
+	sInv := new(big.Int).SetBytes(sig.Sig[qLen:])
+	if sInv.ModInverse(sInv, q) == nil {
+		return nil, nil, fmt.Errorf("invalid signature, s has no inverse")
+	}
+	z := s.hashToInt(sig.Msg)
+
+	// s = k⁻¹(z + r·x) so k = z·s⁻¹ + r·s⁻¹·x
+	alpha := z.Mul(z, sInv)
+	beta := r.Mul(r, sInv)
+	return alpha.Mod(alpha, q), beta.Mod(beta, q), nil
+}
+
+// hashToInt hashes msg and keeps the leftmost N bits as FIPS 186 specifies.
+func (s *dsaScheme) hashToInt(msg []byte) *big.Int {
+	return hashToIntBits(hashBytes(s.sigID.Hash(), msg), s.size.N)
+}
@@ -0,0 +1,259 @@
+package recovery
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+* ECDH oracles: a server with a static private key d that takes a peer's public point P, computes
+* d·P and answers with a tag derived from the shared x coordinate, such as the hash of it or a MAC
+* keyed with it. A server that doesn't check that P is on its curve (or, taking only x, that it
+* isn't on the twist) leaks d mod the order of P, which ecdh_attack.go turns into the key.
+*
+* LocalECDHOracle is such a server, deliberately vulnerable, for tests and demos. The others talk
+* to a real one, either a local process speaking hex lines on stdin and stdout or an HTTP endpoint
+* which takes the hex point as the POST body and returns the hex tag.
+ */
+
+type ECDHTag string
+
+const (
+	// ECDHTag_SHA256 is SHA-256 of the shared x coordinate.
+	ECDHTag_SHA256 ECDHTag = "sha256"
+
+	// ECDHTag_HMACSHA256 is HMAC-SHA256 of ecdhMACMessage keyed with SHA-256 of the shared x
+	// coordinate, like a key confirmation message.
+	ECDHTag_HMACSHA256 ECDHTag = "hmac-sha256"
+)
+
+const ecdhMACMessage = "keyrecovery ecdh oracle"
+
+func NewECDHTag(id string) (ECDHTag, error) {
+	switch tag := ECDHTag(strings.ToLower(id)); tag {
+	case ECDHTag_SHA256, ECDHTag_HMACSHA256:
+		return tag, nil
+	default:
+		return "", fmt.Errorf("unknown ecdh tag: %s", id)
+	}
+}
+
+// compute returns the tag for the encoded shared x coordinate.
+func (t ECDHTag) compute(sharedX []byte) []byte {
+	key := sha256.Sum256(sharedX)
+	if t == ECDHTag_SHA256 {
+		return key[:]
+	}
+
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(ecdhMACMessage))
+	return mac.Sum(nil)
+}
+
+// ECDHOracle answers ECDH queries with a tag derived from the shared secret. Points are x||y, or
+// only x for oracles that use x-only arithmetic, with each coordinate padded to the curve size.
+type ECDHOracle interface {
+	Query(point []byte) ([]byte, error)
+}
+
+// LocalECDHOracle is a deliberately vulnerable ECDH server. It computes d·P with formulas that
+// never check P is on the curve.
+type LocalECDHOracle struct {
+	curveID CurveIdentifier
+	curve   elliptic.Curve
+	d       *big.Int
+	tag     ECDHTag
+	xOnly   bool
+
+	// queries counts the queries answered, updated atomically since ServeHTTP runs concurrently.
+	queries int64
+}
+
+// NewLocalECDHOracle returns an oracle for the private key d, or a random one if d is nil.
+func NewLocalECDHOracle(curveID CurveIdentifier, d *big.Int, tag ECDHTag, xOnly bool) (*LocalECDHOracle, error) {
+	if !curveID.IsSupported(Sig_ECDSA_SHA256) {
+		return nil, fmt.Errorf("ecdh is only supported on Weierstrass curves")
+	}
+	curve, err := curveID.Curve()
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		if d, err = randScalar(curve.Params().N); err != nil {
+			return nil, err
+		}
+	}
+
+	return &LocalECDHOracle{curveID: curveID, curve: curve, d: d, tag: tag, xOnly: xOnly}, nil
+}
+
+// PublicKey returns d·G serialized as x||y.
+func (o *LocalECDHOracle) PublicKey() []byte {
+	x, y := o.curve.ScalarBaseMult(o.d.Bytes())
+	byteLen := byteLen(o.curve)
+	return append(leftPad(x.Bytes(), byteLen), leftPad(y.Bytes(), byteLen)...)
+}
+
+// Queries returns the number of queries answered.
+func (o *LocalECDHOracle) Queries() int {
+	return int(atomic.LoadInt64(&o.queries))
+}
+
+func (o *LocalECDHOracle) Query(point []byte) ([]byte, error) {
+	params := o.curve.Params()
+	byteLen := byteLen(o.curve)
+	atomic.AddInt64(&o.queries, 1)
+
+	var sharedX *big.Int
+	if o.xOnly {
+		if len(point) != byteLen {
+			return nil, fmt.Errorf("point must be %d bytes", byteLen)
+		}
+		curve := &xOnlyCurve{p: params.P, a: o.curveID.coefficientA(), b: params.B}
+		sharedX = curve.affine(curve.scalarMult(new(big.Int).SetBytes(point), o.d))
+	} else {
+		if len(point) != 2*byteLen {
+			return nil, fmt.Errorf("point must be %d bytes", 2*byteLen)
+		}
+		// The bug: nothing checks that the point is on the curve, and the addition formulas don't
+		// depend on b so they work on whichever curve it's on.
+		curve := newWeierstrassCurve(params, o.curveID.coefficientA())
+		x := new(big.Int).SetBytes(point[:byteLen])
+		y := new(big.Int).SetBytes(point[byteLen:])
+		sharedX, _ = curve.ScalarMult(new(big.Int).Mod(x, params.P), new(big.Int).Mod(y, params.P), o.d.Bytes())
+	}
+
+	return o.tag.compute(leftPad(sharedX.Bytes(), byteLen)), nil
+}
+
+// Serve answers hex encoded queries, one per line, until r is exhausted.
+func (o *LocalECDHOracle) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		resp, err := o.queryHex(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, resp); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ServeHTTP answers a hex encoded query in the POST body.
+func (o *LocalECDHOracle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := o.queryHex(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Fprintln(w, resp)
+}
+
+func (o *LocalECDHOracle) queryHex(query string) (string, error) {
+	point, err := hex.DecodeString(strings.TrimSpace(query))
+	if err != nil {
+		return "", err
+	}
+	tag, err := o.Query(point)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tag), nil
+}
+
+// ProcessECDHOracle queries a local process that reads hex points from stdin and writes hex tags
+// to stdout, one per line.
+type ProcessECDHOracle struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Scanner
+}
+
+// NewProcessECDHOracle starts the shell command.
+func NewProcessECDHOracle(command string) (*ProcessECDHOracle, error) {
+	cmd := exec.Command("sh", "-c", command)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &ProcessECDHOracle{cmd: cmd, in: in, out: bufio.NewScanner(out)}, nil
+}
+
+func (o *ProcessECDHOracle) Query(point []byte) ([]byte, error) {
+	if _, err := fmt.Fprintf(o.in, "%x\n", point); err != nil {
+		return nil, err
+	}
+	if !o.out.Scan() {
+		if err := o.out.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("oracle process exited")
+	}
+
+	return hex.DecodeString(strings.TrimSpace(o.out.Text()))
+}
+
+// Close closes the process's stdin and waits for it to exit.
+func (o *ProcessECDHOracle) Close() error {
+	o.in.Close()
+	return o.cmd.Wait()
+}
+
+// HTTPECDHOracle queries an HTTP endpoint that takes a hex point as the POST body and returns the
+// hex tag.
+type HTTPECDHOracle struct {
+	URL    string
+	Client *http.Client
+}
+
+func (o *HTTPECDHOracle) Query(point []byte) ([]byte, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(o.URL, "text/plain", bytes.NewBufferString(hex.EncodeToString(point)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oracle returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return hex.DecodeString(strings.TrimSpace(string(body)))
+}
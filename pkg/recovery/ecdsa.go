@@ -46,18 +46,7 @@ func randFieldElement(c elliptic.Curve, rand io.Reader) (k *big.Int, err error)
 // OpenSSL right shifts excess bits from the number if the hash is too large
 // and we mirror that too.
 func hashToInt(hash []byte, c elliptic.Curve) *big.Int {
-	orderBits := c.Params().N.BitLen()
-	orderBytes := (orderBits + 7) / 8
-	if len(hash) > orderBytes {
-		hash = hash[:orderBytes]
-	}
-
-	ret := new(big.Int).SetBytes(hash)
-	excess := len(hash)*8 - orderBits
-	if excess > 0 {
-		ret.Rsh(ret, uint(excess))
-	}
-	return ret
+	return hashToIntBits(hash, c.Params().N.BitLen())
 }
 
 // fermatInverse calculates the inverse of k in GF(P) using Fermat's method.
@@ -72,12 +61,34 @@ func fermatInverse(k, N *big.Int) *big.Int {
 
 var errZeroParam = errors.New("zero parameter")
 
+// faultLocation is where a simulated fault corrupts the signature computation.
+type faultLocation int
+
+const (
+	// faultR flips a bit of r after it's computed but before it's used for s.
+	faultR faultLocation = iota
+	// faultHash flips a bit of the truncated message hash.
+	faultHash
+	// faultKey flips a bit of the private key while computing s.
+	faultKey
+)
+
+// ecdsaFault describes a single bit flip injected into a signature computation.
+type ecdsaFault struct {
+	location faultLocation
+	bit      uint
+}
+
 // Sign signs a hash (which should be the result of hashing a larger message)
-// using the private key, priv. If the hash is longer than the bit-length of the
-// private key's curve order, the hash will be truncated to that length. It
+// using the private key, priv. Unlike the stdlib the hash is passed as the
+// integer e, already truncated with hashToInt or otherwise derived from the
+// message, since not every scheme hashes its messages the same way. It
 // returns the signature as a pair of integers. The security of the private key
 // depends on the entropy of rand.
-func ecdsaSign(priv *ecdsa.PrivateKey, k *big.Int, c elliptic.Curve, hash []byte) (r, s *big.Int, err error) {
+//
+// If fault is not nil, the bit flip it describes is injected into the computation to simulate a
+// glitched signer.
+func ecdsaSign(priv *ecdsa.PrivateKey, k *big.Int, c elliptic.Curve, hash *big.Int, fault *ecdsaFault) (r, s *big.Int, err error) {
 	N := c.Params().N
 	if N.Sign() == 0 {
 		return nil, nil, errZeroParam
@@ -87,8 +98,11 @@ func ecdsaSign(priv *ecdsa.PrivateKey, k *big.Int, c elliptic.Curve, hash []byte
 		for {
 			if in, ok := priv.Curve.(invertible); ok {
 				kInv = in.Inverse(k)
-			} else {
+			} else if N.ProbablyPrime(20) {
 				kInv = fermatInverse(k, N) // N != 0
+			} else if kInv = new(big.Int).ModInverse(k, N); kInv == nil {
+				// Custom curves can have a composite order, where not every nonce is invertible.
+				return nil, nil, errors.New("nonce is not invertible")
 			}
 
 			r, _ = priv.Curve.ScalarBaseMult(k.Bytes())
@@ -98,8 +112,21 @@ func ecdsaSign(priv *ecdsa.PrivateKey, k *big.Int, c elliptic.Curve, hash []byte
 			}
 		}
 
-		e := hashToInt(hash, c)
-		s = new(big.Int).Mul(priv.D, r)
+		d := priv.D
+		e := new(big.Int).Set(hash)
+		if fault != nil {
+			flip := new(big.Int).Lsh(one, fault.bit)
+			switch fault.location {
+			case faultR:
+				r = new(big.Int).Xor(r, flip)
+			case faultHash:
+				e.Xor(e, flip)
+			case faultKey:
+				d = new(big.Int).Xor(d, flip)
+			}
+		}
+
+		s = new(big.Int).Mul(d, r)
 		s.Add(s, e)
 		s.Mul(s, kInv)
 		s.Mod(s, N) // N != 0
@@ -107,6 +134,10 @@ func ecdsaSign(priv *ecdsa.PrivateKey, k *big.Int, c elliptic.Curve, hash []byte
 			break
 		}
 	}
+	if new(big.Int).GCD(nil, nil, s, N).Cmp(one) != 0 {
+		// Likewise s, and a signature whose s isn't invertible can't be verified.
+		return nil, nil, errors.New("signature is not invertible")
+	}
 
 	return
 }
@@ -0,0 +1,159 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+)
+
+/*
+* ECDSA public key recovery, better known as ecrecover. The x coordinate of the nonce point is
+* r + j·n for some small j and its y coordinate is one of the two square roots, so each recovery id
+* gives a candidate point R and the public key Q = r⁻¹(s·R − z·G). A single signature leaves a few
+* candidates, but the real key is the only one shared by every signature it made, so cross-matching
+* the candidates of several signatures identifies it.
+*
+* There are up to h + 1 values of j, for the cofactor h, which is at most a few for the named curves.
+* Custom curves can have n much smaller than p, and their signatures aren't tried.
+ */
+
+// maxRecoveryCofactor is the largest cofactor public keys are recovered on, since each x
+// coordinate r + j·n up to p gives a candidate.
+const maxRecoveryCofactor = 1024
+
+// pubRecoverer is implemented by schemes that can recover public keys from signatures.
+type pubRecoverer interface {
+	recoverPublicKeys(sig *Signature) ([][]byte, error)
+}
+
+func (s *ecdsaScheme) recoverPublicKeys(sig *Signature) ([][]byte, error) {
+	params := s.curve.Params()
+	n := params.N
+	if h := cofactorBound(params); h.Cmp(big.NewInt(maxRecoveryCofactor)) > 0 {
+		return nil, fmt.Errorf("public key recovery needs a cofactor of at most %d, %s allows up to %s",
+			maxRecoveryCofactor, params.Name, h)
+	}
+	byteLen := byteLen(s.curve)
+	if len(sig.Sig) != 2*byteLen {
+		return nil, fmt.Errorf("invalid signature length: %d", len(sig.Sig))
+	}
+	r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	sv := new(big.Int).SetBytes(sig.Sig[byteLen:])
+	rInv := new(big.Int).ModInverse(r, n)
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || sv.Sign() == 0 || sv.Cmp(n) >= 0 || rInv == nil {
+		return nil, nil
+	}
+
+	// -z·r⁻¹·G is shared by all the candidates.
+	z := s.digest(sig.Msg)
+	u1 := z.Mul(z, rInv)
+	u1.Neg(u1).Mod(u1, n)
+	gx, gy := s.curve.ScalarBaseMult(u1.Bytes())
+	u2 := new(big.Int).Mul(sv, rInv)
+	u2.Mod(u2, n)
+
+	var pubs [][]byte
+	a := curveA(params)
+	for x := new(big.Int).Set(r); x.Cmp(params.P) < 0; x.Add(x, n) {
+		// y^2 = x^3 + a·x + b
+		y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+		y2.Add(y2, new(big.Int).Mul(a, x))
+		y2.Add(y2, params.B)
+		y := new(big.Int).ModSqrt(y2.Mod(y2, params.P), params.P)
+		if y == nil {
+			continue
+		}
+
+		for _, ry := range []*big.Int{y, new(big.Int).Sub(params.P, y)} {
+			qx, qy := s.curve.ScalarMult(x, ry, u2.Bytes())
+			if qx.Cmp(gx) == 0 {
+				// The candidate would be the point at infinity or double G, neither of which is a
+				// plausible key.
+				continue
+			}
+			qx, qy = s.curve.Add(qx, qy, gx, gy)
+			pubs = append(pubs, s.publicKeyFromPoint(qx, qy))
+		}
+	}
+
+	return pubs, nil
+}
+
+// cofactorBound returns ⌊(p + 1 + 2√p)/n⌋, the largest cofactor the Hasse bound allows.
+func cofactorBound(params *elliptic.CurveParams) *big.Int {
+	h := new(big.Int).Sqrt(new(big.Int).Lsh(params.P, 2))
+	h.Add(h, params.P)
+	h.Add(h, one)
+	return h.Div(h, params.N)
+}
+
+func (s *ecdsaScheme) publicKeyFromPoint(x, y *big.Int) []byte {
+	byteLen := byteLen(s.curve)
+	out := make([]byte, byteLen*2)
+	copy(out, leftPad(x.Bytes(), byteLen))
+	copy(out[byteLen:], leftPad(y.Bytes(), byteLen))
+	return out
+}
+
+// curveA returns the a coefficient of the short Weierstrass curve y^2 = x^3 + a·x + b, which
+// elliptic.CurveParams doesn't store, by solving for it from the base point.
+func curveA(params *elliptic.CurveParams) *big.Int {
+	p := params.P
+	a := new(big.Int).Mul(params.Gy, params.Gy)
+	a.Sub(a, new(big.Int).Exp(params.Gx, big.NewInt(3), p))
+	a.Sub(a, params.B)
+	a.Mul(a, new(big.Int).ModInverse(params.Gx, p))
+	return a.Mod(a, p)
+}
+
+// recoverSigners fills in the public key of each signature that doesn't have one from the
+// candidates recovered from it, picking the candidate shared with the most other signatures.
+// Signatures which don't share a candidate with any other, such as faulty ones, are left without a
+// public key, since any key given to them would be a guess it can't have been made with.
+func recoverSigners(scheme scheme, sigs []*Signature) error {
+	pr, ok := scheme.(pubRecoverer)
+	if !ok {
+		return fmt.Errorf("public key recovery is not supported for the signature type")
+	}
+
+	candidates := make([][][]byte, len(sigs))
+	counts := make(map[string]int)
+	var dominant []byte
+	for i, sig := range sigs {
+		if sig.Pub != nil {
+			candidates[i] = [][]byte{sig.Pub}
+		} else {
+			var err error
+			if candidates[i], err = pr.recoverPublicKeys(sig); err != nil {
+				return err
+			}
+		}
+		for _, pub := range candidates[i] {
+			counts[string(pub)]++
+			if counts[string(pub)] > counts[string(dominant)] {
+				dominant = pub
+			}
+		}
+	}
+	if counts[string(dominant)] < 2 {
+		return fmt.Errorf("can't identify any signer, no two signatures share a public key candidate")
+	}
+
+	for i, sig := range sigs {
+		if sig.Pub != nil {
+			continue
+		}
+
+		var best []byte
+		bestCount := 1
+		for _, pub := range candidates[i] {
+			if count := counts[string(pub)]; count > bestCount {
+				best, bestCount = pub, count
+			}
+		}
+
+		sig.Pub = best
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package recovery
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+/*
+* Ed25519 as specified in RFC 8032. Signatures are R || S where R = rB is the encoded nonce point
+* and S = r + H(R || A || M)·a mod L. Keys are handled as the clamped secret scalar a rather than the
+* seed it's normally derived from, since the scalar is all that can be recovered and is sufficient
+* to produce valid signatures.
+ */
+
+type eddsaScheme struct {
+	curve *edwardsCurve
+}
+
+func (s *eddsaScheme) order() *big.Int {
+	return s.curve.Params().N
+}
+
+func (s *eddsaScheme) pubLen() int {
+	return 32
+}
+
+func (s *eddsaScheme) sigLen() int {
+	return 64
+}
+
+func (s *eddsaScheme) generateKey(rand io.Reader) (*big.Int, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, err
+	}
+	return s.expandSeed(seed), nil
+}
+
+// expandSeed returns the scalar of the Ed25519 key with the seed, the lower half of its hash
+// clamped.
+func (s *eddsaScheme) expandSeed(seed []byte) *big.Int {
+	h := sha512.Sum512(seed)
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+
+	a := new(big.Int).SetBytes(reverse(h[:32]))
+	return a.Mod(a, s.order())
+}
+
+func (s *eddsaScheme) publicKey(d *big.Int) []byte {
+	return s.curve.encodePoint(s.curve.ScalarBaseMult(d.Bytes()))
+}
+
+func (s *eddsaScheme) sign(d, k *big.Int, msg []byte) ([]byte, error) {
+	return s.signWithPub(d, k, s.publicKey(d), msg)
+}
+
+// signWithPub signs msg while binding the signature to the provided public key rather than the one
+// derived from d. This mirrors APIs which accept the public key separately from the secret and
+// don't check that they match.
+func (s *eddsaScheme) signWithPub(d, k *big.Int, pub, msg []byte) ([]byte, error) {
+	n := s.order()
+	R := s.curve.encodePoint(s.curve.ScalarBaseMult(k.Bytes()))
+
+	// S = k + H(R || A || M)·a
+	S := s.challenge(R, pub, msg)
+	S.Mul(S, d)
+	S.Add(S, k)
+	S.Mod(S, n)
+
+	sig := make([]byte, 64)
+	copy(sig, R)
+	copy(sig[32:], reverse(leftPad(S.Bytes(), 32)))
+	return sig, nil
+}
+
+func (s *eddsaScheme) verify(sig *Signature) bool {
+	// ed25519.Verify panics on a key of the wrong length.
+	if len(sig.Pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(sig.Pub, sig.Msg, sig.Sig)
+}
+
+func (s *eddsaScheme) nonceRelation(sig *Signature) (*big.Int, *big.Int, error) {
+	n := s.order()
+	R, S := sig.Sig[:32], new(big.Int).SetBytes(reverse(sig.Sig[32:]))
+	if S.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("invalid signature, S is not reduced")
+	}
+
+	// S = k + h·a so k = S - h·a
+	beta := s.challenge(R, sig.Pub, sig.Msg)
+	beta.Neg(beta)
+	return S, beta.Mod(beta, n), nil
+}
+
+// challenge computes H(R || A || M) mod L.
+func (s *eddsaScheme) challenge(R, pub, msg []byte) *big.Int {
+	h := sha512.New()
+	h.Write(R)
+	h.Write(pub)
+	h.Write(msg)
+
+	e := new(big.Int).SetBytes(reverse(h.Sum(nil)))
+	return e.Mod(e, s.order())
+}
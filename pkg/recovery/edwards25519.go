@@ -0,0 +1,159 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+/*
+* edwards25519 is the twisted Edwards curve -x^2 + y^2 = 1 + d*x^2*y^2 used by Ed25519. It is
+* implemented against the elliptic.Curve interface so that it can be used anywhere the Weierstrass
+* curves are. The arithmetic uses the complete affine addition law and math/big so it is neither
+* fast nor constant time, which is fine for recovering keys but it should never be used to protect
+* them.
+ */
+
+type edwardsCurve struct {
+	params *elliptic.CurveParams
+	d      *big.Int
+}
+
+var (
+	initEd25519  sync.Once
+	ed25519Curve *edwardsCurve
+)
+
+func edwards25519() *edwardsCurve {
+	initEd25519.Do(func() {
+		p := new(big.Int).Sub(new(big.Int).Lsh(one, 255), big.NewInt(19))
+
+		// d = -121665/121666
+		d := new(big.Int).ModInverse(big.NewInt(121666), p)
+		d.Mul(d, big.NewInt(-121665))
+		d.Mod(d, p)
+
+		ed25519Curve = &edwardsCurve{
+			params: &elliptic.CurveParams{
+				Name:    "Ed25519",
+				BitSize: 255,
+				P:       p,
+				N:       bigFromDecimal("7237005577332262213973186563042994240857116359379907606001950938285454250989"),
+				Gx:      bigFromDecimal("15112221349535400772501151409588531511454012693041857206046113283949847762202"),
+				Gy:      bigFromDecimal("46316835694926478169428394003475163141307993866256225615783033603165251855960"),
+			},
+			d: d,
+		}
+	})
+
+	return ed25519Curve
+}
+
+func (c *edwardsCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *edwardsCurve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	x2 := new(big.Int).Mul(x, x)
+	y2 := new(big.Int).Mul(y, y)
+
+	// -x^2 + y^2 - 1 - d*x^2*y^2 == 0
+	lhs := new(big.Int).Sub(y2, x2)
+	lhs.Sub(lhs, one)
+	rhs := new(big.Int).Mul(x2, y2)
+	rhs.Mul(rhs, c.d)
+
+	return lhs.Sub(lhs, rhs).Mod(lhs, p).Sign() == 0
+}
+
+func (c *edwardsCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+
+	// x3 = (x1*y2 + y1*x2) / (1 + d*x1*x2*y1*y2)
+	// y3 = (y1*y2 + x1*x2) / (1 - d*x1*x2*y1*y2)
+	dxy := new(big.Int).Mul(x1, x2)
+	dxy.Mul(dxy, y1)
+	dxy.Mul(dxy, y2)
+	dxy.Mul(dxy, c.d)
+	dxy.Mod(dxy, p)
+
+	xNum := new(big.Int).Add(new(big.Int).Mul(x1, y2), new(big.Int).Mul(y1, x2))
+	xDen := new(big.Int).Add(one, dxy)
+	yNum := new(big.Int).Add(new(big.Int).Mul(y1, y2), new(big.Int).Mul(x1, x2))
+	yDen := new(big.Int).Sub(one, dxy)
+
+	x3 := xNum.Mul(xNum, xDen.ModInverse(xDen.Mod(xDen, p), p))
+	y3 := yNum.Mul(yNum, yDen.ModInverse(yDen.Mod(yDen, p), p))
+
+	return x3.Mod(x3, p), y3.Mod(y3, p)
+}
+
+func (c *edwardsCurve) Double(x, y *big.Int) (*big.Int, *big.Int) {
+	return c.Add(x, y, x, y)
+}
+
+func (c *edwardsCurve) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	// The identity element of an Edwards curve is (0, 1) and the addition law is complete, so
+	// there are no special cases.
+	rx, ry := big.NewInt(0), big.NewInt(1)
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			rx, ry = c.Double(rx, ry)
+			if (b>>uint(bit))&1 == 1 {
+				rx, ry = c.Add(rx, ry, x, y)
+			}
+		}
+	}
+
+	return rx, ry
+}
+
+func (c *edwardsCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+// encodePoint returns the RFC 8032 encoding of a point which is the little endian y coordinate
+// with the sign of x stored in the top bit.
+func (c *edwardsCurve) encodePoint(x, y *big.Int) []byte {
+	out := reverse(leftPad(y.Bytes(), 32))
+	out[31] |= byte(x.Bit(0) << 7)
+	return out
+}
+
+func (c *edwardsCurve) decodePoint(data []byte) (*big.Int, *big.Int, error) {
+	if len(data) != 32 {
+		return nil, nil, fmt.Errorf("invalid point length: %d", len(data))
+	}
+	p := c.params.P
+
+	buf := reverse(data)
+	sign := uint(buf[0] >> 7)
+	buf[0] &= 0x7f
+	y := new(big.Int).SetBytes(buf)
+	if y.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("invalid point encoding")
+	}
+
+	// x^2 = (y^2 - 1) / (d*y^2 + 1)
+	y2 := new(big.Int).Mul(y, y)
+	num := new(big.Int).Sub(y2, one)
+	den := new(big.Int).Mul(c.d, y2)
+	den.Add(den, one)
+	x2 := num.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	x2.Mod(x2, p)
+
+	x := new(big.Int).ModSqrt(x2, p)
+	if x == nil {
+		return nil, nil, fmt.Errorf("invalid point encoding, not on curve")
+	}
+	if x.Sign() == 0 && sign == 1 {
+		return nil, nil, fmt.Errorf("invalid point encoding")
+	}
+	if x.Bit(0) != sign {
+		x.Sub(p, x)
+	}
+
+	return x, y, nil
+}
@@ -0,0 +1,14 @@
+package recovery
+
+import "math/big"
+
+// GOSTSignDigest signs the digest e with the nonce k, for checking against the examples in
+// GOST R 34.10-2012, which start from the digest rather than a message.
+func GOSTSignDigest(curveID CurveIdentifier, sigID SignatureIdentifier, d, k, e *big.Int) ([]byte, error) {
+	scheme, err := newScheme(curveID, sigID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return scheme.(*gostScheme).signDigest(d, k, e)
+}
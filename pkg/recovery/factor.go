@@ -0,0 +1,159 @@
+package recovery
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+/*
+* Integer factorization for group orders: trial division by the small primes and then Pollard's rho
+* with Brent's cycle detection, which finds factors up to around 50 bits quickly. That's as far as it
+* needs to go, since a subgroup whose order has no factor that size can't be attacked anyway. A
+* cofactor that can't be split in the iteration budget is kept as composite.
+ */
+
+const (
+	trialDivisionBound = 1 << 16
+	rhoIterations      = 1 << 20
+)
+
+// OrderFactor is a prime power factor of a group order. Composite is set for a cofactor that
+// couldn't be factored, in which case Exponent is 1.
+type OrderFactor struct {
+	Prime     *big.Int
+	Exponent  int
+	Composite bool
+}
+
+func (f OrderFactor) String() string {
+	switch {
+	case f.Composite:
+		return fmt.Sprintf("[%d-bit composite]", f.Prime.BitLen())
+	case f.Exponent > 1:
+		return fmt.Sprintf("%s^%d", f.Prime, f.Exponent)
+	default:
+		return f.Prime.String()
+	}
+}
+
+// FormatFactorization formats the factors as a product.
+func FormatFactorization(factors []OrderFactor) string {
+	parts := make([]string, len(factors))
+	for i, f := range factors {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, " · ")
+}
+
+// factorize returns the prime power factors of n in increasing order, with any cofactor it can't
+// split last.
+func factorize(n *big.Int) []OrderFactor {
+	var primes, composites []*big.Int
+	m := new(big.Int).Set(n)
+
+	// Composite divisors never divide what's left once their prime factors have been divided out.
+	rem := new(big.Int)
+	for q := int64(2); q < trialDivisionBound && m.Cmp(one) > 0; q++ {
+		bq := big.NewInt(q)
+		for {
+			quo, r := new(big.Int).QuoRem(m, bq, rem)
+			if r.Sign() != 0 {
+				break
+			}
+			m = quo
+			primes = append(primes, bq)
+		}
+	}
+
+	stack := []*big.Int{m}
+	for len(stack) > 0 {
+		m := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case m.Cmp(one) == 0:
+		case m.ProbablyPrime(20):
+			primes = append(primes, m)
+		default:
+			if f := pollardRho(m); f != nil {
+				stack = append(stack, f, new(big.Int).Div(m, f))
+			} else {
+				composites = append(composites, m)
+			}
+		}
+	}
+
+	sort.Slice(primes, func(i, j int) bool { return primes[i].Cmp(primes[j]) < 0 })
+	var factors []OrderFactor
+	for _, p := range primes {
+		if last := len(factors) - 1; last >= 0 && factors[last].Prime.Cmp(p) == 0 {
+			factors[last].Exponent++
+			continue
+		}
+		factors = append(factors, OrderFactor{Prime: p, Exponent: 1})
+	}
+	for _, c := range composites {
+		factors = append(factors, OrderFactor{Prime: c, Exponent: 1, Composite: true})
+	}
+
+	return factors
+}
+
+// pollardRho returns a non-trivial factor of the composite n, or nil if it doesn't find one within
+// the iteration budget.
+func pollardRho(n *big.Int) *big.Int {
+	for c := int64(1); c < 3; c++ {
+		if f := pollardBrent(n, big.NewInt(c), rhoIterations); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// pollardBrent runs Brent's variant of Pollard's rho with f(x) = x^2 + c, batching the gcds.
+func pollardBrent(n, c *big.Int, iterations int) *big.Int {
+	const batch = 128
+
+	f := func(x *big.Int) *big.Int {
+		x.Mul(x, x)
+		x.Add(x, c)
+		return x.Mod(x, n)
+	}
+
+	y, x, ys := big.NewInt(2), new(big.Int), new(big.Int)
+	g, q := big.NewInt(1), big.NewInt(1)
+	diff := new(big.Int)
+	for r, iters := 1, 0; g.Cmp(one) == 0 && iters < iterations; r *= 2 {
+		x.Set(y)
+		for i := 0; i < r; i++ {
+			f(y)
+		}
+		for k := 0; k < r && g.Cmp(one) == 0; k += batch {
+			ys.Set(y)
+			for i := 0; i < batch && i < r-k; i++ {
+				f(y)
+				q.Mul(q, diff.Sub(x, y).Abs(diff))
+				q.Mod(q, n)
+				iters++
+			}
+			g.GCD(nil, nil, q, n)
+		}
+	}
+
+	if g.Cmp(n) == 0 {
+		// The batch overshot, so step through it one at a time.
+		for {
+			f(ys)
+			g.GCD(nil, nil, diff.Sub(x, ys).Abs(diff), n)
+			if g.Cmp(one) != 0 {
+				break
+			}
+		}
+	}
+	if g.Cmp(one) == 0 || g.Cmp(n) == 0 {
+		return nil
+	}
+
+	return g
+}
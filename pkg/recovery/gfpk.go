@@ -0,0 +1,301 @@
+package recovery
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+/*
+* The finite field GF(p^k) = GF(p)[s]/(f(s)) for a monic irreducible f of degree k, which the
+* singular curve and MOV attacks move discrete logs into. Elements are the k coefficients of a
+* polynomial in s, lowest first, and as a dlp.Group it's the multiplicative group. Like the curves it
+* uses math/big and is slow, but the fields are at most a few times the size of the curve's.
+ */
+
+type gfpk struct {
+	p *big.Int
+
+	// f holds the coefficients of the modulus below its leading 1.
+	f []*big.Int
+}
+
+// newGFp returns GF(p) itself as an extension of degree one.
+func newGFp(p *big.Int) *gfpk {
+	return &gfpk{p: p, f: []*big.Int{new(big.Int)}}
+}
+
+// newGFp2 returns GF(p^2) = GF(p)[s]/(s^2 - c) for a non-residue c, so s is a square root of c.
+func newGFp2(p, c *big.Int) *gfpk {
+	return &gfpk{p: p, f: []*big.Int{new(big.Int).Mod(new(big.Int).Neg(c), p), new(big.Int)}}
+}
+
+// newGFpk returns GF(p^k) with a random irreducible modulus. About one in k monic polynomials is
+// irreducible so it doesn't take many tries.
+func newGFpk(p *big.Int, k int) (*gfpk, error) {
+	if k == 1 {
+		return newGFp(p), nil
+	}
+
+	for i := 0; i < 64*k; i++ {
+		field := &gfpk{p: p, f: make([]*big.Int, k)}
+		for j := range field.f {
+			var err error
+			if field.f[j], err = rand.Int(rand.Reader, p); err != nil {
+				return nil, err
+			}
+		}
+		if field.irreducible() {
+			return field, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no irreducible polynomial of degree %d found", k)
+}
+
+func (g *gfpk) degree() int {
+	return len(g.f)
+}
+
+// size returns q = p^k, the number of elements.
+func (g *gfpk) size() *big.Int {
+	return new(big.Int).Exp(g.p, big.NewInt(int64(g.degree())), nil)
+}
+
+func (g *gfpk) fromInt(x *big.Int) element {
+	e := g.zero()
+	e[0].Mod(x, g.p)
+	return e
+}
+
+func (g *gfpk) zero() element {
+	e := make(element, g.degree())
+	for i := range e {
+		e[i] = new(big.Int)
+	}
+	return e
+}
+
+// isBase reports whether the element is in GF(p).
+func (g *gfpk) isBase(x element) bool {
+	for _, v := range x[1:] {
+		if v.Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *gfpk) add(x, y element) element {
+	r := make(element, len(x))
+	for i := range x {
+		r[i] = new(big.Int).Add(x[i], y[i])
+		r[i].Mod(r[i], g.p)
+	}
+	return r
+}
+
+func (g *gfpk) sub(x, y element) element {
+	r := make(element, len(x))
+	for i := range x {
+		r[i] = new(big.Int).Sub(x[i], y[i])
+		r[i].Mod(r[i], g.p)
+	}
+	return r
+}
+
+// scale multiplies by an element of GF(p).
+func (g *gfpk) scale(x element, c *big.Int) element {
+	r := make(element, len(x))
+	for i := range x {
+		r[i] = new(big.Int).Mul(x[i], c)
+		r[i].Mod(r[i], g.p)
+	}
+	return r
+}
+
+func (g *gfpk) Identity() element {
+	return g.fromInt(one)
+}
+
+func (g *gfpk) Op(x, y element) element {
+	k := g.degree()
+	prod := make([]*big.Int, 2*k-1)
+	for i := range prod {
+		prod[i] = new(big.Int)
+	}
+	t := new(big.Int)
+	for i := range x {
+		for j := range y {
+			prod[i+j].Add(prod[i+j], t.Mul(x[i], y[j]))
+		}
+	}
+
+	// s^k = -(f_0 + f_1·s + ... + f_{k-1}·s^(k-1)), from the top down.
+	for i := 2*k - 2; i >= k; i-- {
+		c := prod[i].Mod(prod[i], g.p)
+		for j, fj := range g.f {
+			prod[i-k+j].Sub(prod[i-k+j], t.Mul(c, fj))
+		}
+	}
+
+	r := make(element, k)
+	for i := range r {
+		r[i] = prod[i].Mod(prod[i], g.p)
+	}
+	return r
+}
+
+// Inverse returns x^(q-2), which is x⁻¹ for x != 0.
+func (g *gfpk) Inverse(x element) element {
+	return g.Exp(x, new(big.Int).Sub(g.size(), big.NewInt(2)))
+}
+
+func (g *gfpk) Exp(x element, k *big.Int) element {
+	r := g.Identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = g.Op(r, r)
+		if k.Bit(i) == 1 {
+			r = g.Op(r, x)
+		}
+	}
+	return r
+}
+
+func (g *gfpk) Key(x element) string {
+	return dlp.EncodeElement(x, (g.p.BitLen()+7)/8)
+}
+
+func (g *gfpk) random() (element, error) {
+	r := make(element, g.degree())
+	for i := range r {
+		var err error
+		if r[i], err = rand.Int(rand.Reader, g.p); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// sqrt returns a square root of x with Tonelli–Shanks, or false if x isn't a square.
+func (g *gfpk) sqrt(x element) (element, bool) {
+	if dlp.Equal(x, g.zero()) {
+		return g.zero(), true
+	}
+
+	// q - 1 = 2^e·t for odd t
+	q1 := new(big.Int).Sub(g.size(), one)
+	e := q1.TrailingZeroBits()
+	t := new(big.Int).Rsh(q1, e)
+	half := new(big.Int).Rsh(q1, 1)
+	if !dlp.Equal(g.Exp(x, half), g.Identity()) {
+		return nil, false
+	}
+
+	// Half the elements are non-residues.
+	var z element
+	for {
+		var err error
+		if z, err = g.random(); err != nil {
+			return nil, false
+		}
+		if !dlp.Equal(z, g.zero()) && !dlp.Equal(g.Exp(z, half), g.Identity()) {
+			break
+		}
+	}
+
+	m := e
+	c := g.Exp(z, t)
+	u := g.Exp(x, t)
+	r := g.Exp(x, new(big.Int).Rsh(new(big.Int).Add(t, one), 1))
+	for !dlp.Equal(u, g.Identity()) {
+		// The least i with u^(2^i) = 1
+		i := uint(0)
+		for u2 := u; !dlp.Equal(u2, g.Identity()); i++ {
+			u2 = g.Op(u2, u2)
+		}
+
+		b := c
+		for j := uint(0); j < m-i-1; j++ {
+			b = g.Op(b, b)
+		}
+		m = i
+		c = g.Op(b, b)
+		u = g.Op(u, c)
+		r = g.Op(r, b)
+	}
+
+	return r, true
+}
+
+// irreducible runs Rabin's test on the modulus: f of degree k is irreducible if and only if
+// s^(p^k) = s mod f and gcd(s^(p^(k/r)) - s, f) = 1 for each prime r dividing k. The powers are
+// taken in GF(p)[s]/(f), which is a ring even when f isn't irreducible.
+func (g *gfpk) irreducible() bool {
+	k := g.degree()
+	s := g.zero()
+	s[1].SetInt64(1)
+
+	// frobenius[i] = s^(p^i)
+	frobenius := []element{s}
+	for i := 1; i <= k; i++ {
+		frobenius = append(frobenius, g.Exp(frobenius[i-1], g.p))
+	}
+	if !dlp.Equal(frobenius[k], s) {
+		return false
+	}
+
+	modulus := append(append([]*big.Int{}, g.f...), big.NewInt(1))
+	for r := 2; r <= k; r++ {
+		if k%r != 0 || !big.NewInt(int64(r)).ProbablyPrime(0) {
+			continue
+		}
+		diff := g.sub(frobenius[k/r], s)
+		if len(polyGCD(diff, modulus, g.p)) != 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// polyGCD returns the gcd of two polynomials over GF(p), with coefficients lowest first, up to a
+// constant factor.
+func polyGCD(a, b []*big.Int, p *big.Int) []*big.Int {
+	a, b = polyTrim(a), polyTrim(b)
+	for len(b) > 0 {
+		a, b = b, polyMod(a, b, p)
+	}
+	return a
+}
+
+// polyMod returns a mod b over GF(p).
+func polyMod(a, b []*big.Int, p *big.Int) []*big.Int {
+	r := make([]*big.Int, len(a))
+	for i := range a {
+		r[i] = new(big.Int).Set(a[i])
+	}
+	lead := new(big.Int).ModInverse(b[len(b)-1], p)
+	t := new(big.Int)
+	for len(r) >= len(b) {
+		c := new(big.Int).Mul(r[len(r)-1], lead)
+		c.Mod(c, p)
+		shift := len(r) - len(b)
+		for i, bi := range b {
+			r[shift+i].Sub(r[shift+i], t.Mul(c, bi))
+			r[shift+i].Mod(r[shift+i], p)
+		}
+		r = polyTrim(r)
+	}
+	return r
+}
+
+// polyTrim drops the zero coefficients at the top.
+func polyTrim(a []*big.Int) []*big.Int {
+	for len(a) > 0 && a[len(a)-1].Sign() == 0 {
+		a = a[:len(a)-1]
+	}
+	return a
+}
@@ -0,0 +1,246 @@
+package recovery
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+/*
+* GOST R 34.10-2012 signatures with Streebog over the TC26 curves. The digest is read as a little
+* endian integer e and a signature is s || r, as the standard and RFC 7091 encode it, with
+* r = (k·G).x mod q and s = r·d + k·e mod q. That gives k = e⁻¹·s − e⁻¹·r·d, linear in d like the
+* other schemes.
+*
+* 256-A and 512-C are twisted Edwards curves, which TC26 also gives in short Weierstrass form with
+* a ≠ -3. That's the form used here, so they have a cofactor of 4 but are otherwise like the others.
+ */
+
+var (
+	initTC26 sync.Once
+	tc26256A *weierstrassCurve
+	tc26256B *elliptic.CurveParams
+	tc26512A *elliptic.CurveParams
+	tc26512B *elliptic.CurveParams
+	tc26512C *weierstrassCurve
+)
+
+func initTC26Curves() {
+	// id-tc26-gost-3410-2012-256-paramSetA
+	tc26256A = newWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "TC26-256-A",
+		BitSize: 256,
+		P:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFD97"),
+		N:       hexInt("400000000000000000000000000000000FD8CDDFC87B6635C115AF556C360C67"),
+		B:       hexInt("295F9BAE7428ED9CCC20E7C359A9D41A22FCCD9108E17BF7BA9337A6F8AE9513"),
+		Gx:      hexInt("91E38443A5E82C0D880923425712B2BB658B9196932E02C78B2582FE742DAA28"),
+		Gy:      hexInt("32879423AB1A0375895786C4BB46E9565FDE0B5344766740AF268ADB32322E5C"),
+	}, hexInt("C2173F1513981673AF4892C23035A27CE25E2013BF95AA33B22C656F277E7335"))
+
+	// id-tc26-gost-3410-2012-256-paramSetB, which is the same curve as CryptoPro-A.
+	tc26256B = &elliptic.CurveParams{
+		Name:    "TC26-256-B",
+		BitSize: 256,
+		P:       new(big.Int).Sub(new(big.Int).Lsh(one, 256), big.NewInt(617)),
+		N:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF6C611070995AD10045841B09B761B893"),
+		B:       big.NewInt(166),
+		Gx:      big.NewInt(1),
+		Gy:      hexInt("8D91E471E0989CDA27DF505A453F2B7635294F2DDF23E3B122ACC99C9E9F1E14"),
+	}
+
+	// id-tc26-gost-3410-12-512-paramSetA
+	tc26512A = &elliptic.CurveParams{
+		Name:    "TC26-512-A",
+		BitSize: 512,
+		P:       new(big.Int).Sub(new(big.Int).Lsh(one, 512), big.NewInt(569)),
+		N: hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF" +
+			"27E69532F48D89116FF22B8D4E0560609B4B38ABFAD2B85DCACDB1411F10B275"),
+		B: hexInt("E8C2505DEDFC86DDC1BD0B2B6667F1DA34B82574761CB0E879BD081CFD0B6265" +
+			"EE3CB090F30D27614CB4574010DA90DD862EF9D4EBEE4761503190785A71C760"),
+		Gx: big.NewInt(3),
+		Gy: hexInt("7503CFE87A836AE3A61B8816E25450E6CE5E1C93ACF1ABC1778064FDCBEFA921" +
+			"DF1626BE4FD036E93D75E6A50E3A41E98028FE5FC235F5B889A589CB5215F2A4"),
+	}
+
+	// id-tc26-gost-3410-12-512-paramSetB
+	tc26512B = &elliptic.CurveParams{
+		Name:    "TC26-512-B",
+		BitSize: 512,
+		P:       new(big.Int).Add(new(big.Int).Lsh(one, 511), big.NewInt(0x6F)),
+		N: hexInt("8000000000000000000000000000000000000000000000000000000000000001" +
+			"49A1EC142565A545ACFDB77BD9D40CFA8B996712101BEA0EC6346C54374F25BD"),
+		B: hexInt("687D1B459DC841457E3E06CF6F5E2517B97C7D614AF138BCBF85DC806C4B289F" +
+			"3E965D2DB1416D217F8B276FAD1AB69C50F78BEE1FA3106EFB8CCBC7C5140116"),
+		Gx: big.NewInt(2),
+		Gy: hexInt("1A8F7EDA389B094C2C071E3647A8940F3C123B697578C213BE6DD9E6C8EC7335" +
+			"DCB228FD1EDF4A39152CBCAAF8C0398828041055F94CEEEC7E21340780FE41BD"),
+	}
+
+	// id-tc26-gost-3410-2012-512-paramSetC
+	tc26512C = newWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "TC26-512-C",
+		BitSize: 512,
+		P:       new(big.Int).Sub(new(big.Int).Lsh(one, 512), big.NewInt(569)),
+		N: hexInt("3FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF" +
+			"C98CDBA46506AB004C33A9FF5147502CC8EDA9E7A769A12694623CEF47F023ED"),
+		B: hexInt("B4C4EE28CEBC6C2C8AC12952CF37F16AC7EFB6A9F69F4B57FFDA2E4F0DE5ADE0" +
+			"38CBC2FFF719D2C18DE0284B8BFEF3B52B8CC7A5F5BF0A3C8D2319A5312557E1"),
+		Gx: hexInt("E2E31EDFC23DE7BDEBE241CE593EF5DE2295B7A9CBAEF021D385F7074CEA043A" +
+			"A27272A7AE602BF2A7B9033DB9ED3610C6FB85487EAE97AAC5BC7928C1950148"),
+		Gy: hexInt("F5CE40D95B5EB899ABBCCFF5911CB8577939804D6527378B8C108C3D2090FF9B" +
+			"E18E2D33E3021ED2EF32D85822423B6304F726AA854BAE07D0396E9A9ADDC40F"),
+	}, hexInt("DC9203E514A721875485A529D2C722FB187BC8980EB866644DE41C68E1430645"+
+		"46E861C0E2C9EDD92ADE71F46FCF50FF2AD97F951FDA9F2A2EB6546F39689BD3"))
+}
+
+func tc26Curve(name string) elliptic.Curve {
+	initTC26.Do(initTC26Curves)
+
+	switch name {
+	case "256-A":
+		return tc26256A
+	case "256-B":
+		return tc26256B
+	case "512-A":
+		return tc26512A
+	case "512-B":
+		return tc26512B
+	case "512-C":
+		return tc26512C
+	}
+
+	panic("should be unreachable")
+}
+
+type gostScheme struct {
+	curve elliptic.Curve
+	sigID SignatureIdentifier
+}
+
+func (s *gostScheme) order() *big.Int {
+	return s.curve.Params().N
+}
+
+func (s *gostScheme) pubLen() int {
+	return byteLen(s.curve) * 2
+}
+
+func (s *gostScheme) sigLen() int {
+	return byteLen(s.curve) * 2
+}
+
+func (s *gostScheme) generateKey(rand io.Reader) (*big.Int, error) {
+	key, err := ecdsa.GenerateKey(s.curve, rand)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.D, nil
+}
+
+func (s *gostScheme) publicKey(d *big.Int) []byte {
+	x, y := s.curve.ScalarBaseMult(d.Bytes())
+	return serializePub(&ecdsa.PublicKey{Curve: s.curve, X: x, Y: y}, byteLen(s.curve))
+}
+
+// digest returns e, the little endian digest reduced mod q and replaced with 1 if it's zero.
+func (s *gostScheme) digest(msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(reverse(hashBytes(s.sigID.Hash(), msg)))
+	e.Mod(e, s.order())
+	if e.Sign() == 0 {
+		e.SetInt64(1)
+	}
+
+	return e
+}
+
+func (s *gostScheme) sign(d, k *big.Int, msg []byte) ([]byte, error) {
+	return s.signDigest(d, k, s.digest(msg))
+}
+
+// signDigest signs the reduced digest e, which is what the standard's examples give rather than a
+// message.
+func (s *gostScheme) signDigest(d, k, e *big.Int) ([]byte, error) {
+	n := s.order()
+
+	// r = (k·G).x and s = r·d + k·e
+	r, _ := s.curve.ScalarBaseMult(k.Bytes())
+	r.Mod(r, n)
+	if r.Sign() == 0 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	sig := new(big.Int).Mul(r, d)
+	sig.Add(sig, new(big.Int).Mul(k, e))
+	sig.Mod(sig, n)
+	if sig.Sign() == 0 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	byteLen := byteLen(s.curve)
+	out := make([]byte, byteLen*2)
+	copy(out, leftPad(sig.Bytes(), byteLen))
+	copy(out[byteLen:], leftPad(r.Bytes(), byteLen))
+	return out, nil
+}
+
+func (s *gostScheme) verify(sig *Signature) bool {
+	n := s.order()
+	byteLen := byteLen(s.curve)
+	if len(sig.Pub) != 2*byteLen || len(sig.Sig) != 2*byteLen {
+		return false
+	}
+	qx := new(big.Int).SetBytes(sig.Pub[:byteLen])
+	qy := new(big.Int).SetBytes(sig.Pub[byteLen:])
+	if !s.curve.IsOnCurve(qx, qy) {
+		return false
+	}
+
+	sv := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	r := new(big.Int).SetBytes(sig.Sig[byteLen:])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || sv.Sign() == 0 || sv.Cmp(n) >= 0 {
+		return false
+	}
+	eInv := new(big.Int).ModInverse(s.digest(sig.Msg), n)
+	if eInv == nil {
+		return false
+	}
+
+	// C = s·e⁻¹·G − r·e⁻¹·Q and its x coordinate must equal r
+	z1 := new(big.Int).Mul(sv, eInv)
+	z2 := new(big.Int).Mul(r, eInv)
+	z2.Neg(z2)
+	x1, y1 := s.curve.ScalarBaseMult(z1.Mod(z1, n).Bytes())
+	x2, y2 := s.curve.ScalarMult(qx, qy, z2.Mod(z2, n).Bytes())
+	if x1.Cmp(x2) == 0 {
+		return false
+	}
+	x, _ := s.curve.Add(x1, y1, x2, y2)
+
+	return x.Mod(x, n).Cmp(r) == 0
+}
+
+func (s *gostScheme) nonceRelation(sig *Signature) (*big.Int, *big.Int, error) {
+	n := s.order()
+	byteLen := byteLen(s.curve)
+	sv := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	r := new(big.Int).SetBytes(sig.Sig[byteLen:])
+	eInv := new(big.Int).ModInverse(s.digest(sig.Msg), n)
+	if eInv == nil {
+		return nil, nil, fmt.Errorf("invalid signature, e has no inverse")
+	}
+
+	// s = r·d + k·e so k = s·e⁻¹ − r·e⁻¹·d
+	alpha := sv.Mul(sv, eInv)
+	beta := r.Mul(r, eInv)
+	beta.Neg(beta)
+	return alpha.Mod(alpha, n), beta.Mod(beta, n), nil
+}
+
+// nonceCommitment returns r, which is the second half of the signature.
+func (s *gostScheme) nonceCommitment(sig *Signature) []byte {
+	return sig.Sig[len(sig.Sig)/2:]
+}
@@ -0,0 +1,52 @@
+package recovery
+
+import (
+	"math/big"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+/*
+* Cyclic groups for the generic discrete log algorithms in pkg/dlp, so the same Pohlig–Hellman,
+* baby-step giant-step and rho work on curve points and on the finite field groups that weak curves
+* reduce to. Short Weierstrass curves use dlp.CurveGroup and GF(p^k) in gfpk.go is a dlp.Group
+* itself.
+ */
+
+// element is a point's coordinates or the coefficients of an element of GF(p^k).
+type element = dlp.Element
+
+// edwardsGroup is the group of points of a twisted Edwards curve, whose identity is (0, 1).
+type edwardsGroup struct {
+	curve *edwardsCurve
+}
+
+func (g edwardsGroup) Identity() element {
+	return element{new(big.Int), big.NewInt(1)}
+}
+
+func (g edwardsGroup) Op(x, y element) element {
+	rx, ry := g.curve.Add(x[0], x[1], y[0], y[1])
+	return element{rx, ry}
+}
+
+func (g edwardsGroup) Inverse(x element) element {
+	return g.Negate(x)
+}
+
+func (g edwardsGroup) Negate(x element) element {
+	rx := new(big.Int)
+	if x[0].Sign() != 0 {
+		rx.Sub(g.curve.params.P, x[0])
+	}
+	return element{rx, new(big.Int).Set(x[1])}
+}
+
+func (g edwardsGroup) Exp(x element, k *big.Int) element {
+	rx, ry := g.curve.ScalarMult(x[0], x[1], k.Bytes())
+	return element{rx, ry}
+}
+
+func (g edwardsGroup) Key(x element) string {
+	return dlp.EncodeElement(x, byteLen(g.curve))
+}
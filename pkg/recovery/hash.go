@@ -0,0 +1,72 @@
+package recovery
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+* Message hashes for the signature registry. Whatever the hash, the digest is converted to an
+* integer the same way for every signature type that truncates it: the leftmost bits up to the bit
+* length of the group order are kept, as SEC 1 and FIPS 186 both specify. That's what lets any hash
+* be used with any curve.
+ */
+
+func newSHA1() hash.Hash       { return sha1.New() }
+func newSHA224() hash.Hash     { return sha256.New224() }
+func newSHA256() hash.Hash     { return sha256.New() }
+func newSHA384() hash.Hash     { return sha512.New384() }
+func newSHA512() hash.Hash     { return sha512.New() }
+func newSHA512_256() hash.Hash { return sha512.New512_256() }
+func newSHA3_224() hash.Hash   { return sha3.New224() }
+func newSHA3_256() hash.Hash   { return sha3.New256() }
+func newSHA3_384() hash.Hash   { return sha3.New384() }
+func newSHA3_512() hash.Hash   { return sha3.New512() }
+func newKeccak256() hash.Hash  { return sha3.NewLegacyKeccak256() }
+
+func newBLAKE2b512() hash.Hash {
+	// Only fails for keys longer than 64 bytes.
+	h, _ := blake2b.New512(nil)
+	return h
+}
+
+func newBLAKE2s256() hash.Hash {
+	h, _ := blake2s.New256(nil)
+	return h
+}
+
+// doubleSHA256 is SHA-256 applied twice, as Bitcoin hashes everything including signed messages.
+type doubleSHA256 struct {
+	hash.Hash
+}
+
+func newDoubleSHA256() hash.Hash {
+	return doubleSHA256{sha256.New()}
+}
+
+func (d doubleSHA256) Sum(in []byte) []byte {
+	h := sha256.Sum256(d.Hash.Sum(nil))
+	return append(in, h[:]...)
+}
+
+// hashToIntBits converts a digest to an integer keeping only its leftmost bits, so that it's no
+// longer than the order.
+func hashToIntBits(hash []byte, orderBits int) *big.Int {
+	orderBytes := (orderBits + 7) / 8
+	if len(hash) > orderBytes {
+		hash = hash[:orderBytes]
+	}
+
+	ret := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
+}
@@ -1,9 +1,27 @@
 package recovery_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+	"github.com/jakecraige/keyrecovery/pkg/hdwallet"
+	"github.com/jakecraige/keyrecovery/pkg/prng"
 	"github.com/jakecraige/keyrecovery/pkg/recovery"
 )
 
@@ -15,6 +33,7 @@ func TestGenerateAndRecover(t *testing.T) {
 	}{
 		{recovery.Curve_S256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
 		{recovery.Curve_S256, recovery.Sig_ECDSA_KECCAK256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_S256, recovery.Sig_SCHNORR_BIP340, recovery.Recovery_NonceReuse},
 
 		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
 		{recovery.Curve_P256, recovery.Sig_ECDSA_KECCAK256, recovery.Recovery_NonceReuse},
@@ -23,6 +42,50 @@ func TestGenerateAndRecover(t *testing.T) {
 		{recovery.Curve_P384, recovery.Sig_ECDSA_KECCAK256, recovery.Recovery_NonceReuse},
 
 		{recovery.Curve_P521, recovery.Sig_ECDSA_SHA512, recovery.Recovery_NonceReuse},
+
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA512, recovery.Recovery_NonceReuse},
+		{recovery.Curve_P521, recovery.Sig_ECDSA_SHA1, recovery.Recovery_NonceReuse},
+		{recovery.Curve_S256, recovery.Sig_ECDSA_DOUBLESHA256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_P224, recovery.Sig_ECDSA_SHA3_384, recovery.Recovery_Fault},
+		{recovery.Curve_P256, recovery.Sig_ECDSA_BLAKE2S256, recovery.Recovery_NonceBiasPrefix},
+
+		{recovery.Curve_P192, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_P224, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_BrainpoolP256r1, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_BrainpoolP384r1, recovery.Sig_ECDSA_SHA512, recovery.Recovery_NonceReuse},
+		{recovery.Curve_BrainpoolP512r1, recovery.Sig_ECDSA_SHA512, recovery.Recovery_NonceReuse},
+
+		{recovery.Curve_STARK, recovery.Sig_ECDSA_STARK, recovery.Recovery_NonceReuse},
+
+		{recovery.Curve_Ed25519, recovery.Sig_Ed25519, recovery.Recovery_NonceReuse},
+		{recovery.Curve_Ed25519, recovery.Sig_Ed25519, recovery.Recovery_MismatchedPub},
+
+		{recovery.Curve_S256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_Fault},
+		{recovery.Curve_P521, recovery.Sig_ECDSA_SHA512, recovery.Recovery_Fault},
+		{recovery.Curve_Ed25519, recovery.Sig_Ed25519, recovery.Recovery_Fault},
+
+		{recovery.Curve_S256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceBiasPrefix},
+
+		{recovery.Curve_STARK, recovery.Sig_ECDSA_STARK, recovery.Recovery_NonceBiasPrefix},
+
+		{recovery.Curve_SM2P256, recovery.Sig_SM2_SM3, recovery.Recovery_NonceReuse},
+		{recovery.Curve_SM2P256, recovery.Sig_SM2_SM3, recovery.Recovery_NonceBiasPrefix},
+
+		{recovery.Curve_TC26_256_B, recovery.Sig_GOST_STREEBOG256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_TC26_512_A, recovery.Sig_GOST_STREEBOG512, recovery.Recovery_NonceReuse},
+		{recovery.Curve_TC26_256_A, recovery.Sig_GOST_STREEBOG256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_TC26_512_B, recovery.Sig_GOST_STREEBOG512, recovery.Recovery_NonceReuse},
+		{recovery.Curve_TC26_512_C, recovery.Sig_GOST_STREEBOG512, recovery.Recovery_NonceReuse},
+		{recovery.Curve_TC26_256_B, recovery.Sig_GOST_STREEBOG256, recovery.Recovery_NonceBiasPrefix},
+
+		{recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256, recovery.Recovery_NonceBiasPrefix},
+		{recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA1, recovery.Recovery_NonceReuse},
+
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_SmallNonce},
+		{recovery.Curve_SM2P256, recovery.Sig_SM2_SM3, recovery.Recovery_SmallNonce},
+		{recovery.Curve_TC26_256_B, recovery.Sig_GOST_STREEBOG256, recovery.Recovery_SmallNonce},
+		{recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256, recovery.Recovery_SmallNonce},
 	}
 
 	for _, tt := range tests {
@@ -39,6 +102,21 @@ func TestGenerateAndRecover(t *testing.T) {
 				return
 			}
 
+			for i, sig := range sigs {
+				if tt.mode == recovery.Recovery_MismatchedPub && i == 0 {
+					// Signed under the wrong public key so it's invalid by design.
+					continue
+				}
+				if tt.mode == recovery.Recovery_Fault && i%2 == 1 {
+					// Every other signature is the faulty one of a pair.
+					continue
+				}
+				if ok, err := conf.Verify(sig); err != nil || !ok {
+					t.Errorf("generated sig %d failed to verify: %v", i, err)
+					return
+				}
+			}
+
 			_, err = conf.Recover(sigs)
 			if err != nil {
 				t.Errorf("recovering key: %v", err)
@@ -47,3 +125,1651 @@ func TestGenerateAndRecover(t *testing.T) {
 		})
 	}
 }
+
+func TestRecoverWithoutPublicKeys(t *testing.T) {
+	var tests = []struct {
+		curveID recovery.CurveIdentifier
+		sigID   recovery.SignatureIdentifier
+		mode    recovery.RecoveryMode
+	}{
+		{recovery.Curve_S256, recovery.Sig_ECDSA_KECCAK256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
+		{recovery.Curve_P521, recovery.Sig_ECDSA_SHA512, recovery.Recovery_NonceReuse},
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_Fault},
+		{recovery.Curve_S256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceBiasPrefix},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s | %s | %s", tt.curveID, tt.sigID, tt.mode), func(t *testing.T) {
+			conf, err := recovery.New(tt.curveID, tt.sigID, tt.mode)
+			if err != nil {
+				t.Fatalf("initializing config: %v", err)
+			}
+
+			sigs, err := conf.Generate()
+			if err != nil {
+				t.Fatalf("generating sigs: %v", err)
+			}
+			pub := sigs[0].Pub
+
+			// Serialize without the public keys and parse them back.
+			var input bytes.Buffer
+			for _, sig := range sigs {
+				fmt.Fprintf(&input, "%x%x\n", sig.Sig, sig.Msg)
+			}
+
+			priv, err := conf.RecoverFromReader(&input, recovery.Format_SigMsg)
+			if err != nil {
+				t.Fatalf("recovering key: %v", err)
+			}
+			if !bytes.Equal(priv.Pub, pub) {
+				t.Errorf("recovered key for %x, want %x", priv.Pub, pub)
+			}
+		})
+	}
+}
+
+func TestRecoverSignersLeavesStrangersUnattributed(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_S256, recovery.Sig_ECDSA_KECCAK256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+	others, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+	pub := sigs[0].Pub
+
+	// Two signatures by one key and one by another, which can't be attributed to either.
+	stripped := []*recovery.Signature{
+		{Sig: sigs[0].Sig, Msg: sigs[0].Msg},
+		{Sig: sigs[1].Sig, Msg: sigs[1].Msg},
+		{Sig: others[0].Sig, Msg: others[0].Msg},
+	}
+	if err := conf.RecoverSigners(stripped); err != nil {
+		t.Fatalf("recovering signers: %v", err)
+	}
+	if !bytes.Equal(stripped[0].Pub, pub) || !bytes.Equal(stripped[1].Pub, pub) {
+		t.Errorf("signers weren't recovered")
+	}
+	if stripped[2].Pub != nil {
+		t.Errorf("signature by another key was attributed to %x", stripped[2].Pub)
+	}
+}
+
+// largeCofactorCurveJSON is y^2 = x^3 + 5 over p = 11 mod 12, which has p + 1 points, with G of a
+// 40-bit prime order and a 91-bit cofactor.
+const largeCofactorCurveJSON = `{
+	"name": "large-cofactor-131",
+	"p": "0x6b11aa5169321b213c447bc81ba2dca63",
+	"a": "0x0",
+	"b": "0x5",
+	"gx": "0x3342020aa835d7f9a99b879260fdfea22",
+	"gy": "0x15da01bd6811aa431bc429f0ade8a6369",
+	"n": "0xf252e6b44d"
+}`
+
+func TestRecoverPublicKeysLargeCofactor(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(largeCofactorCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+	conf, err := recovery.New(curveID, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+
+	// Every r + j·n below p would be a candidate x, about 2^91 of them.
+	if _, err := conf.RecoverPublicKeys(sigs[0]); err == nil {
+		t.Errorf("expected an error recovering public keys with a 91-bit cofactor")
+	}
+	stripped := []*recovery.Signature{{Sig: sigs[0].Sig, Msg: sigs[0].Msg}, {Sig: sigs[1].Sig, Msg: sigs[1].Msg}}
+	if err := conf.RecoverSigners(stripped); err == nil {
+		t.Errorf("expected an error recovering signers with a 91-bit cofactor")
+	}
+}
+
+func TestFaultLocations(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_Fault)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+
+	// Each pair has the fault in a different location and should be enough on its own.
+	for i := 0; i+1 < len(sigs); i += 2 {
+		if _, err := conf.Recover(sigs[i : i+2]); err != nil {
+			t.Errorf("recovering key from pair %d: %v", i/2, err)
+		}
+		if _, err := conf.Recover([]*recovery.Signature{sigs[i+1], sigs[i]}); err != nil {
+			t.Errorf("recovering key from reversed pair %d: %v", i/2, err)
+		}
+	}
+}
+
+func TestRecoveredEd25519KeySigns(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_Ed25519, recovery.Sig_Ed25519, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+
+	// The generated signatures must be valid Ed25519 signatures to begin with.
+	for _, sig := range sigs {
+		if !ed25519.Verify(sig.Pub, sig.Msg, sig.Sig) {
+			t.Fatalf("generated signature failed to verify")
+		}
+	}
+
+	priv, err := conf.Recover(sigs)
+	if err != nil {
+		t.Fatalf("recovering key: %v", err)
+	}
+
+	sig, err := priv.Sign([]byte("signed with the recovered key"))
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	if !ed25519.Verify(sigs[0].Pub, sig.Msg, sig.Sig) {
+		t.Errorf("signature from recovered key failed to verify")
+	}
+}
+
+func TestVerifyBIP340(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_S256, recovery.Sig_SCHNORR_BIP340, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	// Test vector 0 from BIP340.
+	pub, _ := hex.DecodeString("F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9")
+	msg := make([]byte, 32)
+	sig, _ := hex.DecodeString("E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0")
+
+	ok, err := conf.Verify(&recovery.Signature{Pub: pub, Sig: sig, Msg: msg})
+	if err != nil || !ok {
+		t.Errorf("expected test vector to verify: %v", err)
+	}
+
+	sig[63] ^= 1
+	if ok, _ := conf.Verify(&recovery.Signature{Pub: pub, Sig: sig, Msg: msg}); ok {
+		t.Errorf("expected modified signature to fail verification")
+	}
+}
+
+func TestVerifyMalformedPub(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+
+	// A DSA key without its domain parameters can't be checked at all, which isn't the same as a
+	// signature that doesn't verify.
+	sig := *sigs[0]
+	sig.Pub = sig.Pub[:16]
+	if ok, err := conf.Verify(&sig); err == nil || ok {
+		t.Errorf("Verify() = %v, %v, want an error", ok, err)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	var tests = []struct {
+		curveID recovery.CurveIdentifier
+		sigID   recovery.SignatureIdentifier
+	}{
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256},
+		{recovery.Curve_Ed25519, recovery.Sig_Ed25519},
+		{recovery.Curve_S256, recovery.Sig_SCHNORR_BIP340},
+		{recovery.Curve_SM2P256, recovery.Sig_SM2_SM3},
+		{recovery.Curve_TC26_256_B, recovery.Sig_GOST_STREEBOG256},
+		{recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256},
+	}
+
+	for _, tt := range tests {
+		conf, err := recovery.New(tt.curveID, tt.sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("generating sigs: %v", err)
+		}
+
+		// Keys and signatures of the wrong length are invalid, not out of range. DSA keys are
+		// rejected with an error since they carry the domain parameters.
+		for i, malformed := range []recovery.Signature{
+			{Pub: nil, Sig: sigs[0].Sig, Msg: sigs[0].Msg},
+			{Pub: sigs[0].Pub[:len(sigs[0].Pub)-1], Sig: sigs[0].Sig, Msg: sigs[0].Msg},
+			{Pub: sigs[0].Pub, Sig: sigs[0].Sig[:len(sigs[0].Sig)-1], Msg: sigs[0].Msg},
+			{Pub: sigs[0].Pub, Sig: nil, Msg: sigs[0].Msg},
+		} {
+			malformed := malformed
+			if ok, _ := conf.Verify(&malformed); ok {
+				t.Errorf("%s %s: malformed signature %d verified", tt.curveID, tt.sigID, i)
+			}
+		}
+	}
+}
+
+func TestVerifySTARK(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_STARK, recovery.Sig_ECDSA_STARK, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	// Signature from the StarkEx crypto library's tests, with the message hash as the message.
+	pub, _ := hex.DecodeString("077a3b314db07c45076d11f62b6f9e748a39790441823307743cf00d6597ea43" +
+		"054d7beec5ec728223671c627557efc5c9a6508425dc6c900b7741bf60afec06")
+	msg, _ := hex.DecodeString("0397e76d1667c4454bfb83514e120583af836f8e32a516765497823eabe16a3f")
+	sig, _ := hex.DecodeString("0173fd03d8b008ee7432977ac27d1e9d1a1f6c98b1a2f05fa84a21c84c44e882" +
+		"04b6d75385aed025aa222f28a0adc6d58db78ff17e51c3f59e259b131cd5a1cc")
+
+	ok, err := conf.Verify(&recovery.Signature{Pub: pub, Sig: sig, Msg: msg})
+	if err != nil || !ok {
+		t.Errorf("expected test vector to verify: %v", err)
+	}
+
+	msg[31] ^= 1
+	if ok, _ := conf.Verify(&recovery.Signature{Pub: pub, Sig: sig, Msg: msg}); ok {
+		t.Errorf("expected signature over a different message to fail verification")
+	}
+}
+
+// The 256-bit test curve of GB/T 32918.2 Annex A.
+const sm2TestCurveJSON = `{
+	"name": "sm2-test-256",
+	"p": "0x8542D69E4C044F18E8B92435BF6FF7DE457283915C45517D722EDB8B08F1DFC3",
+	"a": "0x787968B4FA32C3FD2417842E73BBFEFF2F3C848B6831D7E0EC65228B3937E498",
+	"b": "0x63E4C6D3B23B0C849CF84241484BFE48F61D59A5B16BA06E6E12D1DA27C5249A",
+	"gx": "0x421DEBD61B62EAB6746434EBC3CC315E32220B3BADD50BDC4C4E6C147FEDD43D",
+	"gy": "0x0680512BCBB42C07D47349D2153B70C4E5D7FDFCBFA36EA1A85841B9E46E09A2",
+	"n": "0x8542D69E4C044F18E8B92435BF6FF7DD297720630485628D5AE74EE7C32E79B7"
+}`
+
+func TestVerifySM2(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(sm2TestCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+	conf, err := recovery.New(curveID, recovery.Sig_SM2_SM3, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	if err := conf.SetSM2ID("ALICE123@YAHOO.COM"); err != nil {
+		t.Fatalf("setting ID: %v", err)
+	}
+
+	// The signature example of GB/T 32918.2 Annex A.1.
+	pub, _ := hex.DecodeString("0AE4C7798AA0F119471BEE11825BE46202BB79E2A5844495E97C04FF4DF2548A" +
+		"7C0240F88F1CD4E16352A73C17B7F16F07353E53A176D684A9FE0C6BB798E857")
+	d, _ := new(big.Int).SetString("128B2FA8BD433C6C068C8D803DFF79792A519A55171B1B650C23661D15897263", 16)
+	sig, _ := hex.DecodeString("40F1EC59F793D9F49E09DCEF49130D4194F79FB1EED2CAA55BACDB49C4E755D1" +
+		"6FC6DAC32C5D5CF10C77DFB20F7C2EB667A457872FB09EC56327A67EC7DEEBE7")
+	msg := []byte("message digest")
+
+	if _, err := conf.SolveInterval(pub, d, d, nil); err != nil {
+		t.Errorf("test vector key doesn't match its public key: %v", err)
+	}
+	ok, err := conf.Verify(&recovery.Signature{Pub: pub, Sig: sig, Msg: msg})
+	if err != nil || !ok {
+		t.Errorf("expected test vector to verify: %v", err)
+	}
+
+	// Z_A depends on the ID, so the signature doesn't verify for a signer with the default one.
+	defaultConf, err := recovery.New(curveID, recovery.Sig_SM2_SM3, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	if ok, _ := defaultConf.Verify(&recovery.Signature{Pub: pub, Sig: sig, Msg: msg}); ok {
+		t.Errorf("expected signature to fail verification with the default ID")
+	}
+
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+	if _, err := conf.Recover(sigs); err != nil {
+		t.Errorf("recovering key with the ID: %v", err)
+	}
+	if _, err := defaultConf.Recover(sigs); err == nil {
+		t.Errorf("expected recovery with the default ID to fail")
+	}
+
+	if err := conf.SetSM2ID(strings.Repeat("x", 8192)); err == nil {
+		t.Errorf("expected an error setting an SM2 ID too long for its bit length to fit 16 bits")
+	}
+	ecdsaConf, err := recovery.New(recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	if err := ecdsaConf.SetSM2ID("ALICE123@YAHOO.COM"); err == nil {
+		t.Errorf("expected an error setting an SM2 ID on ECDSA")
+	}
+}
+
+func TestCurveLookup(t *testing.T) {
+	var tests = []struct {
+		name string
+		want recovery.CurveIdentifier
+	}{
+		{"secp256k1", recovery.Curve_S256},
+		{"prime256v1", recovery.Curve_P256},
+		{"P-384", recovery.Curve_P384},
+		{"secp224r1", recovery.Curve_P224},
+		{"BRAINPOOLP256R1", recovery.Curve_BrainpoolP256r1},
+		{"1.3.36.3.3.2.8.1.1.13", recovery.Curve_BrainpoolP512r1},
+		{"1.2.840.10045.3.1.1", recovery.Curve_P192},
+		{"edwards25519", recovery.Curve_Ed25519},
+	}
+
+	for _, tt := range tests {
+		got, err := recovery.NewCurveIdentifier(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("NewCurveIdentifier(%q) = %s, %v, want %s", tt.name, got, err, tt.want)
+			continue
+		}
+
+		if oid := got.OID(); oid != nil {
+			if fromOID, err := recovery.CurveIdentifierFromOID(oid); err != nil || fromOID != got {
+				t.Errorf("CurveIdentifierFromOID(%s) = %s, %v, want %s", oid, fromOID, err, got)
+			}
+		}
+	}
+
+	if _, err := recovery.NewCurveIdentifier("P-999"); err == nil {
+		t.Error("expected an error for an unknown curve")
+	}
+	// Curve25519 is the Montgomery curve, which Ed25519 signatures aren't over.
+	if _, err := recovery.NewCurveIdentifier("Curve25519"); err == nil {
+		t.Error("expected an error for Curve25519")
+	}
+
+	// Every registered curve's base point must be on the curve and have the curve's order.
+	for _, id := range recovery.Curves() {
+		curve, err := id.Curve()
+		if id.IsDSA() {
+			if err == nil {
+				t.Errorf("%s: DSA group has an elliptic curve", id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", id, err)
+			continue
+		}
+		params := curve.Params()
+		if !curve.IsOnCurve(params.Gx, params.Gy) {
+			t.Errorf("%s: base point is not on the curve", id)
+		}
+		x1, y1 := curve.ScalarBaseMult(new(big.Int).Sub(params.N, big.NewInt(1)).Bytes())
+		x2, y2 := curve.ScalarBaseMult([]byte{2})
+		if x, y := curve.Add(x1, y1, x2, y2); x.Cmp(params.Gx) != 0 || y.Cmp(params.Gy) != 0 {
+			t.Errorf("%s: (n-1)·G + 2·G is not G", id)
+		}
+	}
+}
+
+// brainpoolP256r1 with a != -3, as a user would write it in a curve file.
+const customCurveJSON = `{
+	"name": "custom-bp256",
+	"p": "0xA9FB57DBA1EEA9BC3E660A909D838D726E3BF623D52620282013481D1F6E5377",
+	"a": "0x7D5A0975FC2C3057EEF67530417AFFE7FB8055C126DC5C6CE94A4B44F330B5D9",
+	"b": "0x26DC5C6CE94A4B44F330B5D9BBD77CBF958416295CF7E1CE6BCCDC18FF8C07B6",
+	"gx": "0x8BD2AEB9CB7E57CB2C4B482FFC81B7AFB9DE27E1E3BD23C23A4453BD9ACE3262",
+	"gy": "0x547EF835C3DAC4FD97F8461A14611DC9C27745132DED8E545C1D54C72F046997",
+	"n": "0xA9FB57DBA1EEA9BC3E660A909D838D718C397AA3B561A6F7901E0E82974856A7"
+}`
+
+func TestCustomCurve(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(customCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+
+	var tests = []struct {
+		sigID recovery.SignatureIdentifier
+		mode  recovery.RecoveryMode
+	}{
+		{recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse},
+		{recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceBiasPrefix},
+		{recovery.Sig_ECDSA_SHA256, recovery.Recovery_Fault},
+		{recovery.Sig_SM2_SM3, recovery.Recovery_NonceReuse},
+		{recovery.Sig_GOST_STREEBOG256, recovery.Recovery_NonceReuse},
+	}
+
+	for _, tt := range tests {
+		conf, err := recovery.New(curveID, tt.sigID, tt.mode)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("%s %s: generating sigs: %v", tt.sigID, tt.mode, err)
+		}
+		if ok, err := conf.Verify(sigs[0]); err != nil || !ok {
+			t.Errorf("%s %s: generated sig failed to verify: %v", tt.sigID, tt.mode, err)
+		}
+		if _, err := conf.Recover(sigs); err != nil {
+			t.Errorf("%s %s: recovering key: %v", tt.sigID, tt.mode, err)
+		}
+	}
+
+	if again, err := recovery.ParseCurveParams([]byte(customCurveJSON)); err != nil || again != curveID {
+		t.Errorf("registering the same curve again = %s, %v", again, err)
+	}
+
+	invalid := map[string]string{
+		"name taken":   strings.Replace(customCurveJSON, "custom-bp256", "P-256", 1),
+		"G off curve":  strings.Replace(customCurveJSON, `"0x547E`, `"0x547F`, 1),
+		"wrong order":  strings.Replace(customCurveJSON, "974856A7", "974856A9", 1),
+		"missing b":    strings.Replace(customCurveJSON, `"b"`, `"c"`, 1),
+		"composite p":  strings.Replace(customCurveJSON, "1D1F6E5377", "1D1F6E5379", 1),
+		"not a number": strings.Replace(customCurveJSON, "0x7D5A", "0xZZ5A", 1),
+	}
+	for name, params := range invalid {
+		if _, err := recovery.ParseCurveParams([]byte(params)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// With b = 0, (0, 0) is a point of order 2 and not the point at infinity.
+	zeroB, err := recovery.ParseCurveParams([]byte(supersingularACurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve with b = 0: %v", err)
+	}
+	curve, err := zeroB.Curve()
+	if err != nil {
+		t.Fatalf("getting curve: %v", err)
+	}
+	gx, gy := curve.Params().Gx, curve.Params().Gy
+	zero := new(big.Int)
+	if !curve.IsOnCurve(zero, zero) {
+		t.Errorf("expected (0, 0) on the curve")
+	}
+	if x, y := curve.Add(gx, gy, zero, zero); x.Cmp(gx) == 0 && y.Cmp(gy) == 0 {
+		t.Errorf("G + (0, 0) = G, want another point")
+	}
+	ix, iy := curve.Double(zero, zero)
+	if x, y := curve.Add(gx, gy, ix, iy); x.Cmp(gx) != 0 || y.Cmp(gy) != 0 {
+		t.Errorf("G + 2·(0, 0) = (%x, %x), want G", x, y)
+	}
+}
+
+// weakCurveJSON is a 128-bit curve whose base point's order has no prime factor over 30 bits.
+const weakCurveJSON = `{
+	"name": "weak-128",
+	"p": "0xea79dd7b07846d7461c06de69bf29187",
+	"a": "0x0",
+	"b": "0xe97c9b44e5c9dbc4fbd2757156efa5c9",
+	"gx": "0x2eb498ba2f27dea6a6a965b9943050c1",
+	"gy": "0x5cc2da5e2a140e305ea8c6a10fd1c5d6",
+	"n": "0x138a27ca40a05e745cb6492dcc071569"
+}`
+
+func TestWeakCurve(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(weakCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+
+	for _, sigID := range []recovery.SignatureIdentifier{recovery.Sig_ECDSA_SHA256, recovery.Sig_SM2_SM3} {
+		conf, err := recovery.New(curveID, sigID, recovery.Recovery_WeakCurve)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+
+		factors, err := conf.OrderFactorization()
+		if err != nil {
+			t.Fatalf("factoring order: %v", err)
+		}
+		want := "13 · 157 · 1879 · 7351 · 209263 · 1105231 · 5279959 · 754439527"
+		if got := recovery.FormatFactorization(factors); got != want {
+			t.Errorf("order factorization = %s, want %s", got, want)
+		}
+
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("%s: generating sigs: %v", sigID, err)
+		}
+		if ok, err := conf.Verify(sigs[0]); err != nil || !ok {
+			t.Errorf("%s: generated sig failed to verify: %v", sigID, err)
+		}
+		if _, err := conf.Recover(sigs); err != nil {
+			t.Errorf("%s: recovering key: %v", sigID, err)
+		}
+	}
+
+	// A prime order leaves nothing to split up.
+	conf, err := recovery.New(recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_WeakCurve)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+	if _, err := conf.Recover(sigs); err == nil {
+		t.Errorf("expected recovery on P-256 to fail")
+	}
+}
+
+// anomalousCurveJSON is a 127-bit curve whose order is its field prime.
+const anomalousCurveJSON = `{
+	"name": "anomalous-127",
+	"p": "0x4052652809e8ff5f583d611dc03a2d8f",
+	"a": "0x0",
+	"b": "0x3",
+	"gx": "0x2d7982a95ec42e0829a3b2e95d65a441",
+	"gy": "0xe4ccfa156b792e6e7c98c4d9b156f61",
+	"n": "0x4052652809e8ff5f583d611dc03a2d8f"
+}`
+
+func TestAnomalousCurve(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(anomalousCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+	if !curveID.IsAnomalous() {
+		t.Errorf("%s is anomalous", curveID)
+	}
+	if recovery.Curve_P256.IsAnomalous() {
+		t.Errorf("%s isn't anomalous", recovery.Curve_P256)
+	}
+
+	for _, sigID := range []recovery.SignatureIdentifier{recovery.Sig_ECDSA_SHA256, recovery.Sig_SM2_SM3} {
+		conf, err := recovery.New(curveID, sigID, recovery.Recovery_WeakCurve)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("%s: generating sigs: %v", sigID, err)
+		}
+		if _, err := conf.Recover(sigs); err != nil {
+			t.Errorf("%s: recovering key: %v", sigID, err)
+		}
+	}
+}
+
+func TestSingularCurve(t *testing.T) {
+	var tests = []struct {
+		params   string
+		singular string
+	}{
+		{`{"name": "cusp-128", "p": "0xd734fe9e659a191d07f4d6921de0c26b", "a": "0", "b": "0",
+			"gx": "0x67968b27074c2687a953f2f154abb0c0", "gy": "0x26093e932b795a99e74856d7408f39ba",
+			"n": "0xd734fe9e659a191d07f4d6921de0c26b"}`, "cusp"},
+		{`{"name": "split-node-138", "p": "0x2678843ea013c2960506d66d98c5f3c3607",
+			"a": "0x1d573c1e0923471d61ebc245be9c6393c70", "b": "0x1eff196a8e9cacc17d4c16832867f7bfda9",
+			"gx": "0x5000bc22cb1be4a5db2b54af7771436e1d", "gy": "0xc62a3fbab118d71c13c9268e5bb1df94fe",
+			"n": "0x133c421f5009e14b02836b36cc62f9e1b03"}`, "split node"},
+		{`{"name": "non-split-node-138", "p": "0x21457977da701b97a52af4e896f7732c819",
+			"a": "0xc8ac64769e4ca9210f3430579fa93bd6ff", "b": "0xdf65289205d4ab73bf84f1313d50dd9d49",
+			"gx": "0x17ab772b882f48975459e3e4d77159ed066", "gy": "0xc84e28301060517fadd150581b3cf404f9",
+			"n": "0x21457977da701b97a52af4e896f7732c81a"}`, "non-split node"},
+	}
+
+	for _, tt := range tests {
+		curveID, err := recovery.ParseCurveParams([]byte(tt.params))
+		if err != nil {
+			t.Fatalf("%s: parsing curve: %v", tt.singular, err)
+		}
+		if !curveID.IsSingular() || curveID.IsAnomalous() {
+			t.Errorf("%s: IsSingular = %t, IsAnomalous = %t", curveID, curveID.IsSingular(), curveID.IsAnomalous())
+		}
+
+		conf, err := recovery.New(curveID, recovery.Sig_ECDSA_SHA256, recovery.Recovery_WeakCurve)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		analysis, err := conf.AnalyzeCurve()
+		if err != nil || analysis.Singular != tt.singular {
+			t.Errorf("%s: analysis = %v, %v", curveID, analysis, err)
+		}
+
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("%s: generating sigs: %v", curveID, err)
+		}
+		if ok, err := conf.Verify(sigs[0]); err != nil || !ok {
+			t.Errorf("%s: generated sig failed to verify: %v", curveID, err)
+		}
+		if _, err := conf.Recover(sigs); err != nil {
+			t.Errorf("%s: recovering key: %v", curveID, err)
+		}
+	}
+
+	if recovery.Curve_P256.IsSingular() {
+		t.Errorf("%s isn't singular", recovery.Curve_P256)
+	}
+}
+
+// supersingularCurveJSON is y^2 = x^3 + b over p = 2 mod 3, which has p + 1 points and embedding
+// degree 2.
+const supersingularCurveJSON = `{
+	"name": "supersingular-140",
+	"p": "0xbc9446e791a031c0381a22b3dd005703d51",
+	"a": "0x0",
+	"b": "0x427a1ecca3ac886e5c33c0ec57b5fcafd2d",
+	"gx": "0x44f535dd5b2ab707ecd4b317f3115350c03",
+	"gy": "0x146e354b9bc9de238252e379ee6f6aeece6",
+	"n": "0x1f6e0bd142f0084ab4045b1dfa2ab92b4e3"
+}`
+
+// supersingularACurveJSON is y^2 = x^3 + a·x over p = 3 mod 4, which also has p + 1 points and
+// embedding degree 2. (0, 0) is a point of order 2 on it.
+const supersingularACurveJSON = `{
+	"name": "supersingular-a-139",
+	"p": "0x65ba5dde80d2f99277b8887c71031c7c163",
+	"a": "0x1",
+	"b": "0x0",
+	"gx": "0x3f896220bb95bc939a41165d7463f75026a",
+	"gy": "0x261d88a14d440a12381aa5c62cd70508b06",
+	"n": "0x196e9777a034be649dee221f1c40c71f059"
+}`
+
+func TestMOVAttack(t *testing.T) {
+	for _, params := range []string{supersingularCurveJSON, supersingularACurveJSON} {
+		curveID, err := recovery.ParseCurveParams([]byte(params))
+		if err != nil {
+			t.Fatalf("parsing curve: %v", err)
+		}
+
+		conf, err := recovery.New(curveID, recovery.Sig_ECDSA_SHA256, recovery.Recovery_WeakCurve)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		analysis, err := conf.AnalyzeCurve()
+		if err != nil || analysis.EmbeddingDegree != 2 {
+			t.Fatalf("%s analysis = %v, %v, want embedding degree 2", curveID, analysis, err)
+		}
+
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("%s: generating sigs: %v", curveID, err)
+		}
+		if _, err := conf.Recover(sigs); err != nil {
+			t.Errorf("%s: recovering key: %v", curveID, err)
+		}
+	}
+
+	conf, err := recovery.New(recovery.Curve_S256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_WeakCurve)
+
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	if analysis, err := conf.AnalyzeCurve(); err != nil || analysis.EmbeddingDegree != 0 {
+		t.Errorf("%s analysis = %v, %v, want no small embedding degree", recovery.Curve_S256, analysis, err)
+	}
+}
+
+// ecdhCurveJSON is a 64-bit curve of prime order whose twist has no prime factor over 16 bits.
+const ecdhCurveJSON = `{
+	"name": "ecdh-64",
+	"p": "0xfb531f841c7ee69b",
+	"a": "0x0",
+	"b": "0x2",
+	"gx": "0x17ab4dc1bef122f0",
+	"gy": "0x2fb132223d801f64",
+	"n": "0xfb531f82b2c163b3"
+}`
+
+func TestECDHAttack(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(ecdhCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+
+	var tests = []struct {
+		name  string
+		tag   recovery.ECDHTag
+		xOnly bool
+	}{
+		{"invalid curves", recovery.ECDHTag_SHA256, false},
+		{"twist", recovery.ECDHTag_HMACSHA256, true},
+	}
+
+	for _, tt := range tests {
+		oracle, err := recovery.NewLocalECDHOracle(curveID, nil, tt.tag, tt.xOnly)
+		if err != nil {
+			t.Fatalf("%s: creating oracle: %v", tt.name, err)
+		}
+
+		attack := &recovery.ECDHAttack{CurveID: curveID, Pub: oracle.PublicKey(), Tag: tt.tag, XOnly: tt.xOnly}
+		result, err := attack.Recover(oracle)
+		if err != nil {
+			t.Errorf("%s: recovering key: %v", tt.name, err)
+			continue
+		}
+		if result.Key == nil || !bytes.Equal(result.Key.Pub, oracle.PublicKey()) {
+			t.Errorf("%s: recovered key %v, want the oracle's", tt.name, result.Key)
+		}
+		if result.Queries != oracle.Queries() {
+			t.Errorf("%s: counted %d queries, oracle answered %d", tt.name, result.Queries, oracle.Queries())
+		}
+	}
+
+	// A full point attack on P-256 only gets as far as the small factors of the singular curve's
+	// order, but each of those residues must still be right.
+	d, _ := new(big.Int).SetString("0x8f1d2e5b6c7a09f3e4d5c6b7a8190f2e3d4c5b6a79880f1e2d3c4b5a69788796", 0)
+	oracle, err := recovery.NewLocalECDHOracle(recovery.Curve_P256, d, recovery.ECDHTag_SHA256, false)
+	if err != nil {
+		t.Fatalf("creating oracle: %v", err)
+	}
+	attack := &recovery.ECDHAttack{CurveID: recovery.Curve_P256, Pub: oracle.PublicKey(), Tag: recovery.ECDHTag_SHA256, MaxPrimeBits: 16}
+	result, err := attack.Recover(oracle)
+	if err != nil {
+		t.Fatalf("recovering residues: %v", err)
+	}
+	if result.Key != nil || len(result.Residues) == 0 {
+		t.Fatalf("result = %+v, want only residues", result)
+	}
+	for _, r := range result.Residues {
+		want := new(big.Int).Mod(d, r.Modulus)
+		neg := new(big.Int).Sub(r.Modulus, r.Residue)
+		if want.Cmp(r.Residue) != 0 && want.Cmp(neg.Mod(neg, r.Modulus)) != 0 {
+			t.Errorf("%s: d = %v mod %v, residue is ±%v", r.Source, want, r.Modulus, r.Residue)
+		}
+	}
+}
+
+// ecdhOracleKey is the key of the oracles TestECDHOracles talks to.
+var ecdhOracleKey, _ = new(big.Int).SetString("0x3b9aca07c0ffee11", 0)
+
+// TestECDHOracleProcess is the oracle process TestECDHOracles starts by running the test binary
+// again, which serves stdin and exits when the environment asks it to.
+func TestECDHOracleProcess(t *testing.T) {
+	if os.Getenv("KEYRECOVERY_ECDH_ORACLE") == "" {
+		t.Skip("only runs as an oracle process")
+	}
+
+	curveID, err := recovery.ParseCurveParams([]byte(ecdhCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+	oracle, err := recovery.NewLocalECDHOracle(curveID, ecdhOracleKey, recovery.ECDHTag_SHA256, false)
+	if err != nil {
+		t.Fatalf("creating oracle: %v", err)
+	}
+	if err := oracle.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestECDHOracles(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(ecdhCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+	local, err := recovery.NewLocalECDHOracle(curveID, ecdhOracleKey, recovery.ECDHTag_SHA256, false)
+	if err != nil {
+		t.Fatalf("creating oracle: %v", err)
+	}
+
+	server := httptest.NewServer(local)
+	defer server.Close()
+
+	process, err := recovery.NewProcessECDHOracle(fmt.Sprintf("KEYRECOVERY_ECDH_ORACLE=1 exec '%s' -test.run='^TestECDHOracleProcess$'", os.Args[0]))
+	if err != nil {
+		t.Fatalf("starting oracle process: %v", err)
+	}
+	defer process.Close()
+
+	// y^2 = x^3 + 3 has 0xfb531f8605625f45 points, as the CM curves for a = 0 would find.
+	dir, err := ioutil.TempDir("", "ecdh")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	invalidCurvesPath := filepath.Join(dir, "invalid-curves.json")
+	if err := ioutil.WriteFile(invalidCurvesPath, []byte(`[{"b": "0x3", "order": "0xfb531f8605625f45"}]`), 0600); err != nil {
+		t.Fatalf("writing invalid curves: %v", err)
+	}
+	invalidCurves, err := recovery.LoadInvalidCurves(invalidCurvesPath)
+	if err != nil {
+		t.Fatalf("loading invalid curves: %v", err)
+	}
+
+	var tests = []struct {
+		name   string
+		oracle recovery.ECDHOracle
+	}{
+		{"http", &recovery.HTTPECDHOracle{URL: server.URL}},
+		{"process", process},
+	}
+
+	for _, tt := range tests {
+		attack := &recovery.ECDHAttack{CurveID: curveID, Pub: local.PublicKey(), Tag: recovery.ECDHTag_SHA256, InvalidCurves: invalidCurves}
+		result, err := attack.Recover(tt.oracle)
+		if err != nil {
+			t.Errorf("%s: recovering key: %v", tt.name, err)
+			continue
+		}
+		if result.Key == nil || result.Key.D.Cmp(ecdhOracleKey) != 0 {
+			t.Errorf("%s: recovered key %v, want %v", tt.name, result.Key, ecdhOracleKey)
+		}
+		if len(result.Residues) == 0 || result.Residues[0].Source != "invalid curve b=0x3" {
+			t.Errorf("%s: residues %+v, want the first from the given invalid curve", tt.name, result.Residues)
+		}
+	}
+
+	// The oracle's own curve isn't an invalid one.
+	attack := &recovery.ECDHAttack{CurveID: curveID, Pub: local.PublicKey(), Tag: recovery.ECDHTag_SHA256,
+		InvalidCurves: []recovery.InvalidCurve{{B: big.NewInt(2), Order: big.NewInt(1)}}}
+	if _, err := attack.Recover(local); err == nil {
+		t.Errorf("attack with the oracle's curve as an invalid one succeeded")
+	}
+}
+
+func TestSolveInterval(t *testing.T) {
+	// Public keys for a small d on each kind of group, serialized the way each scheme does.
+	d, _ := new(big.Int).SetString("0x9c3e5d07", 0)
+	lo, hi := big.NewInt(0x9c000000), big.NewInt(0x9fffffff)
+
+	ecdsaPub := func(curve elliptic.Curve, d *big.Int) []byte {
+		x, y := curve.ScalarBaseMult(d.Bytes())
+		return append(leftPad(x.Bytes(), 32), leftPad(y.Bytes(), 32)...)
+	}
+
+	// BIP340 only keeps x, so use a d whose point has an odd y to need the other square root.
+	s256 := curveOf(t, recovery.Curve_S256)
+	schnorrD := new(big.Int).Set(d)
+	for _, y := s256.ScalarBaseMult(schnorrD.Bytes()); y.Bit(0) == 0; _, y = s256.ScalarBaseMult(schnorrD.Bytes()) {
+		schnorrD.Add(schnorrD, big.NewInt(1))
+	}
+	schnorrX, _ := s256.ScalarBaseMult(schnorrD.Bytes())
+
+	// Ed25519 is y in little endian with the sign of x in the top bit.
+	edX, edY := curveOf(t, recovery.Curve_Ed25519).ScalarBaseMult(d.Bytes())
+	edPub := leftPad(edY.Bytes(), 32)
+	for i, j := 0, len(edPub)-1; i < j; i, j = i+1, j-1 {
+		edPub[i], edPub[j] = edPub[j], edPub[i]
+	}
+	edPub[31] |= byte(edX.Bit(0)) << 7
+
+	// DSA keys carry their group, so take one from a generated key.
+	conf, err := recovery.New(recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+	dsaPub := append([]byte{}, sigs[0].Pub[:128+20+128]...)
+	p := new(big.Int).SetBytes(dsaPub[:128])
+	g := new(big.Int).SetBytes(dsaPub[128+20:])
+	dsaPub = append(dsaPub, leftPad(new(big.Int).Exp(g, d, p).Bytes(), 128)...)
+
+	var tests = []struct {
+		curveID recovery.CurveIdentifier
+		sigID   recovery.SignatureIdentifier
+		pub     []byte
+		d       *big.Int
+	}{
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, ecdsaPub(elliptic.P256(), d), d},
+		{recovery.Curve_S256, recovery.Sig_SCHNORR_BIP340, leftPad(schnorrX.Bytes(), 32), schnorrD},
+		{recovery.Curve_Ed25519, recovery.Sig_Ed25519, edPub, d},
+		{recovery.Curve_DSA_1024_160, recovery.Sig_DSA_SHA256, dsaPub, d},
+	}
+
+	for _, tt := range tests {
+		conf, err := recovery.New(tt.curveID, tt.sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+
+		priv, err := conf.SolveInterval(tt.pub, lo, hi, nil)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.curveID, tt.sigID, err)
+			continue
+		}
+		if priv.D.Cmp(tt.d) != 0 || !bytes.Equal(priv.Pub, tt.pub) {
+			t.Errorf("%s %s: recovered %x, want %x", tt.curveID, tt.sigID, priv.D, tt.d)
+		}
+	}
+}
+
+// dlpCurveJSON is a 32-bit curve of prime order, small enough for rho over the whole group.
+const dlpCurveJSON = `{
+	"name": "dlp-32",
+	"p": "0xcded0de7",
+	"a": "0xcded0de4",
+	"b": "0x7ea6d07e",
+	"gx": "0x54a65392",
+	"gy": "0xa5daf4c5",
+	"n": "0xcded8647"
+}`
+
+func TestSolveDiscreteLog(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(dlpCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+	conf, err := recovery.New(curveID, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	d := big.NewInt(0x7c3e5d07)
+	x, y := curveOf(t, curveID).ScalarBaseMult(d.Bytes())
+	pub := append(leftPad(x.Bytes(), 4), leftPad(y.Bytes(), 4)...)
+
+	// The default table fits every baby step, and a small one makes it use rho.
+	for _, opts := range []*dlp.Options{nil, {MaxPoints: 1 << 12}} {
+		priv, err := conf.SolveDiscreteLog(pub, opts)
+		if err != nil {
+			t.Errorf("%+v: %v", opts, err)
+			continue
+		}
+		if priv.D.Cmp(d) != 0 {
+			t.Errorf("%+v: recovered %x, want %x", opts, priv.D, d)
+		}
+	}
+
+	// P-256 is far too large.
+	conf, err = recovery.New(recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	x, y = elliptic.P256().ScalarBaseMult(d.Bytes())
+	pub = append(leftPad(x.Bytes(), 32), leftPad(y.Bytes(), 32)...)
+	if _, err := conf.SolveDiscreteLog(pub, nil); err == nil {
+		t.Errorf("expected an error for P-256")
+	}
+}
+
+// The key of the brainwallet SHA256("correct horse battery staple") as each kind of target.
+var brainwalletTargets = []string{
+	"78d430274f8c5ec1321338151e9f27f4c676a008bdf8638d07c0b6be9ab35c71a1518063243acd4dfe96b66e3f2ec8013c8e072cd09b3834a19f81f659cc3455",
+	"1JwSSubhmg6iPtRjtyqhUYYH7bZg3Lfy1T",
+	"0xdccd62d450c645f6437680b8a4daa098396dce0e",
+	"0378d430274f8c5ec1321338151e9f27f4c676a008bdf8638d07c0b6be9ab35c71",
+	"0478d430274f8c5ec1321338151e9f27f4c676a008bdf8638d07c0b6be9ab35c71a1518063243acd4dfe96b66e3f2ec8013c8e072cd09b3834a19f81f659cc3455",
+}
+
+const brainwalletWords = "password\nhello world\nCORRECT HORSE BATTERY STAPLE\nletmein\n"
+
+func newBrainwalletSearch(t *testing.T, target string, rules ...string) *recovery.BrainwalletSearch {
+	t.Helper()
+
+	derivation, err := recovery.NewBrainwalletDerivation("sha256")
+	if err != nil {
+		t.Fatalf("parsing derivation: %v", err)
+	}
+	targets := recovery.NewKeyTargets()
+	if err := targets.Add(target); err != nil {
+		t.Fatalf("adding target: %v", err)
+	}
+
+	search := &recovery.BrainwalletSearch{
+		CurveID:     recovery.Curve_S256,
+		SigID:       recovery.Sig_ECDSA_SHA256,
+		Derivations: []*recovery.BrainwalletDerivation{derivation},
+		Targets:     targets,
+	}
+	for _, text := range rules {
+		rule, err := recovery.ParseBrainwalletRule(text)
+		if err != nil {
+			t.Fatalf("parsing rule: %v", err)
+		}
+		search.Rules = append(search.Rules, rule)
+	}
+	return search
+}
+
+func TestBrainwallet(t *testing.T) {
+	want, _ := new(big.Int).SetString("c4bbcb1fbec99d65bf59d85c8cb62ee2db963f0fe106f483d9afa73bd4e39a8a", 16)
+
+	for _, target := range brainwalletTargets {
+		// The word only matches once lowercased.
+		search := newBrainwalletSearch(t, target, ":", "l")
+		result, err := search.Run(context.Background(), strings.NewReader(brainwalletWords), nil)
+		if err != nil {
+			t.Errorf("%s: %v", target, err)
+			continue
+		}
+		if result.Words != 4 || result.Candidates != 5 {
+			t.Errorf("%s: searched %d words and %d candidates, want 4 and 5", target, result.Words, result.Candidates)
+		}
+		if len(result.Matches) != 1 {
+			t.Errorf("%s: found %d matches, want 1", target, len(result.Matches))
+			continue
+		}
+		if m := result.Matches[0]; m.Passphrase != "correct horse battery staple" || m.Key.D.Cmp(want) != 0 {
+			t.Errorf("%s: found %q with key %x", target, m.Passphrase, m.Key.D)
+		}
+	}
+
+	// Odd length hex that isn't SEC 1 could never match.
+	if err := recovery.NewKeyTargets().Add("05" + brainwalletTargets[0]); err == nil {
+		t.Errorf("expected an error for a malformed public key")
+	}
+
+	// Without the rule there's nothing to find.
+	search := newBrainwalletSearch(t, brainwalletTargets[0])
+	result, err := search.Run(context.Background(), strings.NewReader(brainwalletWords), nil)
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	if len(result.Matches) != 0 {
+		t.Errorf("found %q without rules", result.Matches[0].Passphrase)
+	}
+}
+
+func TestBrainwalletCheckpoint(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	search := newBrainwalletSearch(t, brainwalletTargets[1], "l")
+	search.Checkpoint = checkpoint
+	if _, err := search.Run(context.Background(), strings.NewReader("password\nhello world\n"), nil); err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+
+	// Resuming skips the two words already searched, which aren't in the wordlist any longer.
+	result, err := search.Run(context.Background(), strings.NewReader("password\nCORRECT HORSE BATTERY STAPLE\nletmein\n"), nil)
+	if err != nil {
+		t.Fatalf("resuming: %v", err)
+	}
+	if result.Words != 3 || result.Candidates != 1 || len(result.Matches) != 0 {
+		t.Errorf("resumed at the wrong word: %d words, %d candidates, %d matches", result.Words, result.Candidates, len(result.Matches))
+	}
+
+	// A different search can't resume from it.
+	other := newBrainwalletSearch(t, brainwalletTargets[1], "u")
+	other.Checkpoint = checkpoint
+	if _, err := other.Run(context.Background(), strings.NewReader(brainwalletWords), nil); err == nil {
+		t.Errorf("expected an error resuming a different search")
+	}
+
+	// A checkpoint that can't be written stops the search, without leaving workers blocked.
+	words := strings.Repeat("password\n", 20000)
+	broken := newBrainwalletSearch(t, brainwalletTargets[1])
+	broken.Checkpoint = filepath.Join(filepath.Dir(checkpoint), "missing", "checkpoint.json")
+	broken.CheckpointInterval = time.Millisecond
+	broken.Workers = 2
+	goroutines := runtime.NumGoroutine()
+	result, err = broken.Run(context.Background(), strings.NewReader(words), nil)
+	if err == nil {
+		t.Errorf("expected an error writing the checkpoint")
+	} else if result == nil || result.Words == 20000 {
+		t.Errorf("search went on after the checkpoint failed")
+	}
+	for i := 0; runtime.NumGoroutine() > goroutines; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left running", runtime.NumGoroutine()-goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPRNGSearch(t *testing.T) {
+	// The first 32 bytes of each model from outside the package, so the keys aren't made by the
+	// code under test.
+	var tests = []struct {
+		model  string
+		seed   uint64
+		secret string
+	}{
+		// std::mt19937 default seed, outputs 3499211612, 581869302, ...
+		{"mt19937", 5489, "5cbb91d0f69eae22eefae1e7791fc3d52c358220dfb707f80500d3e9e1af9538"},
+		// srand(1), rand() gives 1804289383, 846930886, ...
+		{"glibc-rand", 1, "67c6697351ff4aec29cdbaabf2fbe3467cc254f81be8e78d765a2e63339fc99a"},
+		// new Random(42).nextBytes(new byte[32]) in Java.
+		{"java-random", 42, "359d41baf78afe0de1bbe7ae28c0450ce43c084f4bbb2bf1839dee466d852cb5"},
+		// rand.New(rand.NewSource(1)).Read() with Go 1.15's math/rand.
+		{"go-math-rand", 1, "52fdfc072182654f163f5f0f9a621d729566c74d10037c4d7bbb0407d1e2c649"},
+		// random.seed(42); random.getrandbits(256) in Python.
+		{"python-random", 42, "23b8c1e9392456de3eb13b9046685257bdd640fb06671ad11c80317fa3b1799d"},
+		// The entropy of the published Milk Sad mnemonic, made at time zero.
+		{"bx-seed", 0, "8c97b7d89adb8bd86c9fa562704ce40ef645627acacf877a9164ecd6125616a5"},
+		// No Debian key is available offline, so these are from a separate Python transliteration of
+		// md_rand.c.
+		{"debian-openssl-i386-256", 1, "d35fa800cc2f5ec64cfb29125cfb66b3bc895019ea0c366220f6ef8142a3602a"},
+		{"debian-openssl-amd64-256", 1234, "1d918cba12aa8738fbf0e9255c530f2a6f8536cdc95675c5e528dfdef8e1aab6"},
+	}
+
+	tested := make(map[string]bool)
+	for _, tt := range tests {
+		tested[tt.model] = true
+		secret, _ := hex.DecodeString(tt.secret)
+		d := new(big.Int).SetBytes(secret)
+		d.Mod(d, elliptic.P256().Params().N)
+		x, y := elliptic.P256().ScalarBaseMult(d.Bytes())
+
+		targets := recovery.NewKeyTargets()
+		if err := targets.Add(hex.EncodeToString(append(leftPad(x.Bytes(), 32), leftPad(y.Bytes(), 32)...))); err != nil {
+			t.Fatalf("adding target: %v", err)
+		}
+		search := &recovery.PRNGSearch{
+			CurveID: recovery.Curve_P256,
+			SigID:   recovery.Sig_ECDSA_SHA256,
+			Model:   prng.Models[tt.model],
+			Targets: targets,
+			To:      tt.seed + 500,
+		}
+		if tt.seed > 500 {
+			search.From = tt.seed - 500
+		}
+
+		result, err := search.Run(context.Background(), nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.model, err)
+			continue
+		}
+
+		// Seeds can make the same key, as glibc's srand(0) is srand(1).
+		found := false
+		for _, m := range result.Matches {
+			if m.Key.D.Cmp(d) != 0 {
+				t.Errorf("%s: found seed %d with key %x, want %x", tt.model, m.Seed, m.Key.D, d)
+			}
+			found = found || m.Seed == tt.seed
+		}
+		if !found {
+			t.Errorf("%s: seed %d not among the %d matches", tt.model, tt.seed, len(result.Matches))
+		}
+	}
+
+	for name := range prng.Models {
+		if !tested[name] {
+			t.Errorf("%s has no outside vector", name)
+		}
+	}
+}
+
+func TestPRNGSearchWallet(t *testing.T) {
+	// The wallet of the mnemonic the Milk Sad disclosure starts with, bx seed -b 256 at time zero:
+	// "milk sad wage cup reward umbrella raven visa give list decorate bulb gold raise twenty fly
+	// manual stand float super gentle climb fold park". These are the P2PKH addresses of its keys at
+	// m/44'/0'/0'/0/1 and m/0.
+	targets := recovery.NewKeyTargets()
+	for _, addr := range []string{"1K2sG6FjJNa9jAPi3xuPwjoEuy5DVgjpUw", "1Dc4uXWPKn8a1Pb5ip7vmjRXB3mU26MuW1"} {
+		if err := targets.Add(addr); err != nil {
+			t.Fatalf("adding target: %v", err)
+		}
+	}
+	paths, err := hdwallet.ParsePaths("m/44'/0'/0'/0/i,m/0", 2)
+	if err != nil {
+		t.Fatalf("parsing paths: %v", err)
+	}
+	search := &recovery.PRNGSearch{
+		CurveID: recovery.Curve_S256,
+		SigID:   recovery.Sig_ECDSA_SHA256,
+		Model:   prng.Models["bx-seed"],
+		Targets: targets,
+		From:    0,
+		To:      3,
+		Wallet:  &hdwallet.Derivation{Mnemonic: true, EntropyBits: 256, Paths: paths},
+	}
+
+	result, err := search.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("searching: %v", err)
+	}
+	if len(result.Matches) != 2 {
+		t.Fatalf("found %d matches, want 2", len(result.Matches))
+	}
+	found := map[string]string{}
+	for _, m := range result.Matches {
+		if m.Seed != 0 {
+			t.Errorf("%s found at seed %d, want 0", m.Target, m.Seed)
+		}
+		found[m.Target] = m.Path
+	}
+	if found["1K2sG6FjJNa9jAPi3xuPwjoEuy5DVgjpUw"] != "m/44'/0'/0'/0/1" || found["1Dc4uXWPKn8a1Pb5ip7vmjRXB3mU26MuW1"] != "m/0" {
+		t.Errorf("found at paths %v", found)
+	}
+
+	// Without the mnemonic, or with a passphrase, it's a different wallet.
+	for _, wallet := range []*hdwallet.Derivation{
+		{EntropyBits: 256, Paths: paths},
+		{Mnemonic: true, Passphrase: "TREZOR", EntropyBits: 256, Paths: paths},
+	} {
+		search.Wallet = wallet
+		if result, err := search.Run(context.Background(), nil); err != nil || len(result.Matches) != 0 {
+			t.Errorf("expected no matches for a different wallet: %v", err)
+		}
+	}
+
+	search.CurveID = recovery.Curve_P256
+	if _, err := search.Run(context.Background(), nil); err == nil {
+		t.Errorf("expected an error for a wallet on P256")
+	}
+}
+
+func TestNonceSeedSearch(t *testing.T) {
+	const seed = 1700000000
+	curve := elliptic.P256()
+	n := curve.Params().N
+	d, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	x, y := curve.ScalarBaseMult(d.Bytes())
+	pub := append(leftPad(x.Bytes(), 32), leftPad(y.Bytes(), 32)...)
+
+	conf, err := recovery.New(recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	for _, name := range []string{"go-math-rand", "python-random", "glibc-rand"} {
+		model := prng.Models[name]
+
+		// Sign with the nonce the PRNG seeded with the time gives.
+		nonce := make([]byte, 32)
+		model.Fill(seed, nonce)
+		k := new(big.Int).SetBytes(nonce)
+		k.Mod(k, n)
+		msg := []byte("signed at " + name)
+		digest := sha256.Sum256(msg)
+		r, _ := curve.ScalarBaseMult(k.Bytes())
+		r.Mod(r, n)
+		sig := new(big.Int).Mul(r, d)
+		sig.Add(sig, new(big.Int).SetBytes(digest[:]))
+		sig.Mul(sig, new(big.Int).ModInverse(k, n))
+		sig.Mod(sig, n)
+		raw := append(append(leftPad(r.Bytes(), 32), leftPad(sig.Bytes(), 32)...), msg...)
+
+		// Once with the public key and once recovering it from the signature.
+		for _, format := range []string{recovery.Format_PubSigMsg, recovery.Format_SigMsg} {
+			data := raw
+			if format == recovery.Format_PubSigMsg {
+				data = append(append([]byte{}, pub...), raw...)
+			}
+			parsed, err := conf.ParseSignature(data, format)
+			if err != nil {
+				t.Fatalf("parsing signature: %v", err)
+			}
+
+			search := &recovery.NonceSeedSearch{
+				CurveID: recovery.Curve_P256,
+				SigID:   recovery.Sig_ECDSA_SHA256,
+				Model:   model,
+				From:    seed - 300,
+				To:      seed + 300,
+			}
+			result, err := search.Run(context.Background(), []*recovery.Signature{parsed})
+			if err != nil {
+				t.Errorf("%s, %s: %v", name, format, err)
+				continue
+			}
+			if result.Key == nil || result.Seed != seed || result.Key.D.Cmp(d) != 0 {
+				t.Errorf("%s, %s: found %+v, want seed %d", name, format, result, seed)
+			}
+		}
+	}
+}
+
+func leftPad(b []byte, n int) []byte {
+	return append(make([]byte, n-len(b)), b...)
+}
+
+func curveOf(t *testing.T, id recovery.CurveIdentifier) elliptic.Curve {
+	curve, err := id.Curve()
+	if err != nil {
+		t.Fatalf("getting curve: %v", err)
+	}
+	return curve
+}
+
+func TestHashTruncation(t *testing.T) {
+	// Signatures from recovered keys must verify with the standard library, which truncates digests
+	// longer than the order as SEC 1 specifies.
+	var tests = []struct {
+		curveID recovery.CurveIdentifier
+		sigID   recovery.SignatureIdentifier
+		curve   elliptic.Curve
+	}{
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA512, elliptic.P256()},
+		{recovery.Curve_P224, recovery.Sig_ECDSA_SHA3_512, elliptic.P224()},
+		{recovery.Curve_P384, recovery.Sig_ECDSA_BLAKE2B512, elliptic.P384()},
+		{recovery.Curve_P521, recovery.Sig_ECDSA_SHA224, elliptic.P521()},
+	}
+
+	for _, tt := range tests {
+		conf, err := recovery.New(tt.curveID, tt.sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("generating sigs: %v", err)
+		}
+		priv, err := conf.Recover(sigs)
+		if err != nil {
+			t.Fatalf("recovering key: %v", err)
+		}
+
+		sig, err := priv.Sign([]byte("signed with the recovered key"))
+		if err != nil {
+			t.Fatalf("signing: %v", err)
+		}
+		byteLen := len(sig.Sig) / 2
+		pub := &ecdsa.PublicKey{
+			Curve: tt.curve,
+			X:     new(big.Int).SetBytes(sig.Pub[:byteLen]),
+			Y:     new(big.Int).SetBytes(sig.Pub[byteLen:]),
+		}
+		h := tt.sigID.Hash()
+		h.Write(sig.Msg)
+		r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+		sv := new(big.Int).SetBytes(sig.Sig[byteLen:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, sv) {
+			t.Errorf("%s %s: signature failed to verify with crypto/ecdsa", tt.curveID, tt.sigID)
+		}
+	}
+}
+
+func TestHashes(t *testing.T) {
+	var tests = []struct {
+		sigID  recovery.SignatureIdentifier
+		digest string
+	}{
+		{recovery.Sig_ECDSA_SHA1, "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{recovery.Sig_ECDSA_SHA512_256, "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+		{recovery.Sig_ECDSA_SHA3_256, "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{recovery.Sig_ECDSA_BLAKE2S256, "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982"},
+		{recovery.Sig_ECDSA_DOUBLESHA256, "4f8b42c22dd3729b519ba6f68d2da7cc5b2d606d05daed5ad5128cc03e6c6358"},
+	}
+
+	for _, tt := range tests {
+		h := tt.sigID.Hash()
+		h.Write([]byte("abc"))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.digest {
+			t.Errorf("%s(abc) = %s, want %s", tt.sigID, got, tt.digest)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	var tests = []struct {
+		curveID recovery.CurveIdentifier
+		sigID   recovery.SignatureIdentifier
+		format  string
+	}{
+		{recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Format_PubSigMsg},
+		{recovery.Curve_S256, recovery.Sig_ECDSA_KECCAK256, recovery.Format_SigMsg},
+		{recovery.Curve_BrainpoolP256r1, recovery.Sig_ECDSA_SHA3_256, recovery.Format_PubSigMsg},
+		{recovery.Curve_Ed25519, recovery.Sig_Ed25519, recovery.Format_PubSigMsg},
+		{recovery.Curve_SM2P256, recovery.Sig_SM2_SM3, recovery.Format_PubSigMsg},
+		{recovery.Curve_S256, recovery.Sig_SCHNORR_BIP340, recovery.Format_PubSigMsg},
+	}
+
+	for _, tt := range tests {
+		conf, err := recovery.New(tt.curveID, tt.sigID, recovery.Recovery_NonceReuse)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("generating sigs: %v", err)
+		}
+
+		data := make([][]byte, len(sigs))
+		for i, sig := range sigs {
+			if tt.format == recovery.Format_SigMsg {
+				sig.Pub = nil
+			}
+			data[i] = sig.Bytes()
+		}
+
+		detections, err := recovery.Detect(data, tt.format, nil, nil)
+		if err != nil {
+			t.Errorf("%s %s: detecting: %v", tt.curveID, tt.sigID, err)
+			continue
+		}
+		if got := detections[0]; got.CurveID != tt.curveID || got.SigID != tt.sigID {
+			t.Errorf("detected %s, want %s %s", got, tt.curveID, tt.sigID)
+		}
+	}
+
+	if _, err := recovery.Detect([][]byte{bytes.Repeat([]byte{0x42}, 200)}, recovery.Format_PubSigMsg, nil, nil); err == nil {
+		t.Error("expected garbage not to be detected as anything")
+	}
+}
+
+func TestDetectMixedSigners(t *testing.T) {
+	// Two signatures from one key and one from another: the odd one out can't be attributed to a
+	// signer, so it's counted as not verifying rather than checked against no key.
+	var data [][]byte
+	for _, n := range []int{2, 1} {
+		conf, err := recovery.New(recovery.Curve_P256, recovery.Sig_ECDSA_SHA256, recovery.Recovery_NonceReuse)
+		if err != nil {
+			t.Fatalf("initializing config: %v", err)
+		}
+		sigs, err := conf.Generate()
+		if err != nil {
+			t.Fatalf("generating sigs: %v", err)
+		}
+		for _, sig := range sigs[:n] {
+			sig.Pub = nil
+			data = append(data, sig.Bytes())
+		}
+	}
+
+	detections, err := recovery.Detect(data, recovery.Format_SigMsg, nil, nil)
+	if err != nil {
+		t.Fatalf("detecting: %v", err)
+	}
+	if got := detections[0]; got.CurveID != recovery.Curve_P256 || got.SigID != recovery.Sig_ECDSA_SHA256 ||
+		got.Verified != 2 || got.Checked != 3 {
+		t.Errorf("detected %s, want P256 ECDSA-SHA256 with 2 of 3 verified", got)
+	}
+}
+
+func TestSM3(t *testing.T) {
+	// Examples from GB/T 32905-2016.
+	var tests = []struct {
+		msg, digest string
+	}{
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	}
+
+	for _, tt := range tests {
+		h := recovery.Sig_SM2_SM3.Hash()
+		h.Write([]byte(tt.msg))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.digest {
+			t.Errorf("SM3(%q) = %s, want %s", tt.msg, got, tt.digest)
+		}
+	}
+}
+
+func TestStreebog(t *testing.T) {
+	// Example 1 from GOST R 34.11-2012, with the digests in the byte order they're output in.
+	msg := []byte("012345678901234567890123456789012345678901234567890123456789012")
+	var tests = []struct {
+		sigID  recovery.SignatureIdentifier
+		digest string
+	}{
+		{recovery.Sig_GOST_STREEBOG256, "9d151eefd8590b89daa6ba6cb74af9275dd051026bb149a452fd84e5e57b5500"},
+		{recovery.Sig_GOST_STREEBOG512, "1b54d01a4af5b9d5cc3d86d68d285462b19abc2475222f35c085122be4ba1ffa" +
+			"00ad30f8767b3a82384c6574f024c311e2a481332b08ef7f41797891c1646f48"},
+	}
+
+	for _, tt := range tests {
+		h := tt.sigID.Hash()
+		h.Write(msg)
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.digest {
+			t.Errorf("%s digest = %s, want %s", tt.sigID, got, tt.digest)
+		}
+	}
+}
+
+func TestGOSTSignatureEncoding(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_TC26_256_A, recovery.Sig_GOST_STREEBOG256, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+	sigs, err := conf.Generate()
+	if err != nil {
+		t.Fatalf("generating sigs: %v", err)
+	}
+
+	// Signatures are s || r, so with a reused nonce only the second half is shared.
+	sig1, sig2 := sigs[0].Sig, sigs[1].Sig
+	half := len(sig1) / 2
+	if !bytes.Equal(sig1[half:], sig2[half:]) || bytes.Equal(sig1[:half], sig2[:half]) {
+		t.Errorf("expected signatures with a reused nonce to share only r, the second half")
+	}
+	if nc, err := conf.NonceCommitment(sigs[0]); err != nil || !bytes.Equal(nc, sig1[half:]) {
+		t.Errorf("NonceCommitment() = %x, %v, want r %x", nc, err, sig1[half:])
+	}
+}
+
+// The 256-bit test curve of GOST R 34.10-2012 Annex A.1.
+const gostTestCurveJSON = `{
+	"name": "gost-test-256",
+	"p": "0x8000000000000000000000000000000000000000000000000000000000000431",
+	"a": "7",
+	"b": "0x5FBFF498AA938CE739B8E022FBAFEF40563F6E6A3472FC2A514C0CE9DAE23B7E",
+	"gx": "2",
+	"gy": "0x08E2A8A0E65147D4BD6316030E16D19C85C97F0A9CA267122B96ABBCEA7E8FC8",
+	"n": "0x8000000000000000000000000000000150FE8A1892976154C59CFC193ACCF5B3"
+}`
+
+func TestGOSTSignExample(t *testing.T) {
+	curveID, err := recovery.ParseCurveParams([]byte(gostTestCurveJSON))
+	if err != nil {
+		t.Fatalf("parsing curve: %v", err)
+	}
+
+	// The example gives the digest e rather than the message it came from.
+	hexInt := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 16)
+		return n
+	}
+	d := hexInt("7A929ADE789BB9BE10ED359DD39A72C11B60961F49397EEE1D19CE9891EC3B28")
+	e := hexInt("2DFBC1B372D89A1188C09C52E0EEC61FCE52032AB1022E8E67ECE6672B043EE5")
+	k := hexInt("77105C9B20BCD3122823C8CF6FCC7B956DE33814E95B7FE64FED924594DCEAB3")
+	want := "01456C64BA4642A1653C235A98A60249BCD6D3F746B631DF928014F6C5BF9C40" + // s
+		"41AA28D2F1AB148280CD9ED56FEDA41974053554A42767B83AD043FD39DC0493" // r
+
+	sig, err := recovery.GOSTSignDigest(curveID, recovery.Sig_GOST_STREEBOG256, d, k, e)
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	if !strings.EqualFold(hex.EncodeToString(sig), want) {
+		t.Errorf("signature = %x, want %s", sig, want)
+	}
+}
+
+func TestMuSig2AggregateKey(t *testing.T) {
+	// Key aggregation vectors from BIP327.
+	keys := []string{
+		"02F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"03DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"023590A94E768F8E1815C2F24B4D80A8E3149316C3518CE7B7AD338368D038CA66",
+	}
+	var tests = []struct {
+		indexes []int
+		want    string
+	}{
+		{[]int{0, 1, 2}, "90539EEDE565F5D054F32CC0C220126889ED1E5D193BAF15AEF344FE59D4610C"},
+		{[]int{2, 1, 0}, "6204DE8B083426DC6EAF9502D27024D53FC826BF7D2012148A0575435DF54B2B"},
+		{[]int{0, 0, 0}, "B436E3BAD62B8CD409969A224731C193D051162D8C5AE8B109306127DA3AA935"},
+		{[]int{0, 0, 1, 1}, "69BC22BFA5D106306E48A20679DE1D7389386124D07571D0D872686028C26A3E"},
+	}
+
+	// The nonces don't affect the key, but each signer needs one.
+	nonce, _ := hex.DecodeString(keys[0])
+	for _, tt := range tests {
+		session := &recovery.MuSig2Session{}
+		for _, i := range tt.indexes {
+			pub, _ := hex.DecodeString(keys[i])
+			session.PubKeys = append(session.PubKeys, pub)
+			session.PubNonces = append(session.PubNonces, [][]byte{nonce})
+		}
+
+		got, err := recovery.MuSig2AggregateKey(session)
+		if err != nil {
+			t.Errorf("%v: aggregating key: %v", tt.indexes, err)
+			continue
+		}
+		if want, _ := hex.DecodeString(tt.want); !bytes.Equal(got, want) {
+			t.Errorf("%v: aggregate key = %X, want %s", tt.indexes, got, tt.want)
+		}
+	}
+}
+
+func TestMuSig2NonceReuse(t *testing.T) {
+	conf, err := recovery.New(recovery.Curve_S256, recovery.Sig_SCHNORR_BIP340, recovery.Recovery_NonceReuse)
+	if err != nil {
+		t.Fatalf("initializing config: %v", err)
+	}
+
+	for _, numNonces := range []int{1, 2} {
+		t.Run(fmt.Sprintf("%d nonces", numNonces), func(t *testing.T) {
+			sessions, err := recovery.SimulateMuSig2NonceReuse(numNonces)
+			if err != nil {
+				t.Fatalf("simulating sessions: %v", err)
+			}
+			if len(sessions) != numNonces+1 {
+				t.Fatalf("expected %d sessions, got %d", numNonces+1, len(sessions))
+			}
+
+			// The simulated sessions must produce valid aggregate signatures for the model to mean
+			// anything.
+			for i, session := range sessions {
+				aggPub, err := recovery.MuSig2AggregateKey(session)
+				if err != nil {
+					t.Fatalf("aggregating key: %v", err)
+				}
+				ok, err := conf.Verify(&recovery.Signature{Pub: aggPub, Sig: session.Sig, Msg: session.Msg})
+				if err != nil || !ok {
+					t.Fatalf("session %d aggregate signature failed to verify: %v", i, err)
+				}
+			}
+
+			if _, err := recovery.RecoverMuSig2NonceReuse(sessions[:numNonces]); err == nil {
+				t.Errorf("expected recovery to fail with too few sessions")
+			}
+
+			priv, err := recovery.RecoverMuSig2NonceReuse(sessions)
+			if err != nil {
+				t.Fatalf("recovering key: %v", err)
+			}
+			if !bytes.Equal(priv.Pub, sessions[0].PubKeys[0]) {
+				t.Errorf("recovered key for the wrong signer")
+			}
+		})
+	}
+
+	// Malformed sessions are errors, not panics.
+	malformed := []func(*recovery.MuSig2Session){
+		func(s *recovery.MuSig2Session) { s.PubNonces = s.PubNonces[:0] },
+		func(s *recovery.MuSig2Session) { s.Signer = len(s.PubKeys) },
+		func(s *recovery.MuSig2Session) { s.PartialSig = s.PartialSig[:16] },
+	}
+	for i, corrupt := range malformed {
+		sessions, err := recovery.SimulateMuSig2NonceReuse(1)
+		if err != nil {
+			t.Fatalf("simulating sessions: %v", err)
+		}
+		corrupt(sessions[len(sessions)-1])
+		if _, err := recovery.RecoverMuSig2NonceReuse(sessions); err == nil {
+			t.Errorf("malformed session %d: expected an error", i)
+		}
+	}
+}
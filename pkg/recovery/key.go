@@ -0,0 +1,35 @@
+package recovery
+
+import (
+	"math/big"
+)
+
+// PrivateKey is a recovered private key. D is the scalar the signature algorithm multiplies by,
+// which for Ed25519 is the clamped secret scalar rather than the seed it was derived from.
+type PrivateKey struct {
+	D *big.Int
+
+	// Pub is the public key serialized the same way as Signature.Pub.
+	Pub []byte
+
+	scheme scheme
+}
+
+func newPrivateKey(scheme scheme, d *big.Int) *PrivateKey {
+	return &PrivateKey{D: d, Pub: scheme.publicKey(d), scheme: scheme}
+}
+
+// Sign produces a valid signature over msg with a fresh random nonce.
+func (k *PrivateKey) Sign(msg []byte) (*Signature, error) {
+	nonce, err := randScalar(k.scheme.order())
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := k.scheme.sign(k.D, nonce, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signature{Pub: k.Pub, Sig: sig, Msg: msg}, nil
+}
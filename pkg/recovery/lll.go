@@ -0,0 +1,135 @@
+package recovery
+
+import (
+	"math/big"
+)
+
+/*
+* Exact LLL lattice basis reduction with δ = 3/4 following Cohen's "A Course in Computational
+* Algebraic Number Theory" algorithm 2.6.3. The Gram-Schmidt coefficients are kept as rationals and
+* updated incrementally, which is slow compared to floating point variants but has no precision
+* issues with the several hundred bit entries the nonce bias lattices have. They're small enough in
+* dimension that it doesn't matter.
+ */
+
+var (
+	lllDelta = big.NewRat(3, 4)
+	ratHalf  = big.NewRat(1, 2)
+)
+
+// lllReduce reduces the lattice basis in place. The rows of basis must be linearly independent.
+func lllReduce(basis [][]*big.Int) {
+	n := len(basis)
+	if n < 2 {
+		return
+	}
+
+	// mu[i][j] and bStar[i] = |b*_i|^2 from Gram-Schmidt orthogonalization.
+	mu := make([][]*big.Rat, n)
+	bStar := make([]*big.Rat, n)
+	orth := make([][]*big.Rat, n)
+	for i := range basis {
+		mu[i] = make([]*big.Rat, n)
+		for j := range mu[i] {
+			mu[i][j] = new(big.Rat)
+		}
+
+		orth[i] = make([]*big.Rat, len(basis[i]))
+		for c, v := range basis[i] {
+			orth[i][c] = new(big.Rat).SetInt(v)
+		}
+		for j := 0; j < i; j++ {
+			mu[i][j] = ratDot(basis[i], orth[j])
+			mu[i][j].Quo(mu[i][j], bStar[j])
+			for c := range orth[i] {
+				orth[i][c].Sub(orth[i][c], new(big.Rat).Mul(mu[i][j], orth[j][c]))
+			}
+		}
+		bStar[i] = ratNormSq(orth[i])
+	}
+
+	reduce := func(k, l int) {
+		if new(big.Rat).Abs(mu[k][l]).Cmp(ratHalf) <= 0 {
+			return
+		}
+
+		q := ratRound(mu[k][l])
+		qRat := new(big.Rat).SetInt(q)
+		for c := range basis[k] {
+			basis[k][c].Sub(basis[k][c], new(big.Int).Mul(q, basis[l][c]))
+		}
+		mu[k][l].Sub(mu[k][l], qRat)
+		for i := 0; i < l; i++ {
+			mu[k][i].Sub(mu[k][i], new(big.Rat).Mul(qRat, mu[l][i]))
+		}
+	}
+
+	swap := func(k int) {
+		basis[k], basis[k-1] = basis[k-1], basis[k]
+		for j := 0; j < k-1; j++ {
+			mu[k][j], mu[k-1][j] = mu[k-1][j], mu[k][j]
+		}
+
+		m := new(big.Rat).Set(mu[k][k-1])
+		b := new(big.Rat).Mul(m, m)
+		b.Mul(b, bStar[k-1])
+		b.Add(b, bStar[k])
+
+		mu[k][k-1] = new(big.Rat).Mul(m, bStar[k-1])
+		mu[k][k-1].Quo(mu[k][k-1], b)
+		bStar[k] = new(big.Rat).Mul(bStar[k-1], bStar[k])
+		bStar[k].Quo(bStar[k], b)
+		bStar[k-1] = b
+
+		for i := k + 1; i < n; i++ {
+			t := mu[i][k]
+			mu[i][k] = new(big.Rat).Sub(mu[i][k-1], new(big.Rat).Mul(m, t))
+			mu[i][k-1] = new(big.Rat).Add(t, new(big.Rat).Mul(mu[k][k-1], mu[i][k]))
+		}
+	}
+
+	k := 1
+	for k < n {
+		reduce(k, k-1)
+
+		// Lovász condition: |b*_k|^2 >= (δ - μ_k,k-1^2)·|b*_k-1|^2
+		bound := new(big.Rat).Mul(mu[k][k-1], mu[k][k-1])
+		bound.Sub(lllDelta, bound)
+		bound.Mul(bound, bStar[k-1])
+		if bStar[k].Cmp(bound) < 0 {
+			swap(k)
+			if k > 1 {
+				k--
+			}
+			continue
+		}
+
+		for l := k - 2; l >= 0; l-- {
+			reduce(k, l)
+		}
+		k++
+	}
+}
+
+func ratDot(a []*big.Int, b []*big.Rat) *big.Rat {
+	sum := new(big.Rat)
+	for i := range a {
+		sum.Add(sum, new(big.Rat).Mul(new(big.Rat).SetInt(a[i]), b[i]))
+	}
+	return sum
+}
+
+func ratNormSq(v []*big.Rat) *big.Rat {
+	sum := new(big.Rat)
+	for _, x := range v {
+		sum.Add(sum, new(big.Rat).Mul(x, x))
+	}
+	return sum
+}
+
+// ratRound rounds x to the nearest integer.
+func ratRound(x *big.Rat) *big.Int {
+	t := new(big.Rat).Add(x, ratHalf)
+	q := new(big.Int).Div(t.Num(), t.Denom()) // Euclidean division floors for positive denominators
+	return q
+}
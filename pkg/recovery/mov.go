@@ -0,0 +1,194 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+/*
+* The MOV (Menezes–Okamoto–Vanstone) and Frey–Rück reduction for curves with a small embedding
+* degree k, the smallest k with n | p^k - 1. The reduced Tate pairing maps the subgroup of order n
+* into the n-th roots of unity in GF(p^k), and since it's bilinear e(Q, T) = e(G, T)^d. Supersingular
+* curves have k <= 2, and pairing-friendly curves are built to have small k.
+*
+* The DLP in GF(p^k) is then solved with Pohlig–Hellman, so it still needs the order of G to be
+* smooth but the group operation is much cheaper than a point addition. Index calculus, which is
+* what makes the reduction subexponential, isn't implemented.
+ */
+
+const (
+	// movMaxDegree is the largest embedding degree the attack is tried for.
+	movMaxDegree = 6
+
+	// movPairingAttempts is how many random points T are tried to get a pairing e(G, T) of order n.
+	movPairingAttempts = 8
+)
+
+// embeddingDegree returns the smallest k <= movMaxDegree with n | p^k - 1, or zero if there isn't
+// one.
+func embeddingDegree(p, n *big.Int) int {
+	pk := big.NewInt(1)
+	for k := 1; k <= movMaxDegree; k++ {
+		pk.Mul(pk, p)
+		pk.Mod(pk, n)
+		if pk.Cmp(one) == 0 {
+			return k
+		}
+	}
+	return 0
+}
+
+// movAttack returns d such that d·G = Q on a curve y^2 = x^3 + a·x + b with embedding degree k.
+func movAttack(params *elliptic.CurveParams, a, qx, qy *big.Int, k int, factors []OrderFactor) (*big.Int, error) {
+	field, err := newGFpk(params.P, k)
+	if err != nil {
+		return nil, err
+	}
+	n := params.N
+
+	for i := 0; i < movPairingAttempts; i++ {
+		// A random point T on the curve over GF(p^k), with x outside GF(p) so T is never a zero or
+		// pole of the lines in the Miller loop.
+		tx, err := field.random()
+		if err != nil {
+			return nil, err
+		}
+		if field.isBase(tx) {
+			continue
+		}
+		rhs := field.Op(field.Op(tx, tx), tx)
+		rhs = field.add(rhs, field.scale(tx, a))
+		rhs = field.add(rhs, field.fromInt(params.B))
+		ty, ok := field.sqrt(rhs)
+		if !ok {
+			continue
+		}
+
+		zeta := tatePairing(field, a, n, params.Gx, params.Gy, tx, ty)
+		if !hasOrder(field, zeta, n, factors) {
+			continue
+		}
+		h := tatePairing(field, a, n, qx, qy, tx, ty)
+
+		d, err := pohligHellman(field, zeta, h, n, factors)
+		if err != nil {
+			return nil, fmt.Errorf("%w, order factorization: %s", err, FormatFactorization(factors))
+		}
+		return d, nil
+	}
+
+	return nil, fmt.Errorf("the pairing was degenerate for %d points", movPairingAttempts)
+}
+
+// hasOrder reports whether x has order n, as far as the factorization of n can tell.
+func hasOrder(grp dlp.Group, x element, n *big.Int, factors []OrderFactor) bool {
+	for _, f := range factors {
+		if f.Composite {
+			continue
+		}
+		if dlp.Equal(grp.Exp(x, new(big.Int).Div(n, f.Prime)), grp.Identity()) {
+			return false
+		}
+	}
+	return true
+}
+
+// tatePairing returns the reduced Tate pairing f_{n,P}(T)^((q-1)/n) for a point P of order n on the
+// curve over GF(p) and T on it over GF(q), q = p^k. Miller's loop builds f_{n,P} from the lines
+// through the multiples of P, with P's arithmetic in GF(p) and the lines evaluated at T. The
+// numerator and denominator are kept apart so there's only one inversion.
+func tatePairing(field *gfpk, a, n, px, py *big.Int, tx, ty element) element {
+	num, den := field.Identity(), field.Identity()
+
+	// V = j·P for the bits of n read so far, nil at infinity.
+	vx, vy := px, py
+	for i := n.BitLen() - 2; i >= 0; i-- {
+		num = field.Op(num, num)
+		den = field.Op(den, den)
+		if vx != nil {
+			var l, v element
+			l, v, vx, vy = millerDouble(field, a, vx, vy, tx, ty)
+			num = field.Op(num, l)
+			den = field.Op(den, v)
+		}
+
+		if n.Bit(i) == 1 {
+			if vx == nil {
+				vx, vy = px, py
+				continue
+			}
+			var l, v element
+			l, v, vx, vy = millerAdd(field, vx, vy, px, py, tx, ty)
+			num = field.Op(num, l)
+			den = field.Op(den, v)
+		}
+	}
+
+	f := field.Op(num, field.Inverse(den))
+	exp := new(big.Int).Sub(field.size(), one)
+	exp.Div(exp, n)
+	return field.Exp(f, exp)
+}
+
+// millerDouble returns the tangent line at V and the vertical line through 2·V evaluated at T,
+// along with 2·V.
+func millerDouble(field *gfpk, a, vx, vy *big.Int, tx, ty element) (l, v element, x, y *big.Int) {
+	p := field.p
+	if vy.Sign() == 0 {
+		// 2·V is at infinity and the tangent is vertical.
+		return field.sub(tx, field.fromInt(vx)), field.Identity(), nil, nil
+	}
+
+	// λ = (3·x^2 + a) / 2·y
+	lambda := new(big.Int).Mul(vx, vx)
+	lambda.Mul(lambda, big.NewInt(3))
+	lambda.Add(lambda, a)
+	lambda.Mul(lambda, new(big.Int).ModInverse(new(big.Int).Lsh(vy, 1), p))
+	lambda.Mod(lambda, p)
+
+	x = new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, new(big.Int).Lsh(vx, 1))
+	x.Mod(x, p)
+	return millerLines(field, lambda, vx, vy, x, tx, ty)
+}
+
+// millerAdd returns the line through V and P and the vertical line through V + P evaluated at T,
+// along with V + P.
+func millerAdd(field *gfpk, vx, vy, px, py *big.Int, tx, ty element) (l, v element, x, y *big.Int) {
+	p := field.p
+	if vx.Cmp(px) == 0 {
+		// V = -P, since V = P never comes up, so V + P is at infinity.
+		return field.sub(tx, field.fromInt(vx)), field.Identity(), nil, nil
+	}
+
+	// λ = (y_P - y_V) / (x_P - x_V)
+	lambda := new(big.Int).Sub(px, vx)
+	lambda.ModInverse(lambda.Mod(lambda, p), p)
+	lambda.Mul(lambda, new(big.Int).Sub(py, vy))
+	lambda.Mod(lambda, p)
+
+	x = new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, vx)
+	x.Sub(x, px)
+	x.Mod(x, p)
+	return millerLines(field, lambda, vx, vy, x, tx, ty)
+}
+
+// millerLines evaluates the line with slope λ through V, y - y_V - λ·(x - x_V), and the vertical
+// line through the new point, x - x_3, at T and returns them with the new point.
+func millerLines(field *gfpk, lambda, vx, vy, x3 *big.Int, tx, ty element) (l, v element, x, y *big.Int) {
+	p := field.p
+	l = field.sub(ty, field.fromInt(vy))
+	l = field.sub(l, field.scale(field.sub(tx, field.fromInt(vx)), lambda))
+	v = field.sub(tx, field.fromInt(x3))
+
+	// y_3 = λ·(x_V - x_3) - y_V
+	y = new(big.Int).Sub(vx, x3)
+	y.Mul(y, lambda)
+	y.Sub(y, vy)
+	y.Mod(y, p)
+	return l, v, x3, y
+}
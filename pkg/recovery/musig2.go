@@ -0,0 +1,464 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+)
+
+/*
+* A local model of MuSig2 (BIP327) signing sessions over secp256k1 used to demonstrate that reusing
+* a signer's nonces across sessions leaks its key.
+*
+* Each signer commits to ν nonce points R_i,j = k_j·G. The session aggregates them into
+* R_j = Σ_i R_i,j and binds them with b = H_non(R_1 || ... || R_ν || Q.x || m) to get the final nonce
+* R = Σ_j b^(j-1)·R_j. With the aggregate key Q = Σ_i a_i·X_i and the BIP340 challenge e, signer i's
+* partial signature is
+*
+*   s_i = ε·Σ_j b^(j-1)·k_j + e·a_i·g·d_i
+*
+* where ε and g are ±1 depending on whether R and Q had to be negated to get an even y coordinate.
+* Because b changes with the co-signers and message, a signer reusing its nonces across sessions
+* produces equations which are linear in the ν+1 unknowns k_1..k_ν and d_i. With MuSig2's ν = 2
+* this means three sessions are needed to solve for the key, while with a single nonce (as in the
+* original MuSig) two are enough.
+ */
+
+// MuSig2Session is the transcript of a signing session as seen by a co-signer, along with the
+// partial signature from the signer being attacked.
+type MuSig2Session struct {
+	// PubKeys are the compressed public keys of all signers in key aggregation order.
+	PubKeys [][]byte
+
+	// PubNonces are the compressed public nonce points from each signer, in the same order as
+	// PubKeys.
+	PubNonces [][][]byte
+
+	Msg []byte
+
+	// Signer is the index into PubKeys of the signer PartialSig is from.
+	Signer     int
+	PartialSig []byte
+
+	// Sig is the final aggregate BIP340 signature, if the session completed.
+	Sig []byte
+}
+
+// musig2Context holds the values every signer derives from a session.
+type musig2Context struct {
+	curve  elliptic.Curve
+	coeffs []*big.Int
+	qx     *big.Int
+	g      int64
+	b, e   *big.Int
+	rx     *big.Int
+	eps    int64
+}
+
+func newMuSig2Context(session *MuSig2Session) (*musig2Context, error) {
+	curve := secp256k1.S256()
+	n := curve.Params().N
+	ctx := &musig2Context{curve: curve}
+
+	if len(session.PubKeys) == 0 || len(session.PubKeys) != len(session.PubNonces) {
+		return nil, fmt.Errorf("session must have a nonce for each public key")
+	}
+	numNonces := len(session.PubNonces[0])
+
+	// Key aggregation: a_i = H_agg(L || X_i) with L = H_list(X_1 || ... || X_n) and
+	// Q = Σ a_i·X_i, except that the second distinct key in the list gets a_i = 1.
+	list := taggedHash("KeyAgg list", session.PubKeys...)
+	second := musig2SecondKey(session.PubKeys)
+	var qx, qy *big.Int
+	for _, pub := range session.PubKeys {
+		x, y, err := decompressPoint(curve, pub)
+		if err != nil {
+			return nil, err
+		}
+
+		coeff := big.NewInt(1)
+		if !bytes.Equal(pub, second) {
+			coeff.SetBytes(taggedHash("KeyAgg coefficient", list, pub))
+			coeff.Mod(coeff, n)
+		}
+		ctx.coeffs = append(ctx.coeffs, coeff)
+
+		x, y = curve.ScalarMult(x, y, coeff.Bytes())
+		qx, qy = addPoints(curve, qx, qy, x, y)
+	}
+	if qx == nil {
+		return nil, fmt.Errorf("aggregate key is the point at infinity")
+	}
+	ctx.qx = qx
+	ctx.g = 1
+	if qy.Bit(0) == 1 {
+		ctx.g = -1
+	}
+	qxBytes := leftPad(qx.Bytes(), 32)
+
+	// Nonce aggregation: R_j = Σ_i R_i,j
+	aggNonces := make([][]byte, numNonces)
+	aggX := make([]*big.Int, numNonces)
+	aggY := make([]*big.Int, numNonces)
+	for _, nonces := range session.PubNonces {
+		if len(nonces) != numNonces {
+			return nil, fmt.Errorf("signers provided different numbers of nonces")
+		}
+		for j, nonce := range nonces {
+			x, y, err := decompressPoint(curve, nonce)
+			if err != nil {
+				return nil, err
+			}
+			aggX[j], aggY[j] = addPoints(curve, aggX[j], aggY[j], x, y)
+		}
+	}
+	for j := range aggNonces {
+		if aggX[j] == nil {
+			return nil, fmt.Errorf("aggregate nonce is the point at infinity")
+		}
+		aggNonces[j] = compressPoint(aggX[j], aggY[j])
+	}
+
+	// b = H_non(R_1 || ... || R_ν || Q.x || m) and R = Σ_j b^(j-1)·R_j
+	ctx.b = new(big.Int).SetBytes(taggedHash("MuSig/noncecoef", append(aggNonces, qxBytes, session.Msg)...))
+	ctx.b.Mod(ctx.b, n)
+
+	var rx, ry *big.Int
+	bPow := big.NewInt(1)
+	for j := range aggNonces {
+		x, y := curve.ScalarMult(aggX[j], aggY[j], bPow.Bytes())
+		rx, ry = addPoints(curve, rx, ry, x, y)
+		bPow = new(big.Int).Mul(bPow, ctx.b)
+		bPow.Mod(bPow, n)
+	}
+	if rx == nil {
+		rx, ry = curve.Params().Gx, curve.Params().Gy
+	}
+	ctx.rx = rx
+	ctx.eps = 1
+	if ry.Bit(0) == 1 {
+		ctx.eps = -1
+	}
+
+	scheme := &schnorrScheme{curve: curve}
+	ctx.e = scheme.challenge(leftPad(rx.Bytes(), 32), qxBytes, session.Msg)
+
+	return ctx, nil
+}
+
+// musig2SecondKey returns the first key in the list that differs from the first one, or nil if
+// they're all the same.
+func musig2SecondKey(pubs [][]byte) []byte {
+	for _, pub := range pubs[1:] {
+		if !bytes.Equal(pub, pubs[0]) {
+			return pub
+		}
+	}
+	return nil
+}
+
+// partialSign computes the partial signature for the signer at index i with secret d and secret
+// nonces k.
+func (ctx *musig2Context) partialSign(i int, d *big.Int, k []*big.Int) *big.Int {
+	n := ctx.curve.Params().N
+
+	// s_i = ε·Σ_j b^(j-1)·k_j + e·a_i·g·d_i
+	s := new(big.Int)
+	bPow := big.NewInt(1)
+	for _, kj := range k {
+		s.Add(s, new(big.Int).Mul(bPow, kj))
+		bPow.Mul(bPow, ctx.b)
+		bPow.Mod(bPow, n)
+	}
+	s.Mul(s, big.NewInt(ctx.eps))
+
+	ead := new(big.Int).Mul(ctx.e, ctx.coeffs[i])
+	ead.Mul(ead, d)
+	ead.Mul(ead, big.NewInt(ctx.g))
+	s.Add(s, ead)
+
+	return s.Mod(s, n)
+}
+
+// SimulateMuSig2NonceReuse generates signing sessions in which the first signer reuses the same
+// numNonces secret nonces every time, while the co-signers and messages change. Enough sessions
+// are generated to recover the signer's key, and each one is completed into a valid aggregate
+// BIP340 signature.
+func SimulateMuSig2NonceReuse(numNonces int) ([]*MuSig2Session, error) {
+	if numNonces < 1 {
+		return nil, fmt.Errorf("must use at least one nonce")
+	}
+	curve := secp256k1.S256()
+	n := curve.Params().N
+
+	victim, err := randScalar(n)
+	if err != nil {
+		return nil, err
+	}
+	reused := make([]*big.Int, numNonces)
+	for j := range reused {
+		if reused[j], err = randScalar(n); err != nil {
+			return nil, err
+		}
+	}
+
+	sessions := make([]*MuSig2Session, numNonces+1)
+	for t := range sessions {
+		// A new co-signer each session, each using fresh nonces as it should.
+		cosigner, err := randScalar(n)
+		if err != nil {
+			return nil, err
+		}
+		fresh := make([]*big.Int, numNonces)
+		for j := range fresh {
+			if fresh[j], err = randScalar(n); err != nil {
+				return nil, err
+			}
+		}
+
+		secrets := []*big.Int{victim, cosigner}
+		nonces := [][]*big.Int{reused, fresh}
+		session := &MuSig2Session{
+			Msg:    []byte(fmt.Sprintf("example musig2 session #%d", t+1)),
+			Signer: 0,
+		}
+		for i, d := range secrets {
+			session.PubKeys = append(session.PubKeys, compressPoint(curve.ScalarBaseMult(d.Bytes())))
+
+			pubNonces := make([][]byte, numNonces)
+			for j, k := range nonces[i] {
+				pubNonces[j] = compressPoint(curve.ScalarBaseMult(k.Bytes()))
+			}
+			session.PubNonces = append(session.PubNonces, pubNonces)
+		}
+
+		ctx, err := newMuSig2Context(session)
+		if err != nil {
+			return nil, err
+		}
+
+		sig := new(big.Int)
+		for i, d := range secrets {
+			partial := ctx.partialSign(i, d, nonces[i])
+			if i == session.Signer {
+				session.PartialSig = leftPad(partial.Bytes(), 32)
+			}
+			sig.Add(sig, partial)
+		}
+		sig.Mod(sig, n)
+
+		session.Sig = make([]byte, 64)
+		copy(session.Sig, leftPad(ctx.rx.Bytes(), 32))
+		copy(session.Sig[32:], leftPad(sig.Bytes(), 32))
+		sessions[t] = session
+	}
+
+	return sessions, nil
+}
+
+// MuSig2AggregateKey returns the x-only aggregate public key the session's signature verifies
+// under.
+func MuSig2AggregateKey(session *MuSig2Session) ([]byte, error) {
+	ctx, err := newMuSig2Context(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return leftPad(ctx.qx.Bytes(), 32), nil
+}
+
+// RecoverMuSig2NonceReuse recovers the secret key of a signer who reused its nonces across the
+// sessions. Each session must contain a partial signature from the same signer and there must be
+// at least one more session than the number of nonces each signer contributes.
+func RecoverMuSig2NonceReuse(sessions []*MuSig2Session) (*PrivateKey, error) {
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("no sessions provided")
+	}
+	curve := secp256k1.S256()
+	n := curve.Params().N
+
+	for i, session := range sessions {
+		if err := checkMuSig2Session(session); err != nil {
+			return nil, fmt.Errorf("session %d: %w", i, err)
+		}
+	}
+
+	first := sessions[0]
+	pub := first.PubKeys[first.Signer]
+	nonces := first.PubNonces[first.Signer]
+	numNonces := len(nonces)
+	if len(sessions) < numNonces+1 {
+		return nil, fmt.Errorf("need at least %d sessions to solve for the key with %d nonces", numNonces+1, numNonces)
+	}
+
+	// Each session gives an equation over the unknowns (k_1, ..., k_ν, d):
+	//   ε·Σ_j b^(j-1)·k_j + e·a_i·g·d = s_i
+	rows := make([][]*big.Int, 0, len(sessions))
+	for _, session := range sessions {
+		if !bytes.Equal(session.PubKeys[session.Signer], pub) {
+			return nil, fmt.Errorf("partial signatures are from different signers")
+		}
+		if !equalNonces(session.PubNonces[session.Signer], nonces) {
+			return nil, fmt.Errorf("signer did not reuse its nonces across sessions")
+		}
+
+		ctx, err := newMuSig2Context(session)
+		if err != nil {
+			return nil, err
+		}
+
+		row := make([]*big.Int, numNonces+2)
+		bPow := big.NewInt(ctx.eps)
+		for j := 0; j < numNonces; j++ {
+			row[j] = new(big.Int).Mod(bPow, n)
+			bPow = new(big.Int).Mul(bPow, ctx.b)
+		}
+		coeff := new(big.Int).Mul(ctx.e, ctx.coeffs[session.Signer])
+		coeff.Mul(coeff, big.NewInt(ctx.g))
+		row[numNonces] = coeff.Mod(coeff, n)
+		row[numNonces+1] = new(big.Int).SetBytes(session.PartialSig)
+		rows = append(rows, row)
+	}
+
+	solution, err := solveLinearModN(rows, n)
+	if err != nil {
+		return nil, err
+	}
+
+	d := solution[numNonces]
+	x, y := curve.ScalarBaseMult(d.Bytes())
+	if !bytes.Equal(compressPoint(x, y), pub) {
+		return nil, fmt.Errorf("failed to recover private key")
+	}
+
+	return &PrivateKey{D: d, Pub: pub, scheme: &schnorrScheme{curve: curve}}, nil
+}
+
+// checkMuSig2Session returns an error unless the session has a nonce for each public key and a
+// partial signature from a signer in it.
+func checkMuSig2Session(session *MuSig2Session) error {
+	if session.Signer < 0 || session.Signer >= len(session.PubKeys) {
+		return fmt.Errorf("signer index out of range")
+	}
+	if len(session.PubNonces) != len(session.PubKeys) {
+		return fmt.Errorf("session must have a nonce for each public key")
+	}
+	if len(session.PartialSig) != 32 {
+		return fmt.Errorf("partial signature must be 32 bytes, got %d", len(session.PartialSig))
+	}
+	return nil
+}
+
+// solveLinearModN solves the system of equations given as augmented matrix rows with Gaussian
+// elimination modulo the prime n. There may be more equations than unknowns, as long as enough of
+// them are independent.
+func solveLinearModN(rows [][]*big.Int, n *big.Int) ([]*big.Int, error) {
+	unknowns := len(rows[0]) - 1
+	m := make([][]*big.Int, len(rows))
+	for i, row := range rows {
+		m[i] = make([]*big.Int, len(row))
+		for j, v := range row {
+			m[i][j] = new(big.Int).Mod(v, n)
+		}
+	}
+
+	for col := 0; col < unknowns; col++ {
+		pivot := -1
+		for i := col; i < len(m); i++ {
+			if m[i][col].Sign() != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, fmt.Errorf("equations are not independent, can't solve for the key")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		inv := new(big.Int).ModInverse(m[col][col], n)
+		for j := col; j <= unknowns; j++ {
+			m[col][j].Mul(m[col][j], inv).Mod(m[col][j], n)
+		}
+
+		for i := range m {
+			if i == col || m[i][col].Sign() == 0 {
+				continue
+			}
+			factor := new(big.Int).Set(m[i][col])
+			for j := col; j <= unknowns; j++ {
+				t := new(big.Int).Mul(factor, m[col][j])
+				m[i][j].Sub(m[i][j], t).Mod(m[i][j], n)
+			}
+		}
+	}
+
+	solution := make([]*big.Int, unknowns)
+	for i := range solution {
+		solution[i] = m[i][unknowns]
+	}
+	return solution, nil
+}
+
+func equalNonces(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// addPoints adds two points where a nil x coordinate represents the point at infinity, which
+// elliptic.Curve implementations don't handle consistently.
+func addPoints(curve elliptic.Curve, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) != 0 {
+			return nil, nil
+		}
+		return curve.Double(x1, y1)
+	}
+
+	return curve.Add(x1, y1, x2, y2)
+}
+
+// compressPoint returns the SEC1 compressed encoding of a point.
+func compressPoint(x, y *big.Int) []byte {
+	out := make([]byte, 33)
+	out[0] = byte(2 + y.Bit(0))
+	copy(out[1:], leftPad(x.Bytes(), 32))
+	return out
+}
+
+// decompressPoint decodes a SEC1 compressed point on a curve of the form y^2 = x^3 + b.
+func decompressPoint(curve elliptic.Curve, data []byte) (*big.Int, *big.Int, error) {
+	if len(data) != 33 || (data[0] != 2 && data[0] != 3) {
+		return nil, nil, fmt.Errorf("invalid compressed point")
+	}
+	params := curve.Params()
+	p := params.P
+
+	x := new(big.Int).SetBytes(data[1:])
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	y2.Add(y2, params.B)
+	y := new(big.Int).ModSqrt(y2.Mod(y2, p), p)
+	if y == nil {
+		return nil, nil, fmt.Errorf("invalid compressed point, not on curve")
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(p, y)
+	}
+
+	return x, y, nil
+}
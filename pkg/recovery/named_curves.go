@@ -0,0 +1,96 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+/*
+* Named curves that aren't in the standard library: the Brainpool curves from RFC 5639, which have
+* a ≠ -3 and so need weierstrassCurve, and secp192r1 which the standard library has never had.
+ */
+
+var (
+	initNamedCurves sync.Once
+	brainpoolP256r1 *weierstrassCurve
+	brainpoolP384r1 *weierstrassCurve
+	brainpoolP512r1 *weierstrassCurve
+	secp192r1       *elliptic.CurveParams
+)
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex constant: " + s)
+	}
+	return n
+}
+
+func initNamed() {
+	brainpoolP256r1 = newWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "brainpoolP256r1",
+		BitSize: 256,
+		P:       hexInt("A9FB57DBA1EEA9BC3E660A909D838D726E3BF623D52620282013481D1F6E5377"),
+		N:       hexInt("A9FB57DBA1EEA9BC3E660A909D838D718C397AA3B561A6F7901E0E82974856A7"),
+		B:       hexInt("26DC5C6CE94A4B44F330B5D9BBD77CBF958416295CF7E1CE6BCCDC18FF8C07B6"),
+		Gx:      hexInt("8BD2AEB9CB7E57CB2C4B482FFC81B7AFB9DE27E1E3BD23C23A4453BD9ACE3262"),
+		Gy:      hexInt("547EF835C3DAC4FD97F8461A14611DC9C27745132DED8E545C1D54C72F046997"),
+	}, hexInt("7D5A0975FC2C3057EEF67530417AFFE7FB8055C126DC5C6CE94A4B44F330B5D9"))
+
+	brainpoolP384r1 = newWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "brainpoolP384r1",
+		BitSize: 384,
+		P: hexInt("8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B4" +
+			"12B1DA197FB71123ACD3A729901D1A71874700133107EC53"),
+		N: hexInt("8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B3" +
+			"1F166E6CAC0425A7CF3AB6AF6B7FC3103B883202E9046565"),
+		B: hexInt("04A8C7DD22CE28268B39B55416F0447C2FB77DE107DCD2A6" +
+			"2E880EA53EEB62D57CB4390295DBC9943AB78696FA504C11"),
+		Gx: hexInt("1D1C64F068CF45FFA2A63A81B7C13F6B8847A3E77EF14FE3" +
+			"DB7FCAFE0CBD10E8E826E03436D646AAEF87B2E247D4AF1E"),
+		Gy: hexInt("8ABE1D7520F9C2A45CB1EB8E95CFD55262B70B29FEEC5864" +
+			"E19C054FF99129280E4646217791811142820341263C5315"),
+	}, hexInt("7BC382C63D8C150C3C72080ACE05AFA0C2BEA28E4FB22787"+
+		"139165EFBA91F90F8AA5814A503AD4EB04A8C7DD22CE2826"))
+
+	brainpoolP512r1 = newWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "brainpoolP512r1",
+		BitSize: 512,
+		P: hexInt("AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA70330871" +
+			"7D4D9B009BC66842AECDA12AE6A380E62881FF2F2D82C68528AA6056583A48F3"),
+		N: hexInt("AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA70330870" +
+			"553E5C414CA92619418661197FAC10471DB1D381085DDADDB58796829CA90069"),
+		B: hexInt("3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A7" +
+			"2BF2C7B9E7C1AC4D77FC94CADC083E67984050B75EBAE5DD2809BD638016F723"),
+		Gx: hexInt("81AEE4BDD82ED9645A21322E9C4C6A9385ED9F70B5D916C1B43B62EEF4D0098E" +
+			"FF3B1F78E2D0D48D50D1687B93B97D5F7C6D5047406A5E688B352209BCB9F822"),
+		Gy: hexInt("7DDE385D566332ECC0EABFA9CF7822FDF209F70024A57B1AA000C55B881F8111" +
+			"B2DCDE494A5F485E5BCA4BD88A2763AED1CA2B2FA8F0540678CD1E0F3AD80892"),
+	}, hexInt("7830A3318B603B89E2327145AC234CC594CBDD8D3DF91610A83441CAEA9863BC"+
+		"2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CA"))
+
+	// p = 2^192 - 2^64 - 1
+	p192 := new(big.Int).Lsh(one, 192)
+	p192.Sub(p192, new(big.Int).Lsh(one, 64))
+	p192.Sub(p192, one)
+	secp192r1 = &elliptic.CurveParams{
+		Name:    "P-192",
+		BitSize: 192,
+		P:       p192,
+		N:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFF99DEF836146BC9B1B4D22831"),
+		B:       hexInt("64210519E59C80E70FA7E9AB72243049FEB8DEECC146B9B1"),
+		Gx:      hexInt("188DA80EB03090F67CBF20EB43A18800F4FF0AFD82FF1012"),
+		Gy:      hexInt("07192B95FFC8DA78631011ED6B24CDD573F977A11E794811"),
+	}
+}
+
+func namedCurve(curve **weierstrassCurve) elliptic.Curve {
+	initNamedCurves.Do(initNamed)
+	return *curve
+}
+
+func p192() elliptic.Curve {
+	initNamedCurves.Do(initNamed)
+	return secp192r1
+}
@@ -0,0 +1,135 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/jakecraige/keyrecovery/pkg/prng"
+)
+
+/*
+* A signer drawing its nonces from a non-cryptographic PRNG seeded with the time, like
+* rand.Seed(time.Now().Unix()), leaves only the seeds around the signing time to guess. The nonce
+* relation k = α + β·d of a signature turns each candidate nonce into the key d = (k - α)/β, which is
+* the right one if its public key is the signer's. Signatures without a public key are checked
+* against every key their signature recovers to, so a single one is enough.
+*
+* The nonce is made from as many bytes of the model's output as the group order takes, read like any
+* other secret.
+ */
+
+// NonceSeedSearch is a search of the seeds of a PRNG model for the nonce of a signature.
+type NonceSeedSearch struct {
+	CurveID CurveIdentifier
+	SigID   SignatureIdentifier
+	Model   prng.Model
+
+	// SM2ID is the distinguishing ID of SM2 signers, SM2DefaultID if it's empty.
+	SM2ID string
+
+	// From and To are the first and last seeds tried, such as the Unix times around the signature.
+	From, To uint64
+
+	// Workers is the number of goroutines deriving nonces, runtime.NumCPU() if zero.
+	Workers int
+}
+
+// NonceSeedResult is the outcome of a search, with the key and the seed of its nonce if found.
+type NonceSeedResult struct {
+	Seeds uint64
+	Seed  uint64
+	Key   *PrivateKey
+}
+
+// nonceSeedSig is a signature as the search uses it, d = (k - α)·β⁻¹ and the keys it can be from.
+type nonceSeedSig struct {
+	alpha, betaInv *big.Int
+	pubs           map[string]bool
+}
+
+// Run tries the nonce of every seed from From to To against each signature until one gives the key
+// or ctx is done. The signatures must all be by one signer.
+func (s *NonceSeedSearch) Run(ctx context.Context, sigs []*Signature) (*NonceSeedResult, error) {
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("no signatures to search nonces for")
+	}
+	if err := checkSeeds(s.Model, s.From, s.To); err != nil {
+		return nil, err
+	}
+	if err := checkSM2ID(s.SM2ID); err != nil {
+		return nil, err
+	}
+	scheme, err := schemeForSignatures(s.CurveID, s.SigID, s.SM2ID, sigs)
+	if err != nil {
+		return nil, err
+	}
+	n := scheme.order()
+
+	targets := make([]*nonceSeedSig, 0, len(sigs))
+	for _, sig := range sigs {
+		alpha, beta, err := scheme.nonceRelation(sig)
+		if err != nil {
+			return nil, err
+		}
+		betaInv := new(big.Int).ModInverse(beta, n)
+		if betaInv == nil {
+			continue
+		}
+
+		t := &nonceSeedSig{alpha: alpha, betaInv: betaInv, pubs: map[string]bool{}}
+		if sig.Pub != nil {
+			t.pubs[string(sig.Pub)] = true
+		} else {
+			pr, ok := scheme.(pubRecoverer)
+			if !ok {
+				return nil, fmt.Errorf("signatures need public keys, %s can't recover them", s.SigID)
+			}
+			pubs, err := pr.recoverPublicKeys(sig)
+			if err != nil {
+				return nil, err
+			}
+			for _, pub := range pubs {
+				t.pubs[string(pub)] = true
+			}
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no signature relates the nonce to the key")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	result := &NonceSeedResult{}
+	nonceLen := (n.BitLen() + 7) / 8
+	result.Seeds = sweepSeeds(ctx, s.From, s.To, s.Workers, func() func(uint64) {
+		nonce := make([]byte, nonceLen)
+		return func(seed uint64) {
+			s.Model.Fill(seed, nonce)
+			k := new(big.Int).SetBytes(nonce)
+			if k.Mod(k, n).Sign() == 0 {
+				return
+			}
+
+			for _, t := range targets {
+				d := new(big.Int).Sub(k, t.alpha)
+				d.Mul(d, t.betaInv)
+				d.Mod(d, n)
+				if d.Sign() == 0 || !t.pubs[string(scheme.publicKey(d))] {
+					continue
+				}
+
+				once.Do(func() {
+					result.Seed, result.Key = seed, newPrivateKey(scheme, d)
+					cancel()
+				})
+				return
+			}
+		}
+	})
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jakecraige/keyrecovery/pkg/hdwallet"
+	"github.com/jakecraige/keyrecovery/pkg/prng"
+)
+
+/*
+* Keys generated from a weakly seeded PRNG, such as the Milk Sad wallets made with libbitcoin's bx
+* seed, are recovered by deriving the key from every seed and looking the public key up in the
+* targets. The key is made from as many bytes of the model's output as the group order takes, read
+* like any other secret.
+*
+* Wallet tools like bx seed draw entropy rather than a key though. With a wallet derivation the
+* output is the entropy of a BIP32 wallet, usually through a BIP39 mnemonic, and every key at its
+* paths is looked up. The mnemonic's PBKDF2 seed takes thousands of hashes, so those searches are
+* much slower and are best narrowed to the seeds of a likely period of time.
+ */
+
+// prngChunk is the number of seeds a worker takes at a time.
+const prngChunk = 1 << 12
+
+// PRNGSearch is a search of the seeds of a PRNG model for keys among the targets.
+type PRNGSearch struct {
+	CurveID CurveIdentifier
+	SigID   SignatureIdentifier
+	Model   prng.Model
+	Targets *KeyTargets
+
+	// From and To are the first and last seeds tried.
+	From, To uint64
+
+	// Wallet, if set, makes the model's output the entropy of a wallet whose keys are derived with
+	// it. Wallets are on secp256k1.
+	Wallet *hdwallet.Derivation
+
+	// Workers is the number of goroutines deriving keys, runtime.NumCPU() if zero.
+	Workers int
+}
+
+// PRNGMatch is a seed whose key is one of the targets.
+type PRNGMatch struct {
+	Seed   uint64
+	Target string
+	Key    *PrivateKey
+
+	// Path is the derivation path of the key in the wallet, if the search was for wallets.
+	Path string
+}
+
+// PRNGResult is the outcome of a search.
+type PRNGResult struct {
+	Seeds   uint64
+	Matches []*PRNGMatch
+}
+
+// Run tries every seed from From to To, calling found for each match as it's found. It stops when
+// every target has been found or ctx is done.
+func (s *PRNGSearch) Run(ctx context.Context, found func(*PRNGMatch)) (*PRNGResult, error) {
+	scheme, err := newScheme(s.CurveID, s.SigID, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := scheme.(*dsaScheme); ok {
+		return nil, fmt.Errorf("PRNG search needs an elliptic curve")
+	}
+	if s.Targets == nil || s.Targets.Len() == 0 {
+		return nil, fmt.Errorf("no PRNG targets")
+	}
+	if err := checkSeeds(s.Model, s.From, s.To); err != nil {
+		return nil, err
+	}
+
+	keyLen := (scheme.order().BitLen() + 7) / 8
+	if s.Wallet != nil {
+		if s.CurveID != Curve_S256 {
+			return nil, fmt.Errorf("wallet keys are on %s, not %s", Curve_S256, s.CurveID)
+		}
+		if err := s.Wallet.Validate(); err != nil {
+			return nil, err
+		}
+		keyLen = s.Wallet.EntropyBits / 8
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	result := &PRNGResult{}
+	matched := map[string]bool{}
+	try := func(seed uint64, secret []byte, path string) {
+		k := secretKey(scheme, secret)
+		if k == nil {
+			return
+		}
+		target, ok := s.Targets.match(scheme, scheme.publicKey(k))
+		if !ok {
+			return
+		}
+
+		m := &PRNGMatch{Seed: seed, Target: target, Key: newPrivateKey(scheme, k), Path: path}
+		mu.Lock()
+		defer mu.Unlock()
+		result.Matches = append(result.Matches, m)
+		matched[target] = true
+		if found != nil {
+			found(m)
+		}
+		if len(matched) == s.Targets.Len() {
+			cancel()
+		}
+	}
+
+	result.Seeds = sweepSeeds(ctx, s.From, s.To, s.Workers, func() func(uint64) {
+		secret := make([]byte, keyLen)
+		return func(seed uint64) {
+			s.Model.Fill(seed, secret)
+			if s.Wallet == nil {
+				try(seed, secret, "")
+				return
+			}
+
+			// Entropy giving an invalid key anywhere along a path, which is vanishingly rare, can't have
+			// made a wallet at all.
+			keys, err := s.Wallet.Keys(secret)
+			if err != nil {
+				return
+			}
+			for i, key := range keys {
+				try(seed, key, s.Wallet.Paths[i].String())
+			}
+		}
+	})
+	return result, nil
+}
+
+func checkSeeds(model prng.Model, from, to uint64) error {
+	if from > to || to > model.MaxSeed() {
+		return fmt.Errorf("seeds must be in [0, %d] and From at most To", model.MaxSeed())
+	}
+	return nil
+}
+
+// sweepSeeds calls a function from newTry, one for each of the workers goroutines, with every seed
+// from from to to until ctx is done. It returns the number of seeds tried.
+func sweepSeeds(ctx context.Context, from, to uint64, workers int, newTry func() func(seed uint64)) uint64 {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// next is the offset from from of the next chunk.
+	var next, tried uint64 // atomic
+	var wg sync.WaitGroup
+	width := to - from
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			try := newTry()
+			for ctx.Err() == nil {
+				start := atomic.AddUint64(&next, prngChunk) - prngChunk
+				if start > width {
+					return
+				}
+				end := start + prngChunk - 1
+				if end > width || end < start {
+					end = width
+				}
+
+				for off := start; ; off++ {
+					try(from + off)
+					if off == end {
+						break
+					}
+				}
+				atomic.AddUint64(&tried, end-start+1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return tried
+}
@@ -2,7 +2,6 @@ package recovery
 
 import (
 	"bufio"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -14,6 +13,10 @@ type RecoveryMode string
 const (
 	Recovery_NonceReuse      RecoveryMode = "nonce-reuse"
 	Recovery_NonceBiasPrefix RecoveryMode = "nonce-bias-prefix"
+	Recovery_MismatchedPub   RecoveryMode = "mismatched-pubkey"
+	Recovery_Fault           RecoveryMode = "fault"
+	Recovery_WeakCurve       RecoveryMode = "weak-curve"
+	Recovery_SmallNonce      RecoveryMode = "small-nonce"
 )
 
 func NewRecoveryMode(mode string) (RecoveryMode, error) {
@@ -22,20 +25,35 @@ func NewRecoveryMode(mode string) (RecoveryMode, error) {
 		return Recovery_NonceReuse, nil
 	case string(Recovery_NonceBiasPrefix):
 		return Recovery_NonceBiasPrefix, nil
+	case string(Recovery_MismatchedPub):
+		return Recovery_MismatchedPub, nil
+	case string(Recovery_Fault):
+		return Recovery_Fault, nil
+	case string(Recovery_WeakCurve):
+		return Recovery_WeakCurve, nil
+	case string(Recovery_SmallNonce):
+		return Recovery_SmallNonce, nil
 	default:
 		return "", fmt.Errorf("unsupported recovery mode: %s", mode)
 	}
 }
 
 func (m RecoveryMode) Strategy(curveID CurveIdentifier, sigID SignatureIdentifier) (Strategy, error) {
+	return m.strategy(curveID, sigID, "")
+}
+
+// strategy returns the strategy for signers with the SM2 distinguishing ID, SM2DefaultID if it's
+// empty.
+func (m RecoveryMode) strategy(curveID CurveIdentifier, sigID SignatureIdentifier, sm2ID string) (Strategy, error) {
 	switch m {
 	case Recovery_NonceReuse:
-		return &NonceReuseStrategy{curve: curveID.Curve(), sigID: sigID}, nil
+		return &NonceReuseStrategy{curveID: curveID, sigID: sigID, sm2ID: sm2ID}, nil
 
 	case Recovery_NonceBiasPrefix:
 		return &NonceBiasPrefixStrategy{
-			curve: curveID.Curve(),
-			sigID: sigID,
+			curveID: curveID,
+			sigID:   sigID,
+			sm2ID:   sm2ID,
 
 			// bitBias is the amount of bits that the nonce is biased by. Using a static value for now but
 			// this could be made configurable in the future.
@@ -47,6 +65,26 @@ func (m RecoveryMode) Strategy(curveID CurveIdentifier, sigID SignatureIdentifie
 			numSigs: 10,
 		}, nil
 
+	case Recovery_MismatchedPub:
+		return &MismatchedPubStrategy{curveID: curveID, sigID: sigID, sm2ID: sm2ID}, nil
+
+	case Recovery_Fault:
+		return &FaultStrategy{curveID: curveID, sigID: sigID, sm2ID: sm2ID}, nil
+
+	case Recovery_WeakCurve:
+		return &WeakCurveStrategy{curveID: curveID, sigID: sigID, sm2ID: sm2ID}, nil
+
+	case Recovery_SmallNonce:
+		return &SmallNonceStrategy{
+			curveID: curveID,
+			sigID:   sigID,
+			sm2ID:   sm2ID,
+
+			// nonceBits is the size of the nonces. Like the nonce bias it's static for now, and small
+			// enough for the kangaroo to take seconds.
+			nonceBits: 32,
+		}, nil
+
 	default:
 		return nil, fmt.Errorf("strategy not implemented")
 	}
@@ -56,6 +94,9 @@ type Config struct {
 	curveID CurveIdentifier
 	sigID   SignatureIdentifier
 	mode    RecoveryMode
+
+	// sm2ID is the distinguishing ID of SM2 signers, SM2DefaultID if it's empty.
+	sm2ID string
 }
 
 func New(curveID CurveIdentifier, sigID SignatureIdentifier, mode RecoveryMode) (*Config, error) {
@@ -70,7 +111,81 @@ func New(curveID CurveIdentifier, sigID SignatureIdentifier, mode RecoveryMode)
 	}, nil
 }
 
-func (c *Config) RecoverFromFile(path string, format string) (*ecdsa.PrivateKey, error) {
+// SetSM2ID sets the distinguishing ID of the signer, which SM2 hashes into every message. It's an
+// error for other signature types.
+func (c *Config) SetSM2ID(id string) error {
+	if c.sigID.family() != familySM2 {
+		return fmt.Errorf("%s signatures don't have a distinguishing ID", c.sigID)
+	}
+	if err := checkSM2ID(id); err != nil {
+		return err
+	}
+
+	c.sm2ID = id
+	return nil
+}
+
+func (c *Config) CurveID() CurveIdentifier {
+	return c.curveID
+}
+
+// RLen returns the length in bytes of the nonce commitment that Signature.R returns.
+func (c *Config) RLen() (int, error) {
+	scheme, err := newScheme(c.curveID, c.sigID, c.sm2ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return scheme.sigLen() / 2, nil
+}
+
+// NonceCommitment returns a value derived from the signature which is equal for all signatures
+// made with the same nonce. For most schemes this is just the r value.
+func (c *Config) NonceCommitment(sig *Signature) ([]byte, error) {
+	scheme, err := newScheme(c.curveID, c.sigID, c.sm2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return nonceCommitment(scheme, sig), nil
+}
+
+// OrderFactorization factors the order of the curve's base point, as far as it can be factored.
+func (c *Config) OrderFactorization() ([]OrderFactor, error) {
+	curve, err := c.curveID.Curve()
+	if err != nil {
+		return nil, fmt.Errorf("order factorization is only supported for elliptic curves: %w", err)
+	}
+
+	return factorize(curve.Params().N), nil
+}
+
+// AnalyzeCurve returns the weakness of the curve that the weak-curve mode will use.
+func (c *Config) AnalyzeCurve() (*CurveAnalysis, error) {
+	curve, err := c.curveID.Curve()
+	if err != nil {
+		return nil, fmt.Errorf("curve analysis is only supported for elliptic curves: %w", err)
+	}
+
+	return analyzeCurve(c.curveID, curve), nil
+}
+
+// ParseSignature parses a serialized signature for the configured curve and signature type.
+func (c *Config) ParseSignature(data []byte, format string) (*Signature, error) {
+	return SignatureFromBytes(data, c.curveID, c.sigID, format)
+}
+
+// Verify reports whether the signature is valid for its public key and message.
+func (c *Config) Verify(sig *Signature) (bool, error) {
+	scheme, err := schemeForSignatures(c.curveID, c.sigID, c.sm2ID, []*Signature{sig})
+	if err != nil {
+		return false, err
+	}
+
+	return scheme.verify(sig), nil
+}
+
+func (c *Config) RecoverFromFile(path string, format string) (*PrivateKey, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -80,7 +195,7 @@ func (c *Config) RecoverFromFile(path string, format string) (*ecdsa.PrivateKey,
 	return c.RecoverFromReader(file, format)
 }
 
-func (c *Config) RecoverFromReader(r io.Reader, format string) (*ecdsa.PrivateKey, error) {
+func (c *Config) RecoverFromReader(r io.Reader, format string) (*PrivateKey, error) {
 	sigs := make([]*Signature, 0)
 
 	scanner := bufio.NewScanner(r)
@@ -90,7 +205,7 @@ func (c *Config) RecoverFromReader(r io.Reader, format string) (*ecdsa.PrivateKe
 			return nil, err
 		}
 
-		sig, err := SignatureFromBytes(bytes, c.curveID.Curve(), format)
+		sig, err := c.ParseSignature(bytes, format)
 		if err != nil {
 			return nil, err
 		}
@@ -105,8 +220,43 @@ func (c *Config) RecoverFromReader(r io.Reader, format string) (*ecdsa.PrivateKe
 	return c.Recover(sigs)
 }
 
-func (c *Config) Recover(signatures []*Signature) (*ecdsa.PrivateKey, error) {
-	strat, err := c.mode.Strategy(c.curveID, c.sigID)
+// RecoverPublicKeys returns the candidate public keys for a signature, one for each recovery id
+// that gives a valid point.
+func (c *Config) RecoverPublicKeys(sig *Signature) ([][]byte, error) {
+	scheme, err := newScheme(c.curveID, c.sigID, c.sm2ID)
+	if err != nil {
+		return nil, err
+	}
+	pr, ok := scheme.(pubRecoverer)
+	if !ok {
+		return nil, fmt.Errorf("public key recovery is not supported for %s", c.sigID)
+	}
+
+	return pr.recoverPublicKeys(sig)
+}
+
+// RecoverSigners fills in the public key of each signature without one by cross-matching the
+// candidate public keys of all of the signatures.
+func (c *Config) RecoverSigners(signatures []*Signature) error {
+	scheme, err := newScheme(c.curveID, c.sigID, c.sm2ID)
+	if err != nil {
+		return err
+	}
+
+	return recoverSigners(scheme, signatures)
+}
+
+func (c *Config) Recover(signatures []*Signature) (*PrivateKey, error) {
+	for _, sig := range signatures {
+		if sig.Pub == nil {
+			if err := c.RecoverSigners(signatures); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	strat, err := c.mode.strategy(c.curveID, c.sigID, c.sm2ID)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +265,7 @@ func (c *Config) Recover(signatures []*Signature) (*ecdsa.PrivateKey, error) {
 }
 
 func (c *Config) Generate() ([]*Signature, error) {
-	strat, err := c.mode.Strategy(c.curveID, c.sigID)
+	strat, err := c.mode.strategy(c.curveID, c.sigID, c.sm2ID)
 	if err != nil {
 		return nil, err
 	}
@@ -125,5 +275,5 @@ func (c *Config) Generate() ([]*Signature, error) {
 
 type Strategy interface {
 	Generate() ([]*Signature, error)
-	Recover(signatures []*Signature) (*ecdsa.PrivateKey, error)
+	Recover(signatures []*Signature) (*PrivateKey, error)
 }
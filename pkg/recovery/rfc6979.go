@@ -0,0 +1,69 @@
+package recovery
+
+import (
+	"crypto/hmac"
+	"hash"
+	"math/big"
+)
+
+/*
+* Deterministic nonce generation for ECDSA from RFC 6979 section 3.2. The nonce only depends on the
+* private key and the message hash, so the same message is always signed with the same nonce which
+* is what makes a single faulty signature exploitable.
+ */
+
+// rfc6979Nonce returns the nonce for signing hash with the private key x for a group of order q.
+func rfc6979Nonce(x, q *big.Int, hash []byte, newHash func() hash.Hash) *big.Int {
+	qLen := q.BitLen()
+	rLen := (qLen + 7) / 8
+
+	bits2int := func(b []byte) *big.Int {
+		return hashToIntBits(b, qLen)
+	}
+	int2octets := func(v *big.Int) []byte {
+		return leftPad(v.Bytes(), rLen)
+	}
+	bits2octets := func(b []byte) []byte {
+		z := bits2int(b)
+		if z.Cmp(q) >= 0 {
+			z.Sub(z, q)
+		}
+		return int2octets(z)
+	}
+	mac := func(key []byte, parts ...[]byte) []byte {
+		m := hmac.New(newHash, key)
+		for _, p := range parts {
+			m.Write(p)
+		}
+		return m.Sum(nil)
+	}
+
+	hLen := newHash().Size()
+	v := make([]byte, hLen)
+	k := make([]byte, hLen)
+	for i := range v {
+		v[i] = 0x01
+	}
+
+	seed := append(int2octets(x), bits2octets(hash)...)
+	k = mac(k, v, []byte{0x00}, seed)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, seed)
+	v = mac(k, v)
+
+	for {
+		var t []byte
+		for len(t) < rLen {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+
+		nonce := bits2int(t[:rLen])
+		if nonce.Sign() > 0 && nonce.Cmp(q) < 0 {
+			return nonce
+		}
+
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
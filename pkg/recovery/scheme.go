@@ -0,0 +1,247 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// scheme implements the algebra of a signature algorithm over a specific curve. Every supported
+// algorithm produces signatures whose nonce is linear in the private key, which is the property
+// the recovery strategies exploit, so they only need to work in terms of that relation.
+type scheme interface {
+	// order is the order of the group the private key and nonce live in.
+	order() *big.Int
+
+	// pubLen and sigLen are the length in bytes of serialized public keys and signatures.
+	pubLen() int
+	sigLen() int
+
+	// generateKey returns a new random private key.
+	generateKey(rand io.Reader) (*big.Int, error)
+
+	// publicKey returns the serialized public key for the private key d.
+	publicKey(d *big.Int) []byte
+
+	// sign signs msg with the private key d using the provided nonce k.
+	sign(d, k *big.Int, msg []byte) ([]byte, error)
+
+	// verify reports whether the signature is valid for its public key and message.
+	verify(sig *Signature) bool
+
+	// nonceRelation returns α and β such that the nonce used to generate the signature is
+	// k = α + β·d mod n.
+	nonceRelation(sig *Signature) (alpha, beta *big.Int, err error)
+}
+
+// boundScheme is implemented by schemes whose domain parameters are carried in the public key
+// rather than being fixed by the curve identifier.
+type boundScheme interface {
+	bind(pub []byte) (scheme, error)
+}
+
+// nonceCommitter is implemented by schemes whose signatures don't contain the nonce commitment
+// directly, so reuse can't be detected by comparing Signature.R.
+type nonceCommitter interface {
+	nonceCommitment(sig *Signature) []byte
+}
+
+// nonceCommitment returns a value which is the same for every signature made with the same nonce.
+func nonceCommitment(scheme scheme, sig *Signature) []byte {
+	if nc, ok := scheme.(nonceCommitter); ok {
+		return nc.nonceCommitment(sig)
+	}
+	return sig.R()
+}
+
+// newScheme returns the scheme of the signature type over the curve. sm2ID is the distinguishing ID
+// of SM2 signers, SM2DefaultID if it's empty, and ignored for other signature types.
+func newScheme(curveID CurveIdentifier, sigID SignatureIdentifier, sm2ID string) (scheme, error) {
+	if !curveID.IsSupported(sigID) {
+		return nil, fmt.Errorf("sig %s is not supported with curve %s", sigID, curveID)
+	}
+
+	if sigID.family() == familyDSA {
+		return &dsaScheme{size: curveID.dsaSize(), sigID: sigID}, nil
+	}
+	curve, err := curveID.Curve()
+	if err != nil {
+		return nil, err
+	}
+
+	switch sigID.family() {
+	case familyECDSA:
+		return &ecdsaScheme{curve: curve, sigID: sigID}, nil
+
+	case familyEdDSA:
+		edwards, ok := curve.(*edwardsCurve)
+		if !ok {
+			return nil, fmt.Errorf("sig %s requires an edwards curve", sigID)
+		}
+		return &eddsaScheme{curve: edwards}, nil
+
+	case familySchnorr:
+		return &schnorrScheme{curve: curve}, nil
+
+	case familySM2:
+		if sm2ID == "" {
+			sm2ID = SM2DefaultID
+		}
+		return &sm2Scheme{curve: curve, id: []byte(sm2ID)}, nil
+
+	case familyGOST:
+		return &gostScheme{curve: curve, sigID: sigID}, nil
+
+	default:
+		return nil, fmt.Errorf("no signature scheme for %s", sigID)
+	}
+}
+
+// schemeForSignatures returns the scheme for the signatures, bound to the domain parameters from
+// their public key if the scheme has them. All signatures must share the same public key.
+func schemeForSignatures(curveID CurveIdentifier, sigID SignatureIdentifier, sm2ID string, sigs []*Signature) (scheme, error) {
+	scheme, err := newScheme(curveID, sigID, sm2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sig := range sigs[1:] {
+		if !bytes.Equal(sig.Pub, sigs[0].Pub) {
+			return nil, fmt.Errorf("signatures are from different public keys")
+		}
+	}
+
+	if bs, ok := scheme.(boundScheme); ok {
+		return bs.bind(sigs[0].Pub)
+	}
+	return scheme, nil
+}
+
+type ecdsaScheme struct {
+	curve elliptic.Curve
+	sigID SignatureIdentifier
+}
+
+func (s *ecdsaScheme) order() *big.Int {
+	return s.curve.Params().N
+}
+
+func (s *ecdsaScheme) pubLen() int {
+	return byteLen(s.curve) * 2
+}
+
+func (s *ecdsaScheme) sigLen() int {
+	return byteLen(s.curve) * 2
+}
+
+func (s *ecdsaScheme) generateKey(rand io.Reader) (*big.Int, error) {
+	key, err := ecdsa.GenerateKey(s.curve, rand)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.D, nil
+}
+
+func (s *ecdsaScheme) publicKey(d *big.Int) []byte {
+	x, y := s.curve.ScalarBaseMult(d.Bytes())
+	return serializePub(&ecdsa.PublicKey{Curve: s.curve, X: x, Y: y}, byteLen(s.curve))
+}
+
+func (s *ecdsaScheme) sign(d, k *big.Int, msg []byte) ([]byte, error) {
+	return s.signWithFault(d, k, msg, nil)
+}
+
+// signWithFault signs msg while injecting the fault into the computation, if it's not nil.
+func (s *ecdsaScheme) signWithFault(d, k *big.Int, msg []byte, fault *ecdsaFault) ([]byte, error) {
+	priv := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: s.curve}, D: d}
+	r, sig, err := ecdsaSign(priv, k, s.curve, s.digest(msg), fault)
+	if err != nil {
+		return nil, err
+	}
+
+	byteLen := byteLen(s.curve)
+	out := make([]byte, byteLen*2)
+	copy(out, leftPad(r.Bytes(), byteLen))
+	copy(out[byteLen:], leftPad(sig.Bytes(), byteLen))
+	return out, nil
+}
+
+func (s *ecdsaScheme) verify(sig *Signature) bool {
+	n := s.order()
+	byteLen := byteLen(s.curve)
+	if len(sig.Pub) != 2*byteLen || len(sig.Sig) != 2*byteLen {
+		return false
+	}
+	qx := new(big.Int).SetBytes(sig.Pub[:byteLen])
+	qy := new(big.Int).SetBytes(sig.Pub[byteLen:])
+	if !s.curve.IsOnCurve(qx, qy) {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	sInv := new(big.Int).SetBytes(sig.Sig[byteLen:])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || sInv.Sign() == 0 || sInv.Cmp(n) >= 0 {
+		return false
+	}
+	if sInv.ModInverse(sInv, n) == nil {
+		return false
+	}
+	z := s.digest(sig.Msg)
+	if s.sigID == Sig_ECDSA_STARK && (z.Cmp(starkMaxValue) >= 0 || r.Cmp(starkMaxValue) >= 0 ||
+		sInv.Cmp(starkMaxValue) >= 0) {
+		return false
+	}
+
+	// R = z·s⁻¹·G + r·s⁻¹·Q and its x coordinate must equal r
+	u1 := z.Mul(z, sInv)
+	u2 := new(big.Int).Mul(r, sInv)
+	x1, y1 := s.curve.ScalarBaseMult(u1.Mod(u1, n).Bytes())
+	x2, y2 := s.curve.ScalarMult(qx, qy, u2.Mod(u2, n).Bytes())
+	if x1.Cmp(x2) == 0 {
+		return false
+	}
+	x, _ := s.curve.Add(x1, y1, x2, y2)
+
+	return x.Mod(x, n).Cmp(r) == 0
+}
+
+func (s *ecdsaScheme) nonceRelation(sig *Signature) (*big.Int, *big.Int, error) {
+	n := s.order()
+	byteLen := byteLen(s.curve)
+	r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	sInv := new(big.Int).SetBytes(sig.Sig[byteLen:])
+	if sInv.ModInverse(sInv, n) == nil {
+		return nil, nil, fmt.Errorf("invalid signature, s has no inverse")
+	}
+	z := s.digest(sig.Msg)
+
+	// s = k⁻¹(z + r·d) so k = z·s⁻¹ + r·s⁻¹·d
+	alpha := z.Mul(z, sInv)
+	beta := r.Mul(r, sInv)
+	return alpha.Mod(alpha, n), beta.Mod(beta, n), nil
+}
+
+// digest returns the message as an integer z. STARK messages are already field elements while the
+// rest are hashed and truncated to the bit length of the order.
+func (s *ecdsaScheme) digest(msg []byte) *big.Int {
+	if s.sigID == Sig_ECDSA_STARK {
+		return new(big.Int).SetBytes(msg)
+	}
+
+	return hashToInt(hashBytes(s.sigID.Hash(), msg), s.curve)
+}
+
+// randScalar returns a uniformly random scalar in [1, n-1].
+func randScalar(n *big.Int) (*big.Int, error) {
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(n, one))
+	if err != nil {
+		return nil, err
+	}
+
+	return k.Add(k, one), nil
+}
@@ -0,0 +1,168 @@
+package recovery
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+/*
+* BIP340 Schnorr signatures over secp256k1. Public keys are x-only and implicitly have an even y
+* coordinate, and the same applies to the nonce point R, so the signer negates the private key and
+* nonce as needed to match. Signatures are R.x || s where s = k + e·d and the challenge e is the
+* tagged hash of R.x || P.x || m. Private keys are always returned in their even-y form since that's
+* the only one a signature can determine.
+ */
+
+type schnorrScheme struct {
+	curve elliptic.Curve
+}
+
+func (s *schnorrScheme) order() *big.Int {
+	return s.curve.Params().N
+}
+
+func (s *schnorrScheme) pubLen() int {
+	return 32
+}
+
+func (s *schnorrScheme) sigLen() int {
+	return 64
+}
+
+func (s *schnorrScheme) generateKey(rand io.Reader) (*big.Int, error) {
+	key, err := ecdsa.GenerateKey(s.curve, rand)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.evenKey(key.D), nil
+}
+
+func (s *schnorrScheme) publicKey(d *big.Int) []byte {
+	x, _ := s.curve.ScalarBaseMult(d.Bytes())
+	return leftPad(x.Bytes(), 32)
+}
+
+// evenKey returns d or -d, whichever results in a public key with an even y coordinate.
+func (s *schnorrScheme) evenKey(d *big.Int) *big.Int {
+	_, y := s.curve.ScalarBaseMult(d.Bytes())
+	if y.Bit(0) == 0 {
+		return new(big.Int).Set(d)
+	}
+
+	return new(big.Int).Sub(s.order(), d)
+}
+
+func (s *schnorrScheme) sign(d, k *big.Int, msg []byte) ([]byte, error) {
+	n := s.order()
+	if k.Sign() == 0 || k.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("nonce out of range")
+	}
+
+	d = s.evenKey(d)
+	rx, _ := s.curve.ScalarBaseMult(k.Bytes())
+	k = s.evenKey(k)
+
+	R := leftPad(rx.Bytes(), 32)
+	e := s.challenge(R, s.publicKey(d), msg)
+
+	// s = k + e·d
+	sig := e.Mul(e, d)
+	sig.Add(sig, k)
+	sig.Mod(sig, n)
+
+	out := make([]byte, 64)
+	copy(out, R)
+	copy(out[32:], leftPad(sig.Bytes(), 32))
+	return out, nil
+}
+
+func (s *schnorrScheme) nonceRelation(sig *Signature) (*big.Int, *big.Int, error) {
+	n := s.order()
+	sigS := new(big.Int).SetBytes(sig.Sig[32:])
+	if sigS.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("invalid signature, s is not reduced")
+	}
+
+	// s = k + e·d so k = s - e·d, where both k and d are their even-y forms.
+	beta := s.challenge(sig.Sig[:32], sig.Pub, sig.Msg)
+	beta.Neg(beta)
+	return sigS, beta.Mod(beta, n), nil
+}
+
+func (s *schnorrScheme) verify(sig *Signature) bool {
+	params := s.curve.Params()
+	if len(sig.Pub) != 32 || len(sig.Sig) != 64 {
+		return false
+	}
+
+	px, py, err := s.liftX(sig.Pub)
+	if err != nil {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig.Sig[:32])
+	sigS := new(big.Int).SetBytes(sig.Sig[32:])
+	if r.Cmp(params.P) >= 0 || sigS.Cmp(params.N) >= 0 {
+		return false
+	}
+	e := s.challenge(sig.Sig[:32], sig.Pub, sig.Msg)
+
+	// R = s·G - e·P must have an even y coordinate and x coordinate r.
+	sx, sy := s.curve.ScalarBaseMult(sigS.Bytes())
+	ex, ey := s.curve.ScalarMult(px, py, e.Bytes())
+	ey.Sub(params.P, ey)
+	if sx.Cmp(ex) == 0 {
+		// Either R would be the point at infinity or this is a doubling, neither of which a valid
+		// signature produces.
+		return false
+	}
+	rx, ry := s.curve.Add(sx, sy, ex, ey)
+
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
+
+// liftX returns the point with the given x coordinate and an even y coordinate.
+func (s *schnorrScheme) liftX(data []byte) (*big.Int, *big.Int, error) {
+	p := s.curve.Params().P
+	x := new(big.Int).SetBytes(data)
+	if x.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("invalid x coordinate")
+	}
+
+	// y^2 = x^3 + 7
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	y2.Add(y2, big.NewInt(7))
+	y := new(big.Int).ModSqrt(y2.Mod(y2, p), p)
+	if y == nil {
+		return nil, nil, fmt.Errorf("invalid x coordinate, not on curve")
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+
+	return x, y, nil
+}
+
+// challenge computes the BIP340 challenge e = H_challenge(R.x || P.x || m) mod n.
+func (s *schnorrScheme) challenge(R, pub, msg []byte) *big.Int {
+	h := taggedHash("BIP0340/challenge", R, pub, msg)
+	e := new(big.Int).SetBytes(h)
+	return e.Mod(e, s.order())
+}
+
+// taggedHash implements the BIP340 tagged hash SHA256(SHA256(tag) || SHA256(tag) || data...).
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
@@ -1,56 +1,165 @@
 package recovery
 
 import (
-	"crypto/elliptic"
-	"crypto/sha256"
-	"crypto/sha512"
 	"fmt"
 	"hash"
-
-	"golang.org/x/crypto/sha3"
+	"strings"
 )
 
 type SignatureIdentifier string
 
 const (
-	Sig_ECDSA_SHA256    SignatureIdentifier = "ECDSA-SHA256"
-	Sig_ECDSA_SHA512    SignatureIdentifier = "ECDSA-SHA512"
-	Sig_ECDSA_KECCAK256 SignatureIdentifier = "ECDSA-KECCAK256"
+	Sig_ECDSA_SHA1         SignatureIdentifier = "ECDSA-SHA1"
+	Sig_ECDSA_SHA224       SignatureIdentifier = "ECDSA-SHA224"
+	Sig_ECDSA_SHA256       SignatureIdentifier = "ECDSA-SHA256"
+	Sig_ECDSA_SHA384       SignatureIdentifier = "ECDSA-SHA384"
+	Sig_ECDSA_SHA512       SignatureIdentifier = "ECDSA-SHA512"
+	Sig_ECDSA_SHA512_256   SignatureIdentifier = "ECDSA-SHA512/256"
+	Sig_ECDSA_SHA3_224     SignatureIdentifier = "ECDSA-SHA3-224"
+	Sig_ECDSA_SHA3_256     SignatureIdentifier = "ECDSA-SHA3-256"
+	Sig_ECDSA_SHA3_384     SignatureIdentifier = "ECDSA-SHA3-384"
+	Sig_ECDSA_SHA3_512     SignatureIdentifier = "ECDSA-SHA3-512"
+	Sig_ECDSA_KECCAK256    SignatureIdentifier = "ECDSA-KECCAK256"
+	Sig_ECDSA_BLAKE2B512   SignatureIdentifier = "ECDSA-BLAKE2B512"
+	Sig_ECDSA_BLAKE2S256   SignatureIdentifier = "ECDSA-BLAKE2S256"
+	Sig_ECDSA_DOUBLESHA256 SignatureIdentifier = "ECDSA-DOUBLESHA256"
+	Sig_ECDSA_STARK        SignatureIdentifier = "ECDSA-STARK"
+
+	Sig_Ed25519        SignatureIdentifier = "Ed25519"
+	Sig_SCHNORR_BIP340 SignatureIdentifier = "SCHNORR-BIP340"
+	Sig_SM2_SM3        SignatureIdentifier = "SM2-SM3"
+
+	Sig_DSA_SHA1   SignatureIdentifier = "DSA-SHA1"
+	Sig_DSA_SHA224 SignatureIdentifier = "DSA-SHA224"
+	Sig_DSA_SHA256 SignatureIdentifier = "DSA-SHA256"
+	Sig_DSA_SHA384 SignatureIdentifier = "DSA-SHA384"
+	Sig_DSA_SHA512 SignatureIdentifier = "DSA-SHA512"
+
+	Sig_GOST_STREEBOG256 SignatureIdentifier = "GOST-STREEBOG256"
+	Sig_GOST_STREEBOG512 SignatureIdentifier = "GOST-STREEBOG512"
 )
 
-func (s SignatureIdentifier) Hash() hash.Hash {
-	switch s {
-	case Sig_ECDSA_SHA256:
-		return sha256.New()
+type sigFamily int
+
+const (
+	familyECDSA sigFamily = iota
+	familyDSA
+	familyEdDSA
+	familySchnorr
+	familySM2
+	familyGOST
+)
 
-	case Sig_ECDSA_SHA512:
-		return sha512.New()
+// sigInfo is a signature registry entry. hash is nil for ECDSA-STARK, whose messages are already
+// field elements.
+type sigInfo struct {
+	id     SignatureIdentifier
+	family sigFamily
+	hash   func() hash.Hash
+}
 
-	case Sig_ECDSA_KECCAK256:
-		return sha3.NewLegacyKeccak256()
+var sigRegistry = []sigInfo{
+	{Sig_ECDSA_SHA1, familyECDSA, newSHA1},
+	{Sig_ECDSA_SHA224, familyECDSA, newSHA224},
+	{Sig_ECDSA_SHA256, familyECDSA, newSHA256},
+	{Sig_ECDSA_SHA384, familyECDSA, newSHA384},
+	{Sig_ECDSA_SHA512, familyECDSA, newSHA512},
+	{Sig_ECDSA_SHA512_256, familyECDSA, newSHA512_256},
+	{Sig_ECDSA_SHA3_224, familyECDSA, newSHA3_224},
+	{Sig_ECDSA_SHA3_256, familyECDSA, newSHA3_256},
+	{Sig_ECDSA_SHA3_384, familyECDSA, newSHA3_384},
+	{Sig_ECDSA_SHA3_512, familyECDSA, newSHA3_512},
+	{Sig_ECDSA_KECCAK256, familyECDSA, newKeccak256},
+	{Sig_ECDSA_BLAKE2B512, familyECDSA, newBLAKE2b512},
+	{Sig_ECDSA_BLAKE2S256, familyECDSA, newBLAKE2s256},
+	{Sig_ECDSA_DOUBLESHA256, familyECDSA, newDoubleSHA256},
+	{Sig_ECDSA_STARK, familyECDSA, nil},
+
+	{Sig_Ed25519, familyEdDSA, newSHA512},
+	{Sig_SCHNORR_BIP340, familySchnorr, newSHA256},
+	{Sig_SM2_SM3, familySM2, newSM3},
+
+	{Sig_DSA_SHA1, familyDSA, newSHA1},
+	{Sig_DSA_SHA224, familyDSA, newSHA224},
+	{Sig_DSA_SHA256, familyDSA, newSHA256},
+	{Sig_DSA_SHA384, familyDSA, newSHA384},
+	{Sig_DSA_SHA512, familyDSA, newSHA512},
+
+	{Sig_GOST_STREEBOG256, familyGOST, newStreebog256},
+	{Sig_GOST_STREEBOG512, familyGOST, newStreebog512},
+}
 
-	default:
-		panic("not defined")
+func (s SignatureIdentifier) info() *sigInfo {
+	for i := range sigRegistry {
+		if sigRegistry[i].id == s {
+			return &sigRegistry[i]
+		}
 	}
+
+	return nil
 }
 
-func NewSignatureIdentifier(id string) (SignatureIdentifier, error) {
-	switch id {
-	case string(Sig_ECDSA_SHA256):
-		return Sig_ECDSA_SHA256, nil
+// Signatures returns the identifiers of every supported signature type.
+func Signatures() []SignatureIdentifier {
+	ids := make([]SignatureIdentifier, len(sigRegistry))
+	for i, info := range sigRegistry {
+		ids[i] = info.id
+	}
+	return ids
+}
 
-	case string(Sig_ECDSA_SHA512):
-		return Sig_ECDSA_SHA512, nil
+func (s SignatureIdentifier) family() sigFamily {
+	info := s.info()
+	if info == nil {
+		panic("should be unreachable")
+	}
 
-	case string(Sig_ECDSA_KECCAK256):
-		return Sig_ECDSA_KECCAK256, nil
+	return info.family
+}
 
-	default:
-		return "", fmt.Errorf("unsupported signature identifier: %s", id)
+// Hash returns the hash the signature type hashes messages with. It panics for ECDSA-STARK since its
+// messages are already field elements.
+func (s SignatureIdentifier) Hash() hash.Hash {
+	info := s.info()
+	if info == nil || info.hash == nil {
+		panic("not defined")
 	}
+
+	return info.hash()
 }
 
+// hashBits returns the digest size of the signature's hash, or false for signatures which sign
+// messages directly.
+func (s SignatureIdentifier) hashBits() (int, bool) {
+	info := s.info()
+	if info == nil || info.hash == nil {
+		return 0, false
+	}
+
+	return info.hash().Size() * 8, true
+}
+
+// NewSignatureIdentifier looks up a signature type by its identifier, ignoring case.
+func NewSignatureIdentifier(id string) (SignatureIdentifier, error) {
+	for _, info := range sigRegistry {
+		if strings.EqualFold(id, string(info.id)) {
+			return info.id, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported signature identifier: %s", id)
+}
+
+// Serialization formats for a signature along with its public key and message.
+const (
+	// Format_PubSigMsg is the public key followed by the signature and then the message.
+	Format_PubSigMsg = "pub||sig||msg"
+	// Format_SigMsg omits the public key, which has to be recovered from the signatures.
+	Format_SigMsg = "sig||msg"
+)
+
 type Signature struct {
+	// Pub is nil if the signature was parsed without one and it hasn't been recovered yet.
 	Pub []byte
 	Sig []byte
 	Msg []byte
@@ -64,13 +173,38 @@ func (s *Signature) Bytes() []byte {
 	return out
 }
 
-func SignatureFromBytes(data []byte, curve elliptic.Curve, format string) (*Signature, error) {
-	byteLen := byteLen(curve)
+// R returns the first half of the signature, which for most schemes is the r component. That's
+// derived only from the nonce and so is shared by all signatures that reused it. GOST puts r
+// second, so use Config.NonceCommitment for a value that works with every scheme.
+func (s *Signature) R() []byte {
+	return s.Sig[:len(s.Sig)/2]
+}
 
-	// TODO: leverage format param
-	pubBytes := data[:byteLen*2]
-	sigBytes := data[byteLen*2 : byteLen*4]
-	msg := data[byteLen*4:]
+func SignatureFromBytes(data []byte, curveID CurveIdentifier, sigID SignatureIdentifier, format string) (*Signature, error) {
+	scheme, err := newScheme(curveID, sigID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	pubLen, sigLen := scheme.pubLen(), scheme.sigLen()
+	switch format {
+	case Format_PubSigMsg, "r||s":
+		// r||s was the original name for the only format, which included the public key.
+	case Format_SigMsg:
+		pubLen = 0
+	default:
+		return nil, fmt.Errorf("unsupported signature format: %s", format)
+	}
+
+	if len(data) < pubLen+sigLen {
+		return nil, fmt.Errorf("signature data too short: %d bytes", len(data))
+	}
+	var pubBytes []byte
+	if pubLen > 0 {
+		pubBytes = data[:pubLen]
+	}
+	sigBytes := data[pubLen : pubLen+sigLen]
+	msg := data[pubLen+sigLen:]
 
 	return &Signature{
 		Pub: pubBytes,
@@ -0,0 +1,99 @@
+package recovery
+
+import (
+	"fmt"
+	"math/big"
+)
+
+/*
+* Discrete logs on singular curves, y^2 = x^3 + a·x + b with 4a^3 + 27b^2 = 0. The cubic has a double
+* root α and the point (α, 0) is singular, but the rest of the points still form a group under the
+* usual addition formulas and it's isomorphic to a group of the field:
+*
+*   - a cusp, y^2 = x^3, maps to GF(p)+ by (x, y) -> x/y, so d is a division.
+*   - a node, y^2 = X^2·(X + c) for X = x - α and c = 3α, maps to the multiplicative group by
+*     (x, y) -> (y + s·X)/(y - s·X) where s^2 = c. The tangents at the node have slopes ±s, which are
+*     in GF(p) for a split node and in GF(p^2) otherwise. The DLP is then solved there with
+*     Pohlig–Hellman, as the orders p - 1 and p + 1 are usually smooth when someone picks a
+*     singular curve on purpose.
+ */
+
+// singularity returns "cusp", "split node" or "non-split node" for a singular curve and an empty
+// string otherwise.
+func (c *weierstrassCurve) singularity() string {
+	p := c.params.P
+	disc := new(big.Int).Exp(c.a, big.NewInt(3), p)
+	disc.Mul(disc, big.NewInt(4))
+	disc.Add(disc, new(big.Int).Mul(big.NewInt(27), new(big.Int).Mul(c.params.B, c.params.B)))
+	switch {
+	case disc.Mod(disc, p).Sign() != 0:
+		return ""
+	case c.a.Sign() == 0:
+		return "cusp"
+	case big.Jacobi(c.nodeSlopeSquare(), p) == 1:
+		return "split node"
+	default:
+		return "non-split node"
+	}
+}
+
+// singularX returns the double root α of x^3 + a·x + b, which is -3b/2a.
+func (c *weierstrassCurve) singularX() *big.Int {
+	p := c.params.P
+	if c.a.Sign() == 0 {
+		return new(big.Int)
+	}
+
+	alpha := new(big.Int).Mul(big.NewInt(-3), c.params.B)
+	alpha.Mul(alpha, new(big.Int).ModInverse(new(big.Int).Lsh(c.a, 1), p))
+	return alpha.Mod(alpha, p)
+}
+
+// nodeSlopeSquare returns c = 3α, the square of the slopes of the tangents at the node.
+func (c *weierstrassCurve) nodeSlopeSquare() *big.Int {
+	slope := new(big.Int).Mul(big.NewInt(3), c.singularX())
+	return slope.Mod(slope, c.params.P)
+}
+
+// singularDiscreteLog returns d such that d·G = Q on a singular curve.
+func singularDiscreteLog(curve *weierstrassCurve, qx, qy *big.Int, analysis *CurveAnalysis) (*big.Int, error) {
+	params := curve.params
+	p := params.P
+	if qy.Sign() == 0 && qx.Cmp(curve.singularX()) == 0 {
+		return nil, fmt.Errorf("public key is the singular point")
+	}
+
+	if analysis.Singular == "cusp" {
+		// x/y for G and Q, whose ratio is d.
+		tg := new(big.Int).Mul(params.Gx, new(big.Int).ModInverse(params.Gy, p))
+		tq := new(big.Int).Mul(qx, new(big.Int).ModInverse(qy, p))
+		d := tq.Mul(tq, tg.ModInverse(tg.Mod(tg, p), p))
+		return d.Mod(d, p), nil
+	}
+
+	var field *gfpk
+	var s element
+	if analysis.Singular == "split node" {
+		field = newGFp(p)
+		s = element{new(big.Int).ModSqrt(curve.nodeSlopeSquare(), p)}
+	} else {
+		field = newGFp2(p, curve.nodeSlopeSquare())
+		s = element{new(big.Int), big.NewInt(1)}
+	}
+
+	alpha := curve.singularX()
+	toField := func(x, y *big.Int) element {
+		// (y + s·X)/(y - s·X)
+		sx := field.scale(s, new(big.Int).Sub(x, alpha))
+		num := field.add(field.fromInt(y), sx)
+		den := field.sub(field.fromInt(y), sx)
+		return field.Op(num, field.Inverse(den))
+	}
+
+	g, h := toField(params.Gx, params.Gy), toField(qx, qy)
+	d, err := pohligHellman(field, g, h, params.N, analysis.Factors)
+	if err != nil {
+		return nil, fmt.Errorf("%w, order factorization: %s", err, FormatFactorization(analysis.Factors))
+	}
+	return d, nil
+}
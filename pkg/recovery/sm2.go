@@ -0,0 +1,208 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+/*
+* SM2 signatures (GB/T 32918.2-2016) over the SM2P256 curve with SM3. The message is hashed along
+* with Z_A, a hash of the signer's distinguishing ID, the curve parameters and their public key, so
+* e = SM3(Z_A || M). Signatures are r || s with r = e + x_1 mod n, where (x_1, y_1) = k·G, and
+* s = (1+d)⁻¹(k − r·d) mod n. That rearranges to k = s + (s+r)·d, which is linear in d just like
+* ECDSA. The x coordinate of the nonce point is r − e rather than r itself, so that's what has to
+* be compared to detect reuse.
+*
+* Signers with a distinguishing ID other than SM2DefaultID need it set with Config.SetSM2ID, since
+* Z_A and so every digest depends on it.
+ */
+
+// SM2DefaultID is the distinguishing ID the standard recommends, and the one nearly every
+// implementation uses, when the signer doesn't have one of its own.
+const SM2DefaultID = "1234567812345678"
+
+// checkSM2ID checks that the distinguishing ID's length fits Z_A, which hashes it in as a 16-bit
+// count of bits.
+func checkSM2ID(id string) error {
+	if len(id)*8 > 0xffff {
+		return fmt.Errorf("SM2 distinguishing ID must be at most %d bytes", 0xffff/8)
+	}
+	return nil
+}
+
+var (
+	initSM2P256 sync.Once
+	sm2P256     *elliptic.CurveParams
+)
+
+func sm2p256() *elliptic.CurveParams {
+	initSM2P256.Do(func() {
+		sm2P256 = &elliptic.CurveParams{Name: "SM2P256", BitSize: 256}
+		sm2P256.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+		sm2P256.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+		sm2P256.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+		sm2P256.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+		sm2P256.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+	})
+
+	return sm2P256
+}
+
+type sm2Scheme struct {
+	curve elliptic.Curve
+	id    []byte
+}
+
+func (s *sm2Scheme) order() *big.Int {
+	return s.curve.Params().N
+}
+
+func (s *sm2Scheme) pubLen() int {
+	return byteLen(s.curve) * 2
+}
+
+func (s *sm2Scheme) sigLen() int {
+	return byteLen(s.curve) * 2
+}
+
+func (s *sm2Scheme) generateKey(rand io.Reader) (*big.Int, error) {
+	// d = n-1 is excluded since 1+d must be invertible.
+	for {
+		d, err := randScalar(s.order())
+		if err != nil {
+			return nil, err
+		}
+		if new(big.Int).Add(d, one).Cmp(s.order()) != 0 {
+			return d, nil
+		}
+	}
+}
+
+func (s *sm2Scheme) publicKey(d *big.Int) []byte {
+	x, y := s.curve.ScalarBaseMult(d.Bytes())
+	byteLen := byteLen(s.curve)
+
+	out := make([]byte, byteLen*2)
+	copy(out, leftPad(x.Bytes(), byteLen))
+	copy(out[byteLen:], leftPad(y.Bytes(), byteLen))
+	return out
+}
+
+// za computes Z_A = SM3(ENTL || ID || a || b || G.x || G.y || P.x || P.y) where ENTL is the bit
+// length of the ID as two bytes.
+func (s *sm2Scheme) za(pub []byte) []byte {
+	params := s.curve.Params()
+	byteLen := byteLen(s.curve)
+	a := curveA(params)
+
+	h := newSM3()
+	var entl [2]byte
+	binary.BigEndian.PutUint16(entl[:], uint16(len(s.id)*8))
+	h.Write(entl[:])
+	h.Write(s.id)
+	h.Write(leftPad(a.Bytes(), byteLen))
+	h.Write(leftPad(params.B.Bytes(), byteLen))
+	h.Write(leftPad(params.Gx.Bytes(), byteLen))
+	h.Write(leftPad(params.Gy.Bytes(), byteLen))
+	h.Write(pub)
+	return h.Sum(nil)
+}
+
+// digest returns e = SM3(Z_A || M) as an integer.
+func (s *sm2Scheme) digest(pub, msg []byte) *big.Int {
+	h := newSM3()
+	h.Write(s.za(pub))
+	h.Write(msg)
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+func (s *sm2Scheme) sign(d, k *big.Int, msg []byte) ([]byte, error) {
+	n := s.order()
+	e := s.digest(s.publicKey(d), msg)
+
+	// r = e + x_1 and s = (1+d)⁻¹(k − r·d)
+	x1, _ := s.curve.ScalarBaseMult(k.Bytes())
+	r := new(big.Int).Add(e, x1)
+	r.Mod(r, n)
+	if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	dInv := new(big.Int).Add(d, one)
+	if dInv.ModInverse(dInv, n) == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	sig := new(big.Int).Mul(r, d)
+	sig.Sub(k, sig)
+	sig.Mul(sig, dInv)
+	sig.Mod(sig, n)
+	if sig.Sign() == 0 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	byteLen := byteLen(s.curve)
+	out := make([]byte, byteLen*2)
+	copy(out, leftPad(r.Bytes(), byteLen))
+	copy(out[byteLen:], leftPad(sig.Bytes(), byteLen))
+	return out, nil
+}
+
+func (s *sm2Scheme) verify(sig *Signature) bool {
+	n := s.order()
+	byteLen := byteLen(s.curve)
+	if len(sig.Pub) != 2*byteLen || len(sig.Sig) != 2*byteLen {
+		return false
+	}
+	px := new(big.Int).SetBytes(sig.Pub[:byteLen])
+	py := new(big.Int).SetBytes(sig.Pub[byteLen:])
+	if !s.curve.IsOnCurve(px, py) {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	sv := new(big.Int).SetBytes(sig.Sig[byteLen:])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || sv.Sign() == 0 || sv.Cmp(n) >= 0 {
+		return false
+	}
+	t := new(big.Int).Add(r, sv)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+
+	// (x_1, y_1) = s·G + t·P and e + x_1 must equal r
+	x1, y1 := s.curve.ScalarBaseMult(sv.Bytes())
+	x2, y2 := s.curve.ScalarMult(px, py, t.Bytes())
+	if x1.Cmp(x2) == 0 {
+		return false
+	}
+	x, _ := s.curve.Add(x1, y1, x2, y2)
+	x.Add(x, s.digest(sig.Pub, sig.Msg))
+
+	return x.Mod(x, n).Cmp(r) == 0
+}
+
+func (s *sm2Scheme) nonceRelation(sig *Signature) (*big.Int, *big.Int, error) {
+	n := s.order()
+	byteLen := byteLen(s.curve)
+	r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	sv := new(big.Int).SetBytes(sig.Sig[byteLen:])
+
+	// s(1+d) = k − r·d so k = s + (s+r)·d
+	beta := new(big.Int).Add(sv, r)
+	return sv.Mod(sv, n), beta.Mod(beta, n), nil
+}
+
+// nonceCommitment returns x_1 mod n = r − e, which is the same for every signature with a nonce.
+func (s *sm2Scheme) nonceCommitment(sig *Signature) []byte {
+	byteLen := byteLen(s.curve)
+	r := new(big.Int).SetBytes(sig.Sig[:byteLen])
+	r.Sub(r, s.digest(sig.Pub, sig.Msg))
+	r.Mod(r, s.order())
+
+	return leftPad(r.Bytes(), byteLen)
+}
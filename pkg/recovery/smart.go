@@ -0,0 +1,175 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+)
+
+/*
+* Smart's attack on anomalous curves, where the order of G is the prime p of the field. The curve and
+* both points are lifted to the p-adic integers, where multiplying a point by p lands it in the kernel
+* of reduction mod p. The kernel is isomorphic to the additive group p·Z_p through the p-adic
+* elliptic logarithm, which is -x/y to first order, so the discrete log of Q is the ratio of the
+* logarithms of p·Q and p·G. Only the first p-adic digit is needed, so it's all computed mod p^2.
+*
+* The attack fails when the lift happens to be the canonical lift, in which the points of order p
+* lift to points of order p, so a few lifts of a are tried.
+ */
+
+// smartLifts is how many lifts of the curve are tried.
+const smartLifts = 4
+
+// smartAttack returns d such that d·G = Q on an anomalous curve y^2 = x^3 + a·x + b.
+func smartAttack(curve elliptic.Curve, a, qx, qy *big.Int) (*big.Int, error) {
+	params := curve.Params()
+	p := params.P
+	if params.N.Cmp(p) != 0 {
+		return nil, fmt.Errorf("the curve isn't anomalous")
+	}
+
+	for i := int64(0); i < smartLifts; i++ {
+		lifted := &liftedCurve{
+			p:  p,
+			p2: new(big.Int).Mul(p, p),
+			a:  new(big.Int).Add(a, new(big.Int).Mul(big.NewInt(i), p)),
+			b:  params.B,
+		}
+
+		logG := lifted.log(lifted.scalarMult(lifted.lift(params.Gx, params.Gy), p))
+		logQ := lifted.log(lifted.scalarMult(lifted.lift(qx, qy), p))
+		if logG == nil || logQ == nil || logG.Sign() == 0 {
+			continue
+		}
+
+		d := logQ.Mul(logQ, logG.ModInverse(logG, p))
+		d.Mod(d, p)
+		if x, y := curve.ScalarBaseMult(d.Bytes()); x.Cmp(qx) == 0 && y.Cmp(qy) == 0 {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Smart's attack failed for %d lifts of the curve", smartLifts)
+}
+
+// liftedCurve is y^2 = x^3 + a·x + b over Z/p^2. Points use Jacobian coordinates, (X, Y, Z) for
+// (X/Z^2, Y/Z^3), so that those in the kernel of reduction, with Z divisible by p, don't need an
+// inverse.
+type liftedCurve struct {
+	p, p2 *big.Int
+	a, b  *big.Int
+}
+
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+// lift returns the point over Z/p^2 which reduces to (x, y) with the same x coordinate, using
+// Hensel's lemma for y.
+func (c *liftedCurve) lift(x, y *big.Int) *jacobianPoint {
+	// (y + k·p)^2 = x^3 + a·x + b mod p^2 for k = (x^3 + a·x + b - y^2)/p · (2·y)⁻¹ mod p
+	k := new(big.Int).Exp(x, big.NewInt(3), c.p2)
+	k.Add(k, new(big.Int).Mul(c.a, x))
+	k.Add(k, c.b)
+	k.Sub(k, new(big.Int).Mul(y, y))
+	k.Mod(k, c.p2)
+	k.Div(k, c.p)
+	k.Mul(k, new(big.Int).ModInverse(new(big.Int).Lsh(y, 1), c.p))
+	k.Mod(k, c.p)
+
+	ly := k.Mul(k, c.p)
+	ly.Add(ly, y)
+	return &jacobianPoint{x: new(big.Int).Set(x), y: ly, z: big.NewInt(1)}
+}
+
+// log returns the first p-adic digit of the elliptic logarithm, -X·Z/Y divided by p, of a point in
+// the kernel of reduction or nil if it isn't in the kernel.
+func (c *liftedCurve) log(pt *jacobianPoint) *big.Int {
+	if new(big.Int).Mod(pt.z, c.p).Sign() != 0 {
+		return nil
+	}
+	yInv := new(big.Int).ModInverse(pt.y, c.p2)
+	if yInv == nil {
+		return nil
+	}
+
+	t := new(big.Int).Mul(pt.x, pt.z)
+	t.Mul(t, yInv)
+	t.Neg(t)
+	t.Mod(t, c.p2)
+	return t.Div(t, c.p)
+}
+
+func (c *liftedCurve) scalarMult(pt *jacobianPoint, k *big.Int) *jacobianPoint {
+	// k is never a multiple of the order of the reduced point before the last step, so the
+	// intermediate points are never the point at infinity.
+	r := pt
+	for i := k.BitLen() - 2; i >= 0; i-- {
+		r = c.double(r)
+		if k.Bit(i) == 1 {
+			r = c.add(r, pt)
+		}
+	}
+	return r
+}
+
+func (c *liftedCurve) double(pt *jacobianPoint) *jacobianPoint {
+	// M = 3·X^2 + a·Z^4, S = 4·X·Y^2
+	// X' = M^2 - 2·S, Y' = M·(S - X') - 8·Y^4, Z' = 2·Y·Z
+	yy := new(big.Int).Mul(pt.y, pt.y)
+	zz := new(big.Int).Mul(pt.z, pt.z)
+	m := new(big.Int).Mul(pt.x, pt.x)
+	m.Mul(m, big.NewInt(3))
+	m.Add(m, zz.Mul(zz.Mul(zz, zz), c.a))
+	s := new(big.Int).Mul(pt.x, yy)
+	s.Lsh(s, 2)
+
+	x := new(big.Int).Mul(m, m)
+	x.Sub(x, new(big.Int).Lsh(s, 1))
+	x.Mod(x, c.p2)
+	y := s.Sub(s, x)
+	y.Mul(y, m)
+	y.Sub(y, yy.Lsh(yy.Mul(yy, yy), 3))
+	y.Mod(y, c.p2)
+	z := new(big.Int).Mul(pt.y, pt.z)
+	z.Lsh(z, 1)
+	z.Mod(z, c.p2)
+
+	return &jacobianPoint{x: x, y: y, z: z}
+}
+
+func (c *liftedCurve) add(p1, p2 *jacobianPoint) *jacobianPoint {
+	// U1 = X1·Z2^2, U2 = X2·Z1^2, S1 = Y1·Z2^3, S2 = Y2·Z1^3, H = U2 - U1, R = S2 - S1
+	// X' = R^2 - H^3 - 2·U1·H^2, Y' = R·(U1·H^2 - X') - S1·H^3, Z' = Z1·Z2·H
+	z1z1 := new(big.Int).Mul(p1.z, p1.z)
+	z2z2 := new(big.Int).Mul(p2.z, p2.z)
+	u1 := new(big.Int).Mul(p1.x, z2z2)
+	u2 := new(big.Int).Mul(p2.x, z1z1)
+	s1 := new(big.Int).Mul(p1.y, z2z2.Mul(z2z2, p2.z))
+	s2 := new(big.Int).Mul(p2.y, z1z1.Mul(z1z1, p1.z))
+	h := u2.Sub(u2, u1)
+	h.Mod(h, c.p2)
+	r := s2.Sub(s2, s1)
+	r.Mod(r, c.p2)
+	if h.Sign() == 0 && r.Sign() == 0 {
+		return c.double(p1)
+	}
+
+	hh := new(big.Int).Mul(h, h)
+	hhh := new(big.Int).Mul(hh, h)
+	u1hh := u1.Mul(u1, hh)
+
+	x := new(big.Int).Mul(r, r)
+	x.Sub(x, hhh)
+	x.Sub(x, new(big.Int).Lsh(u1hh, 1))
+	x.Mod(x, c.p2)
+	y := u1hh.Sub(u1hh, x)
+	y.Mul(y, r)
+	y.Sub(y, s1.Mul(s1, hhh))
+	y.Mod(y, c.p2)
+	z := new(big.Int).Mul(p1.z, p2.z)
+	z.Mul(z, h)
+	z.Mod(z, c.p2)
+
+	return &jacobianPoint{x: x, y: y, z: z}
+}
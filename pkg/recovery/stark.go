@@ -0,0 +1,51 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+/*
+* The STARK curve y^2 = x^3 + x + β over the 252 bit prime 2^251 + 17·2^192 + 1 used by StarkNet and
+* StarkEx. Signatures are plain ECDSA but messages are already field elements, usually Pedersen or
+* Poseidon hashes, so they are used directly as z rather than hashed again.
+ */
+
+var (
+	initSTARK  sync.Once
+	starkCurve *weierstrassCurve
+)
+
+func stark() *weierstrassCurve {
+	initSTARK.Do(func() {
+		p := new(big.Int).Lsh(one, 251)
+		p.Add(p, new(big.Int).Lsh(big.NewInt(17), 192))
+		p.Add(p, one)
+
+		params := &elliptic.CurveParams{Name: "STARK", BitSize: 252, P: p}
+		params.N, _ = new(big.Int).SetString("0800000000000010ffffffffffffffffb781126dcae7b2321e66a241adc64d2f", 16)
+		params.B, _ = new(big.Int).SetString("06f21413efbe40de150e596d72f7a8c5609ad26c15c915c1f4cdfcb99cee9e89", 16)
+		params.Gx, _ = new(big.Int).SetString("01ef15c18599971b7beced415a40f0c7deacfd9b0d1819e03d723d8bc943cfca", 16)
+		params.Gy, _ = new(big.Int).SetString("005668060aa49730b7be4801df46ec62de53ecd11abe43a32873000c36e8dc1f", 16)
+
+		starkCurve = newWeierstrassCurve(params, one)
+	})
+
+	return starkCurve
+}
+
+// starkMaxValue is the exclusive bound StarkEx places on message hashes, r and s⁻¹.
+var starkMaxValue = new(big.Int).Lsh(one, 251)
+
+// exampleMessage returns the message the generators sign for the example text. It's the text itself
+// except for STARK, where the text is hashed into a field element like the real messages are.
+func exampleMessage(scheme scheme, text string) []byte {
+	if s, ok := scheme.(*ecdsaScheme); !ok || s.sigID != Sig_ECDSA_STARK {
+		return []byte(text)
+	}
+
+	h := sha256Bytes([]byte(text))
+	h[0] &= 0x07 // keep 251 bits
+	return h
+}
@@ -0,0 +1,258 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+)
+
+/*
+* Deterministic signatures always use the same nonce for a message, so a glitched signature next to a
+* correct one over the same message shares its nonce and the difference between them isolates the
+* fault. The fault locations modelled here are:
+*
+*   - a faulty r (or R for EdDSA): the faulty value is part of the signature so both signatures give
+*     a nonce relation for the same k and it's plain nonce reuse.
+*   - a faulty ECDSA hash: s' = k⁻¹(z' + r·d) where z' differs from z in one bit, so each candidate
+*     z' gives k = (z' - z)/(s' - s).
+*   - a faulty private key while computing the ECDSA s: s' = k⁻¹(z + r·d') where d' differs from d
+*     in one bit, so each candidate bit and direction gives k = ±r·2^i/(s' - s).
+*
+* Every candidate key is checked against the public key, so it doesn't matter which signature of a
+* pair is the faulty one.
+ */
+
+type FaultStrategy struct {
+	curveID CurveIdentifier
+	sigID   SignatureIdentifier
+	sm2ID   string
+}
+
+func (s *FaultStrategy) Recover(signatures []*Signature) (*PrivateKey, error) {
+	if len(signatures) < 2 {
+		return nil, fmt.Errorf("must have at least two signatures for fault recovery")
+	}
+
+	switch s.sigID.family() {
+	case familyECDSA, familyEdDSA:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Try every pair of distinct signatures over the same message by the same key. A faulty
+		// signature without a public key, which recovering signers can't attribute, is tried with
+		// the key of the other signature of the pair.
+		for i, sig1 := range signatures {
+			for _, sig2 := range signatures[i+1:] {
+				if !bytes.Equal(sig1.Msg, sig2.Msg) || bytes.Equal(sig1.Sig, sig2.Sig) {
+					continue
+				}
+				pub := sig1.Pub
+				if pub == nil {
+					pub = sig2.Pub
+				} else if sig2.Pub != nil && !bytes.Equal(sig1.Pub, sig2.Pub) {
+					continue
+				}
+				if pub == nil {
+					continue
+				}
+
+				if priv := recoverFaultPair(scheme, sig1, sig2, pub); priv != nil {
+					return priv, nil
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("failed to recover private key, no pair matched a fault model")
+
+	default:
+		return nil, fmt.Errorf("fault recovery for %s not implemented", s.sigID)
+	}
+}
+
+// recoverFaultPair tries each of the fault models on a pair of signatures over the same message by
+// the key pub, returning nil if none of them give the private key.
+func recoverFaultPair(scheme scheme, sig1, sig2 *Signature, pub []byte) *PrivateKey {
+	n := scheme.order()
+	check := func(d *big.Int) *PrivateKey {
+		d.Mod(d, n)
+		if d.Sign() == 0 {
+			return nil
+		}
+		priv := newPrivateKey(scheme, d)
+		if !bytes.Equal(priv.Pub, pub) {
+			return nil
+		}
+		return priv
+	}
+
+	// A faulty r is in the signature, so both relations hold for the same nonce.
+	a1, b1, err1 := scheme.nonceRelation(sig1)
+	a2, b2, err2 := scheme.nonceRelation(sig2)
+	if err1 == nil && err2 == nil {
+		aDiff := new(big.Int).Sub(a1, a2)
+		bDiff := new(big.Int).Sub(b2, b1)
+		if bDiffInv := new(big.Int).ModInverse(bDiff.Mod(bDiff, n), n); bDiffInv != nil {
+			if priv := check(aDiff.Mul(aDiff, bDiffInv)); priv != nil {
+				return priv
+			}
+		}
+	}
+
+	ecdsa, ok := scheme.(*ecdsaScheme)
+	if !ok || !bytes.Equal(sig1.R(), sig2.R()) {
+		return nil
+	}
+
+	byteLen := byteLen(ecdsa.curve)
+	r := new(big.Int).SetBytes(sig1.Sig[:byteLen])
+	rInv := new(big.Int).ModInverse(r, n)
+	z := ecdsa.digest(sig1.Msg)
+	if rInv == nil {
+		return nil
+	}
+
+	// Either signature may be the faulty one.
+	for _, pair := range [][2]*Signature{{sig1, sig2}, {sig2, sig1}} {
+		good := new(big.Int).SetBytes(pair[0].Sig[byteLen:])
+		bad := new(big.Int).SetBytes(pair[1].Sig[byteLen:])
+		sDiffInv := new(big.Int).Sub(bad, good)
+		if sDiffInv.ModInverse(sDiffInv.Mod(sDiffInv, n), n) == nil {
+			continue
+		}
+
+		// d = (s·k - z)/r for the nonce each candidate fault implies.
+		keyFromNonce := func(k *big.Int) *big.Int {
+			d := new(big.Int).Mul(good, k)
+			d.Sub(d, z)
+			return d.Mul(d, rInv)
+		}
+
+		for bit := 0; bit < n.BitLen(); bit++ {
+			flip := new(big.Int).Lsh(one, uint(bit))
+
+			// Faulty hash: k = (z' - z)/(s' - s)
+			k := new(big.Int).Xor(z, flip)
+			k.Sub(k, z)
+			k.Mul(k, sDiffInv)
+			if priv := check(keyFromNonce(k)); priv != nil {
+				return priv
+			}
+
+			// Faulty key: k = ±r·2^i/(s' - s) depending on whether the bit was set.
+			k = new(big.Int).Mul(r, flip)
+			k.Mul(k, sDiffInv)
+			if priv := check(keyFromNonce(k)); priv != nil {
+				return priv
+			}
+			if priv := check(keyFromNonce(k.Neg(k))); priv != nil {
+				return priv
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *FaultStrategy) Generate() ([]*Signature, error) {
+	// The generator derives nonces with RFC 6979 so it needs a message hash, which rules out
+	// ECDSA-STARK.
+	switch {
+	case s.sigID.family() == familyECDSA && s.sigID != Sig_ECDSA_STARK:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+		ecdsa := scheme.(*ecdsaScheme)
+
+		key, err := scheme.generateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		pub := scheme.publicKey(key)
+		n := scheme.order()
+
+		// A correct and faulty signature pair for each fault location, each over its own message.
+		locations := []faultLocation{faultR, faultHash, faultKey}
+		sigs := make([]*Signature, 0, len(locations)*2)
+		for i, location := range locations {
+			m := []byte(fmt.Sprintf("example faulty sig #%d", i+1))
+			nonce := rfc6979Nonce(key, n, hashBytes(s.sigID.Hash(), m), s.sigID.Hash)
+
+			bit, err := rand.Int(rand.Reader, big.NewInt(int64(n.BitLen()-1)))
+			if err != nil {
+				return nil, err
+			}
+			fault := &ecdsaFault{location: location, bit: uint(bit.Int64())}
+
+			good, err := ecdsa.sign(key, nonce, m)
+			if err != nil {
+				return nil, err
+			}
+			bad, err := ecdsa.signWithFault(key, nonce, m, fault)
+			if err != nil {
+				return nil, err
+			}
+
+			sigs = append(sigs,
+				&Signature{Pub: pub, Msg: m, Sig: good},
+				&Signature{Pub: pub, Msg: m, Sig: bad},
+			)
+		}
+
+		return sigs, nil
+
+	case s.sigID == Sig_Ed25519:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+		eddsa := scheme.(*eddsaScheme)
+
+		key, err := scheme.generateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		pub := scheme.publicKey(key)
+
+		// Derive the nonce from a secret prefix and the message like RFC 8032.
+		m := []byte("example faulty sig")
+		prefix := make([]byte, 32)
+		if _, err := rand.Read(prefix); err != nil {
+			return nil, err
+		}
+		h := sha512.Sum512(append(prefix, m...))
+		nonce := new(big.Int).SetBytes(reverse(h[:]))
+		nonce.Mod(nonce, scheme.order())
+
+		good, err := eddsa.sign(key, nonce, m)
+		if err != nil {
+			return nil, err
+		}
+
+		// Simulate a glitch in the scalar multiplication for R which leaves it off by the base
+		// point, with S computed as usual from the faulty R.
+		curve := eddsa.curve
+		rx, ry := curve.ScalarBaseMult(nonce.Bytes())
+		R := curve.encodePoint(curve.Add(rx, ry, curve.Params().Gx, curve.Params().Gy))
+		S := eddsa.challenge(R, pub, m)
+		S.Mul(S, key)
+		S.Add(S, nonce)
+		S.Mod(S, scheme.order())
+
+		bad := make([]byte, 64)
+		copy(bad, R)
+		copy(bad[32:], reverse(leftPad(S.Bytes(), 32)))
+
+		return []*Signature{
+			{Pub: pub, Msg: m, Sig: good},
+			{Pub: pub, Msg: m, Sig: bad},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("gen fault not supported for sig type")
+	}
+}
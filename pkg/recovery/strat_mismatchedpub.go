@@ -0,0 +1,143 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+)
+
+/*
+* Ed25519 derives its nonce deterministically from the secret and the message, but the challenge
+* also commits to the public key. Libraries which let callers pass the public key separately from
+* the secret will therefore produce two signatures with the same nonce and different challenges
+* when the same message is signed under two claimed public keys, which is just nonce reuse.
+ */
+
+type MismatchedPubStrategy struct {
+	curveID CurveIdentifier
+	sigID   SignatureIdentifier
+	sm2ID   string
+}
+
+func (s *MismatchedPubStrategy) Recover(signatures []*Signature) (*PrivateKey, error) {
+	if len(signatures) < 2 {
+		return nil, fmt.Errorf("must have at least two signatures for mismatched public keys")
+	}
+
+	switch s.sigID {
+	case Sig_Ed25519:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+		n := scheme.order()
+
+		sig1, sig2 := signatures[0], signatures[1]
+		if !bytes.Equal(sig1.Msg, sig2.Msg) {
+			return nil, fmt.Errorf("signatures are over different messages")
+		}
+		if !bytes.Equal(sig1.R(), sig2.R()) {
+			return nil, fmt.Errorf("signatures had different R values, nonce not reused")
+		}
+		if bytes.Equal(sig1.Pub, sig2.Pub) {
+			return nil, fmt.Errorf("signatures have the same public key")
+		}
+
+		a1, b1, err := scheme.nonceRelation(sig1)
+		if err != nil {
+			return nil, err
+		}
+		a2, b2, err := scheme.nonceRelation(sig2)
+		if err != nil {
+			return nil, err
+		}
+
+		// The challenges differ only because of the public key so the usual nonce reuse algebra
+		// applies: x = (a1 - a2) / (b2 - b1)
+		aDiff := new(big.Int).Sub(a1, a2)
+		bDiff := new(big.Int).Sub(b2, b1)
+		bDiffInv := new(big.Int).ModInverse(bDiff.Mod(bDiff, n), n)
+		if bDiffInv == nil {
+			return nil, fmt.Errorf("signatures have the same challenge, can't solve for key")
+		}
+		x := aDiff.Mul(aDiff, bDiffInv)
+		x.Mod(x, n)
+
+		priv := newPrivateKey(scheme, x)
+		if RealPublicKey(priv, signatures) < 0 {
+			return nil, fmt.Errorf("failed to recover private key, no supplied public key matches")
+		}
+
+		return priv, nil
+
+	default:
+		return nil, fmt.Errorf("mismatched public key recovery for %s not implemented", s.sigID)
+	}
+}
+
+// RealPublicKey returns the index of the first signature whose public key actually belongs to the
+// private key, or -1 if none of them do.
+func RealPublicKey(priv *PrivateKey, signatures []*Signature) int {
+	for i, sig := range signatures {
+		if bytes.Equal(sig.Pub, priv.Pub) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *MismatchedPubStrategy) Generate() ([]*Signature, error) {
+	switch s.sigID {
+	case Sig_Ed25519:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+		eddsa := scheme.(*eddsaScheme)
+
+		key, err := scheme.generateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		realPub := scheme.publicKey(key)
+
+		other, err := scheme.generateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		fakePub := scheme.publicKey(other)
+
+		// Derive the nonce from a secret prefix and the message like RFC 8032 so that it's the same
+		// for every signature over the message regardless of the public key used.
+		m := []byte("example mismatched-pubkey sig")
+		prefix := make([]byte, 32)
+		if _, err := rand.Read(prefix); err != nil {
+			return nil, err
+		}
+		h := sha512.Sum512(append(prefix, m...))
+		nonce := new(big.Int).SetBytes(reverse(h[:]))
+		nonce.Mod(nonce, scheme.order())
+
+		sig1, err := eddsa.signWithPub(key, nonce, fakePub, m)
+		if err != nil {
+			return nil, err
+		}
+
+		sig2, err := eddsa.signWithPub(key, nonce, realPub, m)
+		if err != nil {
+			return nil, err
+		}
+
+		sigs := make([]*Signature, 2)
+		sigs[0] = &Signature{Pub: fakePub, Msg: m, Sig: sig1}
+		sigs[1] = &Signature{Pub: realPub, Msg: m, Sig: sig2}
+
+		return sigs, nil
+
+	default:
+		return nil, fmt.Errorf("gen mismatched public key not supported for sig type")
+	}
+}
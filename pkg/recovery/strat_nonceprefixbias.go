@@ -1,108 +1,136 @@
 package recovery
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
+	"bytes"
 	"crypto/rand"
 	"fmt"
 	"math/big"
 )
 
+/*
+* When the top bits of every nonce are zero the nonces are all small, which turns recovering the key
+* into an instance of the hidden number problem. Each signature gives k_i = α_i + β_i·d mod n with
+* 0 <= k_i < B, so with the lattice spanned by the rows
+*
+*   [ n                        ]
+*   [    n                     ]
+*   [       ...                ]
+*   [ β_1 β_2 ... β_m  B/n     ]
+*   [ α_1 α_2 ... α_m       B  ]
+*
+* the vector (k_1, ..., k_m, d·B/n, B) is an unusually short lattice point that LLL will find once
+* there are enough signatures. Everything is scaled by n to keep the entries integral.
+ */
+
 type NonceBiasPrefixStrategy struct {
-	curve            elliptic.Curve
+	curveID          CurveIdentifier
 	sigID            SignatureIdentifier
+	sm2ID            string
 	bitBias, numSigs int
 }
 
-func (s *NonceBiasPrefixStrategy) Recover(sigs []*Signature) (*ecdsa.PrivateKey, error) {
-	params := s.curve.Params()
-	intBytes := byteLen(s.curve)
+func (s *NonceBiasPrefixStrategy) Recover(sigs []*Signature) (*PrivateKey, error) {
+	if len(sigs) < 2 {
+		return nil, fmt.Errorf("must have at least two signatures for nonce bias")
+	}
 
-	// 2**N-bitBias
-	nonceBound := new(big.Int).Lsh(big.NewInt(1), uint(params.N.Int64()-int64(s.bitBias)))
+	switch s.sigID.family() {
+	case familyECDSA, familyDSA, familySM2, familyGOST:
+		scheme, err := schemeForSignatures(s.curveID, s.sigID, s.sm2ID, sigs)
+		if err != nil {
+			return nil, err
+		}
+		n := scheme.order()
+		if s.bitBias <= 0 || s.bitBias >= n.BitLen() {
+			return nil, fmt.Errorf("invalid nonce bias of %d bits", s.bitBias)
+		}
 
-	// Initialize matrix with default 0 values.
-	rowColLen := len(sigs) + 1
-	matrix := make([][]*big.Int, rowColLen)
-	for i := range matrix {
-		matrix[i] = make([]*big.Int, rowColLen)
-	}
+		// B = 2^(bits(n) - bias) is the bound on the nonces.
+		nonceBound := new(big.Int).Lsh(one, uint(n.BitLen()-s.bitBias))
+		m := len(sigs)
+		nSq := new(big.Int).Mul(n, n)
 
-	// Initialize matrix to a state like so, computed based on the number of signatures provided.
-	//   [N .  .  .]
-	//   [. N  .  .]
-	//   [. . B/N .]
-	//   [. .  .  B]
-	for i, row := range matrix[:len(matrix)-2] {
-		row[i] = new(big.Int).SetBytes(params.N.Bytes()) // clone N for unique reference
-	}
-	noncesRow := matrix[len(matrix)-2]
-	msgsRow := matrix[len(matrix)-1]
-	noncesRow[rowColLen-2] = new(big.Int).Div(nonceBound, params.N)
-	msgsRow[rowColLen-1] = nonceBound
-
-	// Fill in the nonces row
-	sig_n := sigs[len(sigs)-1]
-	r_n := new(big.Int).SetBytes(sig_n.Sig[:intBytes])
-	s_n := new(big.Int).SetBytes(sig_n.Sig[intBytes:])
-	rsInv_n := mulModInv(r_n, s_n, params.N)
-	for i := 0; i < len(sigs)-1; i++ {
-		sig_i := sigs[i]
-		r_i := new(big.Int).SetBytes(sig_i.Sig[:intBytes])
-		s_i := new(big.Int).SetBytes(sig_i.Sig[intBytes:])
-		rsInv_i := mulModInv(r_i, s_i, params.N)
-		noncesRow[i] = rsInv_i.Sub(rsInv_i, rsInv_n)
-	}
+		basis := make([][]*big.Int, m+2)
+		for i := range basis {
+			basis[i] = make([]*big.Int, m+2)
+			for j := range basis[i] {
+				basis[i][j] = new(big.Int)
+			}
+		}
+		for i, sig := range sigs {
+			alpha, beta, err := scheme.nonceRelation(sig)
+			if err != nil {
+				return nil, err
+			}
 
-	// Fill in the messages row
-	m_n := new(big.Int).SetBytes(hashBytes(s.sigID.Hash(), sig_n.Msg))
-	msInv_n := mulModInv(m_n, s_n, params.N)
-	for i := 0; i < len(sigs)-1; i++ {
-		sig_i := sigs[i]
-		m_i := new(big.Int).SetBytes(hashBytes(s.sigID.Hash(), sig_n.Msg))
-		s_i := new(big.Int).SetBytes(sig_i.Sig[intBytes:])
-		msInv_i := mulModInv(m_i, s_i, params.N)
-		msgsRow[i] = msInv_i.Sub(msInv_i, msInv_n)
-	}
+			basis[i][i].Set(nSq)
+			basis[m][i].Mul(beta, n)
+			basis[m+1][i].Mul(alpha, n)
+		}
+		basis[m][m].Set(nonceBound)
+		target := new(big.Int).Mul(nonceBound, n)
+		basis[m+1][m+1].Set(target)
 
-	// TODO: finish implementation, need and LLL implementation which doesn't seem to exist in go,
-	// so I might have to implement it to finish this up.
-	panic("not implemented")
-}
+		lllReduce(basis)
 
-// Performs x*y^-1, mutating x and y and returning the result in x.
-func mulModInv(x, y, n *big.Int) *big.Int {
-	return x.Mul(x, y.ModInverse(y, n))
+		// The short vector may show up negated, and the row it's in depends on the lattice.
+		for _, row := range basis {
+			if new(big.Int).Abs(row[m+1]).Cmp(target) != 0 {
+				continue
+			}
+
+			d := new(big.Int).Div(row[m], nonceBound)
+			if row[m+1].Sign() < 0 {
+				d.Neg(d)
+			}
+			d.Mod(d, n)
+			if d.Sign() == 0 {
+				continue
+			}
+
+			priv := newPrivateKey(scheme, d)
+			if bytes.Equal(priv.Pub, sigs[0].Pub) {
+				return priv, nil
+			}
+		}
+
+		return nil, fmt.Errorf("failed to recover private key, more signatures may be needed")
+
+	default:
+		return nil, fmt.Errorf("nonce bias recovery for %s not implemented", s.sigID)
+	}
 }
 
 func (s *NonceBiasPrefixStrategy) Generate() ([]*Signature, error) {
-	switch s.sigID {
-	case Sig_ECDSA_SHA256:
-		byteLen := byteLen(s.curve)
+	switch s.sigID.family() {
+	case familyECDSA, familyDSA, familySM2, familyGOST:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
 
-		key, err := ecdsa.GenerateKey(s.curve, rand.Reader)
+		key, err := scheme.generateKey(rand.Reader)
 		if err != nil {
 			return nil, err
 		}
-		pub := serializePub(&key.PublicKey, byteLen)
+		pub := scheme.publicKey(key)
+		n := scheme.order()
 
 		sigs := make([]*Signature, s.numSigs)
 		for i := range sigs {
-			k, err := rand.Int(rand.Reader, s.curve.Params().N)
-			if err != nil {
-				return nil, err
+			k := new(big.Int)
+			for k.Sign() == 0 {
+				if k, err = rand.Int(rand.Reader, n); err != nil {
+					return nil, err
+				}
+				k.Rsh(k, uint(s.bitBias)) // introduce the bias via shifting to zero the highest bits
 			}
-			k.Rsh(k, uint(s.bitBias)) // introduce the bias via shifting to zero the highest bits
 
-			m := []byte("example sig with nonce-prefix-bias")
-			r, s, err := ecdsaSign(key, k, s.curve, hashBytes(s.sigID.Hash(), m))
+			m := exampleMessage(scheme, fmt.Sprintf("example sig with nonce-prefix-bias #%d", i+1))
+			sig, err := scheme.sign(key, k, m)
 			if err != nil {
 				return nil, err
 			}
-
-			sig := make([]byte, byteLen*2)
-			copy(sig, leftPad(r.Bytes(), byteLen))
-			copy(sig[byteLen:], leftPad(s.Bytes(), byteLen))
 			sigs[i] = &Signature{Pub: pub, Msg: m, Sig: sig}
 		}
 
@@ -2,65 +2,56 @@ package recovery
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"fmt"
 	"math/big"
 )
 
 type NonceReuseStrategy struct {
-	curve elliptic.Curve
-	sigID SignatureIdentifier
+	curveID CurveIdentifier
+	sigID   SignatureIdentifier
+	sm2ID   string
 }
 
-func (s *NonceReuseStrategy) Recover(signatures []*Signature) (*ecdsa.PrivateKey, error) {
+func (s *NonceReuseStrategy) Recover(signatures []*Signature) (*PrivateKey, error) {
 	if len(signatures) < 2 {
 		return nil, fmt.Errorf("must have at least two signatures for nonce reuse")
 	}
-	curve := s.curve
 
-	switch s.sigID {
-	case Sig_ECDSA_SHA256, Sig_ECDSA_SHA512, Sig_ECDSA_KECCAK256:
-		byteLen := byteLen(curve)
-		n := curve.Params().N
+	switch s.sigID.family() {
+	case familyECDSA, familyDSA, familyEdDSA, familySchnorr, familySM2, familyGOST:
+		scheme, err := schemeForSignatures(s.curveID, s.sigID, s.sm2ID, signatures[:2])
+		if err != nil {
+			return nil, err
+		}
+		n := scheme.order()
 
 		sig1, sig2 := signatures[0], signatures[1]
-		z1 := new(big.Int).SetBytes(hashBytes(s.sigID.Hash(), sig1.Msg))
-		r1 := new(big.Int).SetBytes(sig1.Sig[:byteLen])
-		s1 := new(big.Int).SetBytes(sig1.Sig[byteLen:])
-		z2 := new(big.Int).SetBytes(hashBytes(s.sigID.Hash(), sig2.Msg))
-		r2 := new(big.Int).SetBytes(sig2.Sig[:byteLen])
-		s2 := new(big.Int).SetBytes(sig2.Sig[byteLen:])
-
-		if r1.Cmp(r2) != 0 {
+		if !bytes.Equal(nonceCommitment(scheme, sig1), nonceCommitment(scheme, sig2)) {
 			return nil, fmt.Errorf("signatures had different r values, nonce not reused")
 		}
 
-		// k = z1-z2 / s1-s2
-		zDiff := new(big.Int).Sub(z1, z2)
-		sDiff := new(big.Int).Sub(s1, s2)
-		sDiffInv := new(big.Int).ModInverse(sDiff, n)
-		if sDiffInv == nil {
-			panic("no mod inverse found")
+		a1, b1, err := scheme.nonceRelation(sig1)
+		if err != nil {
+			return nil, err
 		}
-		k := new(big.Int).Mul(zDiff, sDiffInv)
-		k.Mod(k, n)
-
-		// x = sk - z / r
-		skSubZ := new(big.Int).Sub(new(big.Int).Mul(s1, k), z1)
-		rInv := new(big.Int).ModInverse(r1, n)
-		if sDiffInv == nil {
-			panic("no r mod inverse found")
+		a2, b2, err := scheme.nonceRelation(sig2)
+		if err != nil {
+			return nil, err
 		}
-		x := new(big.Int).Mul(skSubZ, rInv)
-		x.Mod(x, n)
 
-		pubX, pubY := curve.ScalarBaseMult(x.Bytes())
-		pub := ecdsa.PublicKey{Curve: curve, X: pubX, Y: pubY}
-		priv := &ecdsa.PrivateKey{PublicKey: pub, D: x}
+		// k = a1 + b1·x = a2 + b2·x so x = (a1 - a2) / (b2 - b1)
+		aDiff := new(big.Int).Sub(a1, a2)
+		bDiff := new(big.Int).Sub(b2, b1)
+		bDiffInv := new(big.Int).ModInverse(bDiff.Mod(bDiff, n), n)
+		if bDiffInv == nil {
+			return nil, fmt.Errorf("signatures are over the same message, can't solve for key")
+		}
+		x := aDiff.Mul(aDiff, bDiffInv)
+		x.Mod(x, n)
 
-		if !bytes.Equal(sig1.Pub, serializePub(&pub, byteLen)) {
+		priv := newPrivateKey(scheme, x)
+		if !bytes.Equal(sig1.Pub, priv.Pub) {
 			return nil, fmt.Errorf("failed to recover private key")
 		}
 
@@ -72,37 +63,32 @@ func (s *NonceReuseStrategy) Recover(signatures []*Signature) (*ecdsa.PrivateKey
 }
 
 func (s *NonceReuseStrategy) Generate() ([]*Signature, error) {
-	switch s.sigID {
-	case Sig_ECDSA_SHA256, Sig_ECDSA_SHA512, Sig_ECDSA_KECCAK256:
-		byteLen := byteLen(s.curve)
+	switch s.sigID.family() {
+	case familyECDSA, familyDSA, familyEdDSA, familySchnorr, familySM2, familyGOST:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
 
-		key, err := ecdsa.GenerateKey(s.curve, rand.Reader)
+		key, err := scheme.generateKey(rand.Reader)
 		if err != nil {
 			return nil, err
 		}
-		pub := serializePub(&key.PublicKey, byteLen)
+		pub := scheme.publicKey(key)
 
 		nonce := big.NewInt(1337)
-		m1 := []byte("example nonce-reuse sig #1")
-		r1, s1, err := ecdsaSign(key, nonce, s.curve, hashBytes(s.sigID.Hash(), m1))
+		m1 := exampleMessage(scheme, "example nonce-reuse sig #1")
+		sig1, err := scheme.sign(key, nonce, m1)
 		if err != nil {
 			return nil, err
 		}
 
-		sig1 := make([]byte, byteLen*2)
-		copy(sig1, leftPad(r1.Bytes(), byteLen))
-		copy(sig1[byteLen:], leftPad(s1.Bytes(), byteLen))
-
-		m2 := []byte("example nonce-reuse sig #2")
-		r2, s2, err := ecdsaSign(key, nonce, s.curve, hashBytes(s.sigID.Hash(), m2))
+		m2 := exampleMessage(scheme, "example nonce-reuse sig #2")
+		sig2, err := scheme.sign(key, nonce, m2)
 		if err != nil {
 			return nil, err
 		}
 
-		sig2 := make([]byte, byteLen*2)
-		copy(sig2, leftPad(r2.Bytes(), byteLen))
-		copy(sig2[byteLen:], leftPad(s2.Bytes(), byteLen))
-
 		sigs := make([]*Signature, 2)
 		sigs[0] = &Signature{Pub: pub, Msg: m1, Sig: sig1}
 		sigs[1] = &Signature{Pub: pub, Msg: m2, Sig: sig2}
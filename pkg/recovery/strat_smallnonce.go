@@ -0,0 +1,108 @@
+package recovery
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+/*
+* A nonce that's simply small, k < 2^bits, gives the key from a single signature. The nonce relation
+* k = α + β·d turns into d = -α·β⁻¹ + β⁻¹·k, so the public key is g^(-α·β⁻¹)·(g^(β⁻¹))^k with k in
+* an interval, which the kangaroo solves in about 2^(bits/2 + 1) group operations whatever the size
+* of the group. Unlike the lattice of NonceBiasPrefixStrategy it needs no more signatures for a
+* smaller bias, but it only reaches nonces of up to intervalMaxBits.
+ */
+
+type SmallNonceStrategy struct {
+	curveID   CurveIdentifier
+	sigID     SignatureIdentifier
+	sm2ID     string
+	nonceBits int
+}
+
+func (s *SmallNonceStrategy) Recover(sigs []*Signature) (*PrivateKey, error) {
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("must have a signature for a small nonce")
+	}
+
+	switch s.sigID.family() {
+	case familyECDSA, familyDSA, familySM2, familyGOST:
+		if s.nonceBits <= 0 || s.nonceBits > intervalMaxBits {
+			return nil, fmt.Errorf("nonces of %d bits can't be searched, at most %d", s.nonceBits, intervalMaxBits)
+		}
+		scheme, err := schemeForSignatures(s.curveID, s.sigID, s.sm2ID, sigs)
+		if err != nil {
+			return nil, err
+		}
+		n := scheme.order()
+		hi := new(big.Int).Lsh(one, uint(s.nonceBits))
+		hi.Sub(hi, one)
+
+		// Any one signature with a small nonce is enough, so try them in turn.
+		err = fmt.Errorf("no signature has a public key")
+		for _, sig := range sigs {
+			if sig.Pub == nil {
+				continue
+			}
+			alpha, beta, relErr := scheme.nonceRelation(sig)
+			if relErr != nil {
+				err = relErr
+				continue
+			}
+			betaInv := new(big.Int).ModInverse(beta, n)
+			if betaInv == nil {
+				continue
+			}
+			a := new(big.Int).Mul(alpha, betaInv)
+
+			var priv *PrivateKey
+			if priv, err = solveLinear(scheme, sig.Pub, a.Neg(a), betaInv, one, hi, nil); err == nil {
+				return priv, nil
+			}
+		}
+		return nil, fmt.Errorf("no nonce below 2^%d found: %w", s.nonceBits, err)
+
+	default:
+		return nil, fmt.Errorf("small nonce recovery for %s not implemented", s.sigID)
+	}
+}
+
+func (s *SmallNonceStrategy) Generate() ([]*Signature, error) {
+	switch s.sigID.family() {
+	case familyECDSA, familyDSA, familySM2, familyGOST:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := scheme.generateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		pub := scheme.publicKey(key)
+		bound := new(big.Int).Lsh(one, uint(s.nonceBits))
+
+		sigs := make([]*Signature, 2)
+		for i := range sigs {
+			k := new(big.Int)
+			for k.Sign() == 0 {
+				if k, err = rand.Int(rand.Reader, bound); err != nil {
+					return nil, err
+				}
+			}
+
+			m := exampleMessage(scheme, fmt.Sprintf("example sig with small nonce #%d", i+1))
+			sig, err := scheme.sign(key, k, m)
+			if err != nil {
+				return nil, err
+			}
+			sigs[i] = &Signature{Pub: pub, Msg: m, Sig: sig}
+		}
+
+		return sigs, nil
+
+	default:
+		return nil, fmt.Errorf("small nonces not supported for sig type")
+	}
+}
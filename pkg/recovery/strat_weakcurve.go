@@ -0,0 +1,254 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/jakecraige/keyrecovery/pkg/dlp"
+)
+
+/*
+* Pohlig–Hellman for curves whose base point has a composite order. The discrete log of the public
+* key is solved in each prime power subgroup separately, a digit at a time with pkg/dlp in the prime
+* order subgroup, and the results are combined with the CRT. That only needs the public
+* key, so a single signature is enough. Each prime can be up to weakCurveMaxDLPBits and so can
+* whatever's left of the order once the small primes have been handled, since d is then known up to
+* a multiple of their product.
+*
+* Anomalous curves have a prime order, but the order is the prime of the field and Smart's attack in
+* smart.go solves those instead. Singular curves are mapped to the field in singular.go, and curves
+* with a small embedding degree are moved to an extension of it with a pairing in mov.go.
+ */
+
+// weakCurveMaxDLPBits bounds the subgroups solved, with baby-step giant-step while its table of
+// 2^(bits/2) points is small and rho past that, which takes about as many steps but little memory.
+const weakCurveMaxDLPBits = 48
+
+// CurveAnalysis is what makes a curve weak, which decides the attack used by the weak-curve mode.
+type CurveAnalysis struct {
+	// Singular is "cusp", "split node" or "non-split node" for a singular curve and empty otherwise.
+	Singular string
+
+	// Anomalous is set when the order of G is the prime of the field.
+	Anomalous bool
+
+	// EmbeddingDegree is the smallest k with n | p^k - 1 when it's small enough for the MOV attack,
+	// and zero otherwise.
+	EmbeddingDegree int
+
+	// Factors is the factorization of the order of G when the attack depends on it.
+	Factors []OrderFactor
+}
+
+func (a *CurveAnalysis) String() string {
+	switch a.Singular {
+	case "cusp":
+		return "Curve is singular with a cusp, mapping to GF(p)+"
+	case "split node":
+		return fmt.Sprintf("Curve is singular with a split node, mapping to GF(p)*, order factorization: %s",
+			FormatFactorization(a.Factors))
+	case "non-split node":
+		return fmt.Sprintf("Curve is singular with a non-split node, mapping to GF(p^2)*, order factorization: %s",
+			FormatFactorization(a.Factors))
+	}
+	switch {
+	case a.Anomalous:
+		return "Curve is anomalous, using Smart's attack"
+	case a.EmbeddingDegree > 1:
+		return fmt.Sprintf("Curve has embedding degree %d, using the MOV attack in GF(p^%d), order factorization: %s",
+			a.EmbeddingDegree, a.EmbeddingDegree, FormatFactorization(a.Factors))
+	}
+	return fmt.Sprintf("Order factorization: %s", FormatFactorization(a.Factors))
+}
+
+func analyzeCurve(curveID CurveIdentifier, curve elliptic.Curve) *CurveAnalysis {
+	params := curve.Params()
+	analysis := &CurveAnalysis{Anomalous: curveID.IsAnomalous()}
+	if custom := curveID.info().custom; custom != nil {
+		analysis.Singular = custom.singularity()
+	}
+	if analysis.Singular == "" && !analysis.Anomalous {
+		analysis.EmbeddingDegree = embeddingDegree(params.P, params.N)
+	}
+	if analysis.Singular != "cusp" && !analysis.Anomalous {
+		analysis.Factors = factorize(params.N)
+	}
+	return analysis
+}
+
+type WeakCurveStrategy struct {
+	curveID CurveIdentifier
+	sigID   SignatureIdentifier
+	sm2ID   string
+}
+
+func (s *WeakCurveStrategy) Recover(signatures []*Signature) (*PrivateKey, error) {
+	if len(signatures) < 1 {
+		return nil, fmt.Errorf("must have at least one signature for weak curve recovery")
+	}
+
+	switch s.sigID.family() {
+	case familyECDSA, familySM2, familyGOST:
+		scheme, err := schemeForSignatures(s.curveID, s.sigID, s.sm2ID, signatures[:1])
+		if err != nil {
+			return nil, err
+		}
+		curve, err := s.curveID.Curve()
+		if err != nil {
+			return nil, err
+		}
+
+		pub := signatures[0].Pub
+		byteLen := byteLen(curve)
+		qx := new(big.Int).SetBytes(pub[:byteLen])
+		qy := new(big.Int).SetBytes(pub[byteLen:])
+		if !curve.IsOnCurve(qx, qy) {
+			return nil, fmt.Errorf("public key is not on the curve")
+		}
+
+		d, err := s.discreteLog(curve, qx, qy)
+		if err != nil {
+			return nil, err
+		}
+
+		priv := newPrivateKey(scheme, d)
+		if !bytes.Equal(priv.Pub, pub) {
+			return nil, fmt.Errorf("recovered key doesn't match the public key")
+		}
+
+		return priv, nil
+
+	default:
+		return nil, fmt.Errorf("weak curve recovery for %s not implemented", s.sigID)
+	}
+}
+
+// discreteLog returns d such that d·G = Q with the attack that the analysis of the curve picks.
+func (s *WeakCurveStrategy) discreteLog(curve elliptic.Curve, qx, qy *big.Int) (*big.Int, error) {
+	analysis := analyzeCurve(s.curveID, curve)
+	switch {
+	case analysis.Singular != "":
+		return singularDiscreteLog(s.curveID.info().custom, qx, qy, analysis)
+	case analysis.Anomalous:
+		return smartAttack(curve, s.curveID.coefficientA(), qx, qy)
+	}
+
+	params := curve.Params()
+	if analysis.EmbeddingDegree > 1 {
+		return movAttack(params, s.curveID.coefficientA(), qx, qy, analysis.EmbeddingDegree, analysis.Factors)
+	}
+
+	g := element{params.Gx, params.Gy}
+	d, err := pohligHellman(dlp.CurveGroup(curve), g, element{qx, qy}, params.N, analysis.Factors)
+	if err != nil {
+		return nil, fmt.Errorf("%w, order factorization: %s", err, FormatFactorization(analysis.Factors))
+	}
+	return d, nil
+}
+
+// pohligHellman returns d such that g^d = h, where the order n of g factors as given.
+func pohligHellman(grp dlp.Group, g, h element, n *big.Int, factors []OrderFactor) (*big.Int, error) {
+	// d is known mod m so far.
+	d, m := new(big.Int), big.NewInt(1)
+	for _, f := range factors {
+		if f.Composite || f.Prime.BitLen() > weakCurveMaxDLPBits {
+			continue
+		}
+
+		pe := new(big.Int).Exp(f.Prime, big.NewInt(int64(f.Exponent)), nil)
+		x, err := dlogPrimePower(grp, g, h, n, f.Prime, f.Exponent)
+		if err != nil {
+			return nil, err
+		}
+
+		// CRT: d' = d + m·((x - d)·m⁻¹ mod p^e)
+		t := new(big.Int).Sub(x, d)
+		t.Mul(t, new(big.Int).ModInverse(m, pe))
+		t.Mod(t, pe)
+		d.Add(d, t.Mul(t, m))
+		m.Mul(m, pe)
+	}
+
+	// Whatever's left of the order is solved directly: h·g^-d = (g^m)^t for t < n/m.
+	rest := new(big.Int).Div(n, m)
+	if rest.Cmp(one) > 0 {
+		if rest.BitLen() > weakCurveMaxDLPBits {
+			return nil, fmt.Errorf("the order has a %d-bit factor which is too large to solve", rest.BitLen())
+		}
+
+		result, err := dlp.Solve(grp, grp.Exp(g, m), grp.Op(h, grp.Inverse(grp.Exp(g, d))), rest, nil)
+		if err != nil {
+			return nil, fmt.Errorf("public key is not in the subgroup generated by the base point")
+		}
+		d.Add(d, result.D.Mul(result.D, m))
+	}
+
+	return d.Mod(d, n), nil
+}
+
+// dlogPrimePower returns d mod p^e, solving for one base p digit at a time in the subgroup of
+// order p.
+func dlogPrimePower(grp dlp.Group, g, h element, n, p *big.Int, e int) (*big.Int, error) {
+	pe := new(big.Int).Exp(p, big.NewInt(int64(e)), nil)
+	cofactor := new(big.Int).Div(n, pe)
+
+	// g' and h' are in the subgroup of order p^e and γ in the one of order p.
+	g1 := grp.Exp(g, cofactor)
+	h1 := grp.Exp(h, cofactor)
+	gamma := grp.Exp(g1, new(big.Int).Exp(p, big.NewInt(int64(e-1)), nil))
+
+	x := new(big.Int)
+	pk := big.NewInt(1)
+	for k := 0; k < e; k++ {
+		// h_k = (h'·g'^-x)^(p^(e-1-k)) = γ^d_k
+		hk := grp.Op(h1, grp.Inverse(grp.Exp(g1, x)))
+		hk = grp.Exp(hk, new(big.Int).Exp(p, big.NewInt(int64(e-1-k)), nil))
+
+		result, err := dlp.Solve(grp, gamma, hk, p, nil)
+		if err != nil {
+			return nil, fmt.Errorf("public key is not in the subgroup generated by the base point")
+		}
+		x.Add(x, result.D.Mul(result.D, pk))
+		pk.Mul(pk, p)
+	}
+
+	return x, nil
+}
+
+func (s *WeakCurveStrategy) Generate() ([]*Signature, error) {
+	switch s.sigID.family() {
+	case familyECDSA, familySM2, familyGOST:
+		scheme, err := newScheme(s.curveID, s.sigID, s.sm2ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The signature is perfectly ordinary, it's only the curve that's weak. With a composite
+		// order not every key and nonce is invertible, and with an even one most attempts fail.
+		m := exampleMessage(scheme, "example weak curve sig")
+		for i := 0; i < 64; i++ {
+			key, err := scheme.generateKey(rand.Reader)
+			if err != nil {
+				return nil, err
+			}
+			nonce, err := randScalar(scheme.order())
+			if err != nil {
+				return nil, err
+			}
+			sig, err := scheme.sign(key, nonce, m)
+			if err != nil {
+				continue
+			}
+
+			return []*Signature{{Pub: scheme.publicKey(key), Msg: m, Sig: sig}}, nil
+		}
+
+		return nil, fmt.Errorf("failed to sign with the curve's order")
+
+	default:
+		return nil, fmt.Errorf("gen weak curve not supported for sig type")
+	}
+}
@@ -0,0 +1,212 @@
+package recovery
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ripemd160" //nolint:staticcheck
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+* Searches for keys made from guessable secrets, passphrases or the seeds of weak generators, derive
+* a key from each candidate and look its public key up in a set of targets. Targets are public keys
+* serialized like Signature.Pub or in SEC 1 form, compressed or not, Bitcoin P2PKH addresses, which
+* match the compressed and uncompressed keys, or Ethereum addresses.
+ */
+
+// KeyTargets is the set of public keys and addresses a search for keys looks for.
+type KeyTargets struct {
+	// Each maps the raw bytes compared against to the target as it was given.
+	pubs    map[string]string
+	hash160 map[string]string
+	eth     map[string]string
+
+	// sec1 holds SEC 1 public keys by their compressed form, whichever form they were given in.
+	sec1 map[string]string
+}
+
+func NewKeyTargets() *KeyTargets {
+	return &KeyTargets{
+		pubs:    map[string]string{},
+		hash160: map[string]string{},
+		eth:     map[string]string{},
+		sec1:    map[string]string{},
+	}
+}
+
+// LoadKeyTargets reads targets, one per line.
+func LoadKeyTargets(path string) (*KeyTargets, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	targets := NewKeyTargets()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := targets.Add(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// Add adds a target: a 0x prefixed Ethereum address, a base58 Bitcoin P2PKH address or a hex public
+// key, either serialized like Signature.Pub or in SEC 1 form.
+func (t *KeyTargets) Add(target string) error {
+	if strings.HasPrefix(target, "0x") && len(target) == 42 {
+		addr, err := hex.DecodeString(target[2:])
+		if err != nil {
+			return fmt.Errorf("invalid Ethereum address %s: %w", target, err)
+		}
+		t.eth[string(addr)] = target
+		return nil
+	}
+
+	if pub, err := hex.DecodeString(target); err == nil {
+		// Signature.Pub is always an even number of bytes and SEC 1 keys never are.
+		if len(pub)%2 == 0 {
+			t.pubs[string(pub)] = target
+			return nil
+		}
+		switch {
+		case len(pub) > 1 && (pub[0] == 0x02 || pub[0] == 0x03):
+			t.sec1[string(pub)] = target
+		case len(pub) > 1 && pub[0] == 0x04:
+			xLen := (len(pub) - 1) / 2
+			compressed := append([]byte{0x02 | pub[len(pub)-1]&1}, pub[1:1+xLen]...)
+			t.sec1[string(compressed)] = target
+		default:
+			return fmt.Errorf("public key %s is neither a SEC 1 key nor the length of a serialized one", target)
+		}
+		return nil
+	}
+
+	payload, err := decodeBase58Check(target)
+	if err != nil {
+		return fmt.Errorf("target %s is not a public key or address: %w", target, err)
+	}
+	if len(payload) != 21 || (payload[0] != 0x00 && payload[0] != 0x6f) {
+		return fmt.Errorf("address %s is not P2PKH", target)
+	}
+	t.hash160[string(payload[1:])] = target
+	return nil
+}
+
+// Len returns the number of targets.
+func (t *KeyTargets) Len() int {
+	return len(t.pubs) + len(t.hash160) + len(t.eth) + len(t.sec1)
+}
+
+// match returns the target matching the key, if any.
+func (t *KeyTargets) match(scheme scheme, pub []byte) (string, bool) {
+	if target, ok := t.pubs[string(pub)]; ok {
+		return target, true
+	}
+	if len(t.hash160) == 0 && len(t.eth) == 0 && len(t.sec1) == 0 {
+		return "", false
+	}
+
+	curve, x, y, err := publicPoint(scheme, pub)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := curve.(*edwardsCurve); ok {
+		return "", false
+	}
+	byteLen := byteLen(curve)
+	xb, yb := leftPad(x.Bytes(), byteLen), leftPad(y.Bytes(), byteLen)
+	compressed := append([]byte{0x02 | byte(y.Bit(0))}, xb...)
+
+	if target, ok := t.sec1[string(compressed)]; ok {
+		return target, true
+	}
+	if len(t.hash160) > 0 {
+		uncompressed := append(append([]byte{0x04}, xb...), yb...)
+		for _, sec := range [][]byte{compressed, uncompressed} {
+			if target, ok := t.hash160[string(hash160(sec))]; ok {
+				return target, true
+			}
+		}
+	}
+	if len(t.eth) > 0 {
+		h := sha3.NewLegacyKeccak256()
+		h.Write(xb)
+		h.Write(yb)
+		if target, ok := t.eth[string(h.Sum(nil)[12:])]; ok {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// hash160 is RIPEMD-160 of SHA-256, which Bitcoin addresses are made of.
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// decodeBase58Check decodes a base58 string and checks and strips its 4 byte checksum.
+func decodeBase58Check(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	// Leading ones are leading zero bytes.
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	data := append(make([]byte, zeros), n.Bytes()...)
+	if len(data) < 5 {
+		return nil, fmt.Errorf("base58 string is too short")
+	}
+
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, fmt.Errorf("bad base58 checksum")
+	}
+	return payload, nil
+}
+
+// secretKey returns the private key made from secret bytes, or nil if they don't make a valid one.
+// The secret is an Ed25519 seed and otherwise the scalar itself.
+func secretKey(scheme scheme, secret []byte) *big.Int {
+	if s, ok := scheme.(*eddsaScheme); ok {
+		if len(secret) < 32 {
+			return nil
+		}
+		return s.expandSeed(secret[:32])
+	}
+
+	k := new(big.Int).SetBytes(secret)
+	if k.Mod(k, scheme.order()).Sign() == 0 {
+		return nil
+	}
+	return k
+}
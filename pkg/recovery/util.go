@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"hash"
+	"math/big"
 )
 
 func leftPad(bytes []byte, targetLen int) []byte {
@@ -49,3 +50,20 @@ func byteLen(curve elliptic.Curve) int {
 
 	return bitSize / 8
 }
+
+func bigFromDecimal(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid decimal constant: " + s)
+	}
+	return n
+}
+
+// reverse returns a reversed copy of bytes, converting between big and little endian.
+func reverse(bytes []byte) []byte {
+	out := make([]byte, len(bytes))
+	for i, b := range bytes {
+		out[len(bytes)-1-i] = b
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package recovery
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+/*
+* weierstrassCurve is a short Weierstrass curve y^2 = x^3 + a·x + b with an arbitrary a, since
+* elliptic.CurveParams only implements curves with a = -3. Like edwardsCurve it uses affine
+* coordinates and math/big so it is slow and not constant time. The point at infinity is (0, 0) as
+* it is for the standard library curves, unless b = 0 and (0, 0) is a point of the curve. It's then
+* (0, p), which no point of the curve is since coordinates are reduced mod p.
+ */
+
+type weierstrassCurve struct {
+	params *elliptic.CurveParams
+	a      *big.Int
+
+	// infY is the y coordinate of the point at infinity.
+	infY *big.Int
+}
+
+func newWeierstrassCurve(params *elliptic.CurveParams, a *big.Int) *weierstrassCurve {
+	infY := new(big.Int)
+	if params.B.Sign() == 0 {
+		infY.Set(params.P)
+	}
+	return &weierstrassCurve{params: params, a: new(big.Int).Mod(a, params.P), infY: infY}
+}
+
+func (c *weierstrassCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *weierstrassCurve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	// y^2 - x^3 - a·x - b == 0
+	lhs := new(big.Int).Mul(y, y)
+	lhs.Sub(lhs, new(big.Int).Exp(x, big.NewInt(3), p))
+	lhs.Sub(lhs, new(big.Int).Mul(c.a, x))
+	lhs.Sub(lhs, c.params.B)
+
+	return lhs.Mod(lhs, p).Sign() == 0
+}
+
+// Infinity returns the point at infinity.
+func (c *weierstrassCurve) Infinity() (x, y *big.Int) {
+	return new(big.Int), new(big.Int).Set(c.infY)
+}
+
+func (c *weierstrassCurve) isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Cmp(c.infY) == 0
+}
+
+func (c *weierstrassCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if c.isInfinity(x1, y1) {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2)
+	}
+	if c.isInfinity(x2, y2) {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1)
+	}
+
+	p := c.params.P
+	if x1.Cmp(x2) == 0 {
+		if new(big.Int).Add(y1, y2).Mod(new(big.Int).Add(y1, y2), p).Sign() == 0 {
+			return c.Infinity()
+		}
+		return c.Double(x1, y1)
+	}
+
+	// λ = (y2 - y1) / (x2 - x1)
+	l := new(big.Int).Sub(x2, x1)
+	l.ModInverse(l.Mod(l, p), p)
+	l.Mul(l, new(big.Int).Sub(y2, y1))
+
+	return c.finishAdd(l, x1, y1, x2)
+}
+
+func (c *weierstrassCurve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+	if c.isInfinity(x1, y1) || y1.Sign() == 0 {
+		return c.Infinity()
+	}
+
+	// λ = (3·x1^2 + a) / 2·y1
+	l := new(big.Int).Lsh(y1, 1)
+	l.ModInverse(l.Mod(l, p), p)
+	num := new(big.Int).Mul(x1, x1)
+	num.Mul(num, big.NewInt(3))
+	num.Add(num, c.a)
+	l.Mul(l, num)
+
+	return c.finishAdd(l, x1, y1, x1)
+}
+
+// finishAdd computes x3 = λ^2 - x1 - x2 and y3 = λ(x1 - x3) - y1.
+func (c *weierstrassCurve) finishAdd(l, x1, y1, x2 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+	l.Mod(l, p)
+
+	x3 := new(big.Int).Mul(l, l)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, l)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, p)
+
+	return x3, y3
+}
+
+func (c *weierstrassCurve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	x, y := c.Infinity()
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			x, y = c.Double(x, y)
+			if b>>uint(bit)&1 == 1 {
+				x, y = c.Add(x, y, x1, y1)
+			}
+		}
+	}
+
+	return x, y
+}
+
+func (c *weierstrassCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
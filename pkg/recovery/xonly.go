@@ -0,0 +1,99 @@
+package recovery
+
+import (
+	"math/big"
+)
+
+/*
+* x-only arithmetic on y^2 = x^3 + a·x + b in projective coordinates (X : Z), as used by ECDH
+* implementations that only take the x coordinate of the peer's point. The formulas never look at
+* y, so an x for which x^3 + a·x + b isn't a square is a point on the quadratic twist and multiplies
+* just as well, which is what the twist attack relies on. The point at infinity has Z = 0.
+ */
+
+type xOnlyCurve struct {
+	p, a, b *big.Int
+}
+
+type xOnlyPoint struct {
+	x, z *big.Int
+}
+
+func (c *xOnlyCurve) isInfinity(pt xOnlyPoint) bool {
+	return new(big.Int).Mod(pt.z, c.p).Sign() == 0
+}
+
+// affine returns X/Z, or zero for the point at infinity.
+func (c *xOnlyCurve) affine(pt xOnlyPoint) *big.Int {
+	zInv := new(big.Int).ModInverse(new(big.Int).Mod(pt.z, c.p), c.p)
+	if zInv == nil {
+		return new(big.Int)
+	}
+	x := zInv.Mul(zInv, pt.x)
+	return x.Mod(x, c.p)
+}
+
+func (c *xOnlyCurve) double(pt xOnlyPoint) xOnlyPoint {
+	// X' = (X^2 - a·Z^2)^2 - 8b·X·Z^3
+	// Z' = 4Z·(X^3 + a·X·Z^2 + b·Z^3)
+	xx := new(big.Int).Mul(pt.x, pt.x)
+	zz := new(big.Int).Mul(pt.z, pt.z)
+	azz := new(big.Int).Mul(c.a, zz)
+	zzz := new(big.Int).Mul(zz, pt.z)
+
+	x := new(big.Int).Sub(xx, azz)
+	x.Mul(x, x)
+	t := new(big.Int).Mul(c.b, pt.x)
+	t.Mul(t, zzz)
+	x.Sub(x, t.Lsh(t, 3))
+
+	z := new(big.Int).Add(xx, azz)
+	z.Mul(z, pt.x)
+	z.Add(z, new(big.Int).Mul(c.b, zzz))
+	z.Mul(z, pt.z)
+	z.Lsh(z, 2)
+
+	return xOnlyPoint{x.Mod(x, c.p), z.Mod(z, c.p)}
+}
+
+// add returns P1 + P2 given their difference D = P1 - P2.
+func (c *xOnlyCurve) add(p1, p2, d xOnlyPoint) xOnlyPoint {
+	// X' = Z_D·(2(X1·Z2 + X2·Z1)(X1·X2 + a·Z1·Z2) + 4b·(Z1·Z2)^2) - X_D·(X1·Z2 - X2·Z1)^2
+	// Z' = Z_D·(X1·Z2 - X2·Z1)^2
+	x1z2 := new(big.Int).Mul(p1.x, p2.z)
+	x2z1 := new(big.Int).Mul(p2.x, p1.z)
+	z1z2 := new(big.Int).Mul(p1.z, p2.z)
+
+	x := new(big.Int).Add(x1z2, x2z1)
+	t := new(big.Int).Mul(p1.x, p2.x)
+	t.Add(t, new(big.Int).Mul(c.a, z1z2))
+	x.Mul(x, t)
+	x.Lsh(x, 1)
+	t.Mul(z1z2, z1z2)
+	t.Mul(t, c.b)
+	x.Add(x, t.Lsh(t, 2))
+	x.Mul(x, d.z)
+
+	diff := x1z2.Sub(x1z2, x2z1)
+	diff.Mul(diff, diff)
+	diff.Mod(diff, c.p)
+	x.Sub(x, new(big.Int).Mul(d.x, diff))
+	z := diff.Mul(diff, d.z)
+
+	return xOnlyPoint{x.Mod(x, c.p), z.Mod(z, c.p)}
+}
+
+// scalarMult runs the Montgomery ladder, which keeps R1 - R0 = P throughout.
+func (c *xOnlyCurve) scalarMult(x *big.Int, k *big.Int) xOnlyPoint {
+	pt := xOnlyPoint{new(big.Int).Set(x), big.NewInt(1)}
+	r0 := xOnlyPoint{big.NewInt(1), new(big.Int)}
+	r1 := pt
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		if k.Bit(i) == 1 {
+			r0, r1 = c.add(r1, r0, pt), c.double(r1)
+		} else {
+			r0, r1 = c.double(r0), c.add(r1, r0, pt)
+		}
+	}
+	return r0
+}